@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,17 +14,24 @@ import (
 	"github.com/cerera/internal/cerera/config"
 	"github.com/cerera/internal/cerera/network"
 	"github.com/cerera/internal/cerera/pool"
+	"github.com/cerera/internal/cerera/safego"
+	"github.com/cerera/internal/cerera/service"
 	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
 	"github.com/cerera/internal/cerera/validator"
 	"github.com/cerera/internal/gigea/gigea"
 )
 
+// time services have to stop after signal
+const shutdownTimeout = 10 * time.Second
+
 type Process struct {
 }
 
-func (p *Process) Stop() {
+func (p *Process) Stop() error {
 	fmt.Printf("Stopping...\r\n")
 	fmt.Printf("Stopped!\r\n")
+	return nil
 }
 
 type cerera struct {
@@ -34,19 +42,52 @@ type cerera struct {
 	proc   Process
 	v      storage.Vault
 	status [8]byte
+
+	registry *service.Registry
 }
 
 func main() {
 	listenRpcPortParam := flag.Int("r", -1, "rpc port to listen")
 	listenP2pPortParam := flag.Int("l", -1, "p2p port for connections")
 	keyPathFlag := flag.String("key", "", "path to pem key")
+	inMemFlag := flag.Bool("mem", false, "run vault, pool and chain in memory")
+	selfTestFlag := flag.Bool("selftest", false, "check crypto round-trips before start")
+	configPathFlag := flag.String("config", "", "path to config file, environment variables CERERA_* override it")
 	// logto := flag.String("logto", "stdout", "file path to log to, \"syslog\" or \"stdout\"")
 	flag.Parse()
 
-	cfg := config.GenerageConfig()
+	if *selfTestFlag {
+		if err := types.SelfTest(); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Crypto self-test passed\r\n")
+	}
+
+	var cfg *config.Config
+	if *configPathFlag != "" {
+		var err error
+		if cfg, err = config.LoadConfig(*configPathFlag); err != nil {
+			panic(err)
+		}
+		// ports of config file are used unless flags are set
+		if *listenRpcPortParam == -1 {
+			*listenRpcPortParam = cfg.NetCfg.RPC
+		}
+		if *listenP2pPortParam == -1 {
+			*listenP2pPortParam = cfg.NetCfg.P2P
+		}
+	} else {
+		cfg = config.GenerageConfig()
+	}
 	cfg.SetPorts(*listenRpcPortParam, *listenP2pPortParam)
 	cfg.SetNodeKey(*keyPathFlag)
 	cfg.SetAutoGen(true)
+	if *inMemFlag {
+		cfg.SetInMem(true)
+	}
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
 
 	ctx, _ := signal.NotifyContext(context.Background(), os.Kill, syscall.SIGTERM)
 
@@ -68,7 +109,29 @@ func main() {
 		status: [8]byte{0xf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x0},
 	}
 
+	// blocks of swarm compete with blocks of chain
+	c.h.SetBlockHandler(c.bc.HandleCompetingBlock)
+
+	// services are stopped in reverse order
+	c.registry = service.NewRegistry()
+	c.registry.Register("process", &c.proc)
+	c.registry.Register("vault", c.v)
+	c.registry.Register("pool", c.p)
+	c.registry.Register("validator", c.g)
+	c.registry.Register("chain", &c.bc)
+	c.registry.Register("host", c.h)
+	http.HandleFunc("/healthz", service.HealthHandler(c.registry))
+
 	c.v.Prepare()
+	// history of accounts reads txs from blocks of chain
+	storage.GetVault().SetTxLocator(chain.RunningTxLocator())
+	c.p.SetUp(cfg)
+	// txs pending before restart, saved again when pool is stopped
+	if path := cfg.GetPoolFile(); path != "" {
+		if err := c.p.Load(path, c.g.Signer(), c.v); err != nil {
+			fmt.Printf("WARNING! Pending txs are not loaded: %s\r\n", err)
+		}
+	}
 
 	// coinbase.SetCoinbase()
 
@@ -92,11 +155,24 @@ func main() {
 		time.Sleep(3 * time.Second)
 	}
 
+	// handshake with swarm means voters agreed on chain
+	c.bc.SetConsensus(c.h.NetType == 0x2, c.h.Voters())
+	var info = c.bc.ConsensusInfo()
+	fmt.Printf("Consensus of %s: status %d, voters %d, nodes %d, nonce %d\r\n", info.Address, info.Status, info.Voters, info.Nodes, info.Nonce)
+
 	c.g.SetUp(cfg.Chain.ChainID)
 
-	go s.Execute()
+	safego.Loop("gigea_ring", s.Execute)
+	blocks, _ := c.bc.SubscribeBlocks()
+	safego.Go("block_broadcast", func() { c.h.BroadcastBlocks(blocks) })
+	// every tx accepted by pool is gossiped while node runs
+	var txs, _ = c.p.SubscribeAll()
+	safego.Go("tx_broadcast", func() { c.h.BroadcastTransactions(txs) })
 
 	<-ctx.Done()
-	_ = c.h.Stop()
-	c.proc.Stop()
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.registry.StopAllServices(stopCtx); err != nil {
+		fmt.Printf("Failed to stop services: %s\r\n", err)
+	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// account which balance differs from sum of its inputs
+type auditMismatch struct {
+	Address  types.Address `json:"address"`
+	Balance  *big.Int      `json:"balance"`
+	InputSum *big.Int      `json:"inputSum"`
+	Missing  []common.Hash `json:"missing,omitempty"` // inputs not found in chain
+}
+
+type auditReport struct {
+	Accounts   int             `json:"accounts"`
+	Audited    int             `json:"audited"`
+	Skipped    int             `json:"skipped"` // spent accounts and accounts without inputs
+	Mismatched int             `json:"mismatched"`
+	Mismatches []auditMismatch `json:"mismatches"`
+}
+
+// load all accounts from vault file, last record of address wins
+func readVault(path string) (*storage.AccountsTrie, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the vault file: %w", err)
+	}
+	defer file.Close()
+
+	var accounts = storage.GetAccountsTrie()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var line int
+	for scanner.Scan() {
+		line++
+		sa, err := types.BytesToStateAccountSafe(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse account at line %d: %w", line, err)
+		}
+		accounts.Append(sa.Address, *sa)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the vault file: %w", err)
+	}
+	return accounts, nil
+}
+
+// audit checks that accounts which never spent (nonce is 1)
+// have balance equal to sum of their inputs found in chain
+func audit(accounts *storage.AccountsTrie, blocks []block.Block) auditReport {
+	var values = make(map[common.Hash]*big.Int)
+	for _, b := range blocks {
+		for i := range b.Transactions {
+			values[b.Transactions[i].Hash()] = b.Transactions[i].Value()
+		}
+	}
+
+	var report = auditReport{Mismatches: make([]auditMismatch, 0)}
+	accounts.ForEach(func(sa types.StateAccount) bool {
+		report.Accounts++
+		if sa.Nonce != 1 || len(sa.Inputs) == 0 {
+			report.Skipped++
+			return true
+		}
+		report.Audited++
+		var sum, missing = sa.GetInputSum(values)
+		var balance = sa.Balance
+		if balance == nil {
+			balance = big.NewInt(0)
+		}
+		if sum.Cmp(balance) != 0 || len(missing) > 0 {
+			report.Mismatched++
+			report.Mismatches = append(report.Mismatches, auditMismatch{
+				Address:  sa.Address,
+				Balance:  balance,
+				InputSum: sum,
+				Missing:  missing,
+			})
+		}
+		return true
+	})
+	return report
+}
+
+// runAudit is cereractl audit command
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	vaultPath := fs.String("vault", "./vault.dat", "path to vault file")
+	chainPath := fs.String("chain", "./chain.dat", "path to chain file")
+	fs.Parse(args)
+
+	accounts, err := readVault(*vaultPath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	blocks, err := readChain(*chainPath)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	var report = audit(accounts, blocks)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Println(string(data))
+	if report.Mismatched > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+)
+
+func TestAudit(t *testing.T) {
+	var good, bad, spent = types.Address{0x1}, types.Address{0x2}, types.Address{0x3}
+	var tx1 = types.NewTransaction(1, good, big.NewInt(10), 500, big.NewInt(250), nil)
+	var tx2 = types.NewTransaction(2, good, big.NewInt(5), 500, big.NewInt(250), nil)
+	var tx3 = types.NewTransaction(3, bad, big.NewInt(7), 500, big.NewInt(250), nil)
+	var b = block.NewBlockWithHeader(&block.Header{Height: 1, Number: big.NewInt(1)})
+	b.Transactions = append(b.Transactions, *tx1, *tx2, *tx3)
+
+	var accounts = storage.GetAccountsTrie()
+	accounts.Append(good, types.StateAccount{Address: good, Nonce: 1, Balance: big.NewInt(15), Inputs: []common.Hash{tx1.Hash(), tx2.Hash()}})
+	// balance drifted from inputs
+	accounts.Append(bad, types.StateAccount{Address: bad, Nonce: 1, Balance: big.NewInt(70), Inputs: []common.Hash{tx3.Hash()}})
+	accounts.Append(spent, types.StateAccount{Address: spent, Nonce: 2, Balance: big.NewInt(1), Inputs: []common.Hash{tx3.Hash()}})
+
+	var report = audit(accounts, []block.Block{*b})
+	if report.Accounts != 3 || report.Audited != 2 || report.Skipped != 1 {
+		t.Errorf("Different audit counts, have %+v", report)
+	}
+	if report.Mismatched != 1 || len(report.Mismatches) != 1 || report.Mismatches[0].Address != bad {
+		t.Fatalf("Only inconsistent account should be flagged, have %+v", report.Mismatches)
+	}
+	if report.Mismatches[0].InputSum.Int64() != 7 || report.Mismatches[0].Balance.Int64() != 70 {
+		t.Errorf("Different mismatch values, have %+v", report.Mismatches[0])
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+// CtlCommand is command of cereractl with its code and description
+type CtlCommand struct {
+	Name        string
+	Code        int
+	Description string
+	run         func(args []string) int
+}
+
+// commands of cereractl, first one runs when command is not given
+var ctlCommands = []CtlCommand{
+	{Name: "verify", Code: 0x1, Description: "verify block from chain file by height or hash, or block from json file", run: runVerify},
+	{Name: "audit", Code: 0x2, Description: "check balances of vault file against txs of chain file", run: runAudit},
+	{Name: "consensus-dump", Code: 0x3, Description: "print consensus state of running node", run: runConsensusDump},
+}
+
+// ListCtlCommands returns all commands of cereractl.
+func ListCtlCommands() []CtlCommand {
+	var res = make([]CtlCommand, len(ctlCommands))
+	copy(res, ctlCommands)
+	return res
+}
+
+func findCtlCommand(match func(c CtlCommand) bool) (CtlCommand, error) {
+	for _, c := range ctlCommands {
+		if match(c) {
+			return c, nil
+		}
+	}
+	return CtlCommand{}, ErrUnknownCommand
+}
+
+// ExecuteCtl runs command by its code and returns exit code of command.
+func ExecuteCtl(code int, args []string) (int, error) {
+	c, err := findCtlCommand(func(c CtlCommand) bool { return c.Code == code })
+	if err != nil {
+		return 1, fmt.Errorf("%w: %d", err, code)
+	}
+	return c.run(args), nil
+}
+
+// ExecuteCtlByName runs command by its name and returns exit code of command.
+func ExecuteCtlByName(name string, args []string) (int, error) {
+	c, err := findCtlCommand(func(c CtlCommand) bool { return c.Name == name })
+	if err != nil {
+		return 1, fmt.Errorf("%w: %s", err, name)
+	}
+	return c.run(args), nil
+}
+
+// Usage returns help of cereractl built from list of commands.
+func Usage() string {
+	var sb strings.Builder
+	sb.WriteString("Usage: cereractl [command] [flags]\r\n\r\nCommands:\r\n")
+	for _, c := range ListCtlCommands() {
+		fmt.Fprintf(&sb, "  %-16s %s\r\n", c.Name, c.Description)
+	}
+	fmt.Fprintf(&sb, "\r\nWithout command %s is run. Run cereractl <command> -h for flags of command.\r\n", ctlCommands[0].Name)
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+)
+
+func TestCtlCommands(t *testing.T) {
+	var codes = make(map[int]string)
+	var usage = Usage()
+	for _, c := range ListCtlCommands() {
+		if c.Description == "" || c.run == nil {
+			t.Errorf("Command %s has no description or handler", c.Name)
+		}
+		if other, ok := codes[c.Code]; ok {
+			t.Errorf("Commands %s and %s have same code %d", c.Name, other, c.Code)
+		}
+		codes[c.Code] = c.Name
+		if !strings.Contains(usage, c.Name) || !strings.Contains(usage, c.Description) {
+			t.Errorf("Usage misses command %s", c.Name)
+		}
+	}
+	if _, err := ExecuteCtlByName("unknown", nil); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Expected %s, have %v", ErrUnknownCommand, err)
+	}
+	if _, err := ExecuteCtl(0xff, nil); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Expected %s, have %v", ErrUnknownCommand, err)
+	}
+	// verify of missing chain file fails with exit code 1
+	if code, err := ExecuteCtlByName("verify", []string{"-chain", t.TempDir() + "/missing.dat"}); err != nil || code != 1 {
+		t.Errorf("Expected exit code 1, have %d, %v", code, err)
+	}
+}
+
+func TestVerifyBlockFile(t *testing.T) {
+	// difficulty 1 accepts any hash, so block is sealed as is
+	var b = block.NewBlock(&block.Header{Difficulty: big.NewInt(1), Number: big.NewInt(1), Height: 1})
+	b.Head.Size = int(unsafe.Sizeof(b))
+	var path = filepath.Join(t.TempDir(), "block.json")
+	if err := os.WriteFile(path, b.ToBytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, err := ExecuteCtlByName("verify", []string{"-block", path}); err != nil || code != 0 {
+		t.Errorf("Valid block should pass, have exit code %d, %v", code, err)
+	}
+
+	b.Head.Root = common.HexToHash("0x01")
+	if err := os.WriteFile(path, b.ToBytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, err := ExecuteCtlByName("verify", []string{"-block", path}); err != nil || code != 1 {
+		t.Errorf("Block with wrong root should fail, have exit code %d, %v", code, err)
+	}
+}
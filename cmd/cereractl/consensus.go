@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/config"
+)
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	ID      int           `json:"id"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// request consensus snapshot from running node
+func fetchConsensus(url string) (*chain.ConsensusSnapshot, error) {
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", Method: "consensus", Params: []interface{}{}, ID: 1})
+	if err != nil {
+		return nil, err
+	}
+	var client = http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to request node: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("node responded %s", resp.Status)
+	}
+
+	var res rpcResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var snap chain.ConsensusSnapshot
+	if err := json.Unmarshal(res.Result, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse consensus snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+func runConsensusDump(args []string) int {
+	fs := flag.NewFlagSet("consensus-dump", flag.ExitOnError)
+	url := fs.String("rpc", fmt.Sprintf("http://localhost:%d/", config.DefaultRpcPort), "rpc url of node")
+	fs.Parse(args)
+
+	snap, err := fetchConsensus(*url)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cerera/internal/cerera/chain"
+)
+
+func TestFetchConsensus(t *testing.T) {
+	var want = chain.ConsensusSnapshot{
+		Status:  "started",
+		Started: true,
+		Voters:  2,
+		Nodes:   []chain.ConsensusNode{{ID: "peer-a", Addr: "/ip4/10.0.0.1/tcp/6116", Height: 3}},
+		Nonce:   4,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "consensus" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "result": want, "id": req.ID})
+	}))
+	defer srv.Close()
+
+	snap, err := fetchConsensus(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Status != want.Status || snap.Voters != 2 || snap.Nonce != 4 || len(snap.Nodes) != 1 || snap.Nodes[0].ID != "peer-a" {
+		t.Errorf("Unexpected snapshot %+v", snap)
+	}
+}
@@ -1 +1,119 @@
 package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+)
+
+// load all blocks from chain file, one json block per line
+func readChain(path string) ([]block.Block, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the chain file: %w", err)
+	}
+	defer file.Close()
+
+	var blocks = make([]block.Block, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		b, err := block.FromBytes(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse block %d: %w", len(blocks), err)
+		}
+		blocks = append(blocks, *b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the chain file: %w", err)
+	}
+	return blocks, nil
+}
+
+func readBlock(path string) (*block.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the block file: %w", err)
+	}
+	return block.FromBytes(data)
+}
+
+func main() {
+	var name, args = ctlCommands[0].Name, os.Args[1:]
+	if len(args) > 0 {
+		switch {
+		case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+			fmt.Print(Usage())
+			return
+		case !strings.HasPrefix(args[0], "-"):
+			name, args = args[0], args[1:]
+		}
+	}
+	code, err := ExecuteCtlByName(name, args)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Print(Usage())
+	}
+	os.Exit(code)
+}
+
+// runVerify is cereractl verify command
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	chainPath := fs.String("chain", "./chain.dat", "path to chain file")
+	height := fs.Int("height", -1, "height of block to verify")
+	hashStr := fs.String("hash", "", "hash of block to verify")
+	blockPath := fs.String("block", "", "path to json file with block to verify")
+	parentPath := fs.String("parent", "", "path to json file with parent of block")
+	fs.Parse(args)
+
+	var b, parent *block.Block
+	if *blockPath != "" {
+		var err error
+		if b, err = readBlock(*blockPath); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if *parentPath != "" {
+			if parent, err = readBlock(*parentPath); err != nil {
+				fmt.Println(err)
+				return 1
+			}
+		}
+	} else {
+		blocks, err := readChain(*chainPath)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		var hash = common.HexToHash(*hashStr)
+		for i := range blocks {
+			if (*hashStr != "" && blocks[i].Hash() == hash) || (*hashStr == "" && blocks[i].Head != nil && blocks[i].Head.Height == *height) {
+				b = &blocks[i]
+				if i > 0 {
+					parent = &blocks[i-1]
+				}
+				break
+			}
+		}
+		if b == nil {
+			fmt.Printf("Block not found in %s\r\n", *chainPath)
+			return 1
+		}
+	}
+
+	var report = block.VerifyBlock(b, parent)
+	fmt.Printf("Block %s\r\n", b.Hash())
+	if parent == nil {
+		fmt.Printf("Parent is unknown, linkage checks skipped\r\n")
+	}
+	fmt.Print(report)
+	if !report.Ok() {
+		return 1
+	}
+	return 0
+}
@@ -1,14 +1,19 @@
 package block
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
 	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/coinbase"
 )
 
 var nodeAddress = types.HexToAddress("0x94F369F35D4323dF9980eDF0E1bEdb882C4705e984Bb01aceE5B80F4b6Ad1A81a976278d1245dC6863CfF8ec7F99b5B6")
@@ -218,3 +223,293 @@ func TestHashFunctions(t *testing.T) {
 		t.Errorf("Hash does not match expected value! Expected: %s, given: %s\r\n", expectedHash, block.Hash())
 	}
 }
+
+func TestGenesisBlockDifficulty(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.GenesisDifficulty = big.NewInt(4242)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Valid genesis difficulty rejected: %s", err)
+	}
+	genesis := GenesisBlock(cfg)
+	if genesis.Head.Difficulty.Cmp(big.NewInt(4242)) != 0 {
+		t.Errorf("expected genesis difficulty to be 4242, got %s", genesis.Head.Difficulty)
+	}
+	if genesis.Head.Number.Sign() != 0 {
+		t.Errorf("expected genesis number to be 0, got %s", genesis.Head.Number)
+	}
+
+	cfg.Chain.GenesisDifficulty = big.NewInt(0)
+	if cfg.Validate() == nil {
+		t.Errorf("Zero genesis difficulty should be rejected")
+	}
+}
+
+func TestGenesisBlockDeterministic(t *testing.T) {
+	cfg1 := &config.Config{}
+	cfg1.Chain.GenesisTimestamp = 1700000000000
+	cfg2 := &config.Config{}
+	cfg2.Chain.GenesisTimestamp = 1700000000000
+
+	genesis1 := GenesisBlock(cfg1)
+	time.Sleep(2 * time.Millisecond)
+	genesis2 := GenesisBlock(cfg2)
+	if genesis1.Head.Timestamp != 1700000000000 {
+		t.Errorf("expected genesis timestamp to be %d, got %d", 1700000000000, genesis1.Head.Timestamp)
+	}
+	if genesis1.Head.Timestamp != genesis2.Head.Timestamp {
+		t.Errorf("Different genesis timestamps! Have %d, want %d", genesis2.Head.Timestamp, genesis1.Head.Timestamp)
+	}
+	if genesis1.Hash() != genesis2.Hash() {
+		t.Errorf("Different genesis hashes! Have %s, want %s", genesis2.Hash(), genesis1.Hash())
+	}
+
+	if GenesisBlock(&config.Config{}).Head.Timestamp != config.DefaultGenesisTimestamp {
+		t.Errorf("Genesis without timestamp in config should use default epoch")
+	}
+}
+
+func TestGenesisBlockOfConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(77)
+	cfg.Chain.GenesisDifficulty = big.NewInt(4242)
+	cfg.Chain.GenesisTimestamp = 1700000000000
+	cfg.Chain.GenesisNonce = 5
+	cfg.Chain.GenesisGasLimit = 1000
+
+	var gc = GenesisConfig{ChainId: 77, Difficulty: big.NewInt(4242), Nonce: 5, Timestamp: 1700000000000, GasLimit: 1000}
+	var genesis, expected = GenesisBlock(cfg), GenesisFromConfig(gc)
+	if genesis.Hash() != expected.Hash() {
+		t.Errorf("Genesis of node config should be same as of genesis config, have %s, want %s", genesis.Hash(), expected.Hash())
+	}
+	if genesis.Head.Ctx != 77 || genesis.Nonce != 5 || genesis.Head.GasLimit != 1000 {
+		t.Errorf("Genesis should have parameters of config, have %+v nonce %d", genesis.Head, genesis.Nonce)
+	}
+}
+
+func createTestChild(parent *Block) *Block {
+	header := CopyHeader(parent.Head)
+	header.PrevHash = parent.Hash()
+	header.Number = big.NewInt(0).Add(parent.Head.Number, big.NewInt(1))
+	header.Height = parent.Head.Height + 1
+	header.Timestamp = parent.Head.Timestamp + 1000
+	header.Root = ComputeTxRoot(nil)
+	return NewBlockWithHeader(header)
+}
+
+// sealTestBlock sets size and nonce of block, so its hash meets difficulty
+func sealTestBlock(b *Block) *Block {
+	b.Head.Size = int(unsafe.Sizeof(b))
+	var target = HashTarget(b.Head.Difficulty)
+	for b.Nonce = 0; new(big.Int).SetBytes(b.Hash().Bytes()).Cmp(target) >= 0; b.Nonce++ {
+	}
+	return b
+}
+
+// createVerifiableParent returns test block with valid root and seal
+func createVerifiableParent() *Block {
+	parent := createTestBlock()
+	parent.Head.Root = ComputeTxRoot(parent.Transactions)
+	return sealTestBlock(parent)
+}
+
+func TestVerifyBlock(t *testing.T) {
+	parent := createVerifiableParent()
+	child := sealTestBlock(createTestChild(parent))
+	if report := VerifyBlock(child, parent); !report.Ok() {
+		t.Errorf("Valid block rejected:\r\n%s", report)
+	}
+	if report := VerifyBlock(parent, nil); !report.Ok() {
+		t.Errorf("Valid block without parent rejected:\r\n%s", report)
+	}
+
+	var cases = map[string]func(b *Block){
+		"linkage":    func(b *Block) { b.Head.PrevHash = common.EmptyHash() },
+		"number":     func(b *Block) { b.Head.Number = big.NewInt(100) },
+		"timestamp":  func(b *Block) { b.Head.Timestamp = parent.Head.Timestamp - 1 },
+		"difficulty": func(b *Block) { b.Head.Difficulty = big.NewInt(0) },
+		"gas": func(b *Block) {
+			b.Transactions = append(b.Transactions, *prepareSignedTx())
+			b.Head.Root = ComputeTxRoot(b.Transactions)
+			b.Head.GasUsed = 0
+		},
+		"txroot": func(b *Block) { b.Transactions = append(b.Transactions, *prepareSignedTx()) },
+	}
+	for name, tamper := range cases {
+		tampered := createTestChild(parent)
+		tamper(tampered)
+		report := VerifyBlock(sealTestBlock(tampered), parent)
+		if report.Ok() {
+			t.Errorf("Tampered %s should be rejected", name)
+		}
+		if failed := report.Failed(); len(failed) != 1 || failed[0] != name {
+			t.Errorf("Tampered %s should fail only its check, have %v", name, failed)
+		}
+	}
+
+	// seal is spoiled after block is sealed
+	child.Nonce = -1
+	report := VerifyBlock(child, parent)
+	if failed := report.Failed(); len(failed) != 1 || failed[0] != "pow" {
+		t.Errorf("Unsealed block should fail only pow check, have %v", failed)
+	}
+	if out := report.String(); !strings.Contains(out, ErrInvalidPoW.Error()) || !strings.Contains(out, NonceTooLow.String()) {
+		t.Errorf("Report should tell reason of failed pow, have:\r\n%s", report)
+	}
+}
+
+func TestVerifyCoinbase(t *testing.T) {
+	parent := createVerifiableParent()
+	child := createTestChild(parent)
+	var cb = coinbase.CreateCoinBaseTransation(child.Head.Height, child.Head.Timestamp, child.Head.Node)
+	child.Transactions = append([]types.GTransaction{*cb}, child.Transactions...)
+	if err := VerifyCoinbase(child); err != nil {
+		t.Errorf("Valid coinbase rejected: %s", err)
+	}
+
+	// coinbase survives serialization of block
+	decoded, err := FromBytes(child.ToBytes())
+	if err != nil {
+		t.Fatalf("Error while decode block: %s", err)
+	}
+	if err := VerifyCoinbase(decoded); err != nil {
+		t.Errorf("Valid coinbase rejected after serialization: %s", err)
+	}
+
+	// coinbase of other height has other hash
+	var other = coinbase.CreateCoinBaseTransation(child.Head.Height+1, child.Head.Timestamp, child.Head.Node)
+	if other.Hash() == cb.Hash() {
+		t.Errorf("Coinbase of different heights should have different hashes")
+	}
+
+	var tampered = coinbase.CreateCoinBaseTransation(child.Head.Height, child.Head.Timestamp, child.Head.Node)
+	tampered = types.NewTx(&types.PGTransaction{
+		Nonce:    tampered.Nonce(),
+		To:       tampered.To(),
+		Value:    new(big.Int).Mul(tampered.Value(), big.NewInt(2)),
+		GasPrice: tampered.GasPrice(),
+		Data:     tampered.Data(),
+		Time:     tampered.GetTime(),
+	})
+	child.Transactions[0] = *tampered
+	child.Head.Root = ComputeTxRoot(child.Transactions)
+	sealTestBlock(child)
+	if err := VerifyCoinbase(child); !errors.Is(err, ErrInvalidCoinbase) {
+		t.Errorf("Coinbase with tampered reward should be rejected, have %v", err)
+	}
+	if failed := VerifyBlock(child, parent).Failed(); len(failed) != 1 || failed[0] != "coinbase" {
+		t.Errorf("Tampered coinbase should fail only its check, have %v", failed)
+	}
+}
+
+func TestHashWithNonce(t *testing.T) {
+	var b = NewBlock(&Header{
+		Difficulty: big.NewInt(100),
+		Extra:      []byte("nonce"),
+		Height:     7,
+		Number:     big.NewInt(7),
+		GasLimit:   250000,
+	})
+	b.Confirmations = 3
+	b.Transactions = append(b.Transactions, *types.NewTransaction(1, types.Address{0x1}, big.NewInt(10), 1000, big.NewInt(5), []byte("tx")))
+
+	var hs = b.PrepareHashingState()
+	if hs == nil {
+		t.Fatal("Hashing state of block should be prepared")
+	}
+	for _, nonce := range []int{0, 1, 9, 10, 12345, -7, 1 << 40} {
+		var cpy = *b
+		cpy.Nonce = nonce
+		if h := hs.HashWithNonce(nonce); h != cpy.Hash() {
+			t.Errorf("Nonce %d: different hash, have %s, want %s", nonce, h, cpy.Hash())
+		}
+	}
+}
+
+func TestVerifyBlockHashReason(t *testing.T) {
+	var sealed = func(difficulty int64) *Block {
+		var b = NewBlock(&Header{Difficulty: big.NewInt(difficulty), Number: big.NewInt(1), Height: 1})
+		b.Head.Size = int(unsafe.Sizeof(b))
+		var hs = b.PrepareHashingState()
+		var target = HashTarget(b.Head.Difficulty)
+		for b.Nonce = 0; new(big.Int).SetBytes(hs.HashWithNonce(b.Nonce).Bytes()).Cmp(target) >= 0; b.Nonce++ {
+		}
+		return b
+	}
+
+	var b = sealed(16)
+	if res := VerifyBlockHashWithDetails(b); !res.Valid() || !VerifyBlockHash(b) {
+		t.Errorf("Sealed block should be valid, have %s", res)
+	}
+
+	var cases = map[HashCheckReason]func(b *Block){
+		NonceTooLow:     func(b *Block) { b.Nonce = -1 },
+		DifficultyZero:  func(b *Block) { b.Head.Difficulty = big.NewInt(0) },
+		SizeMismatch:    func(b *Block) { b.Head.Size++ },
+		HashAboveTarget: func(b *Block) { b.Head.Difficulty = new(big.Int).Lsh(big.NewInt(1), 255) },
+	}
+	for reason, spoil := range cases {
+		var b = sealed(16)
+		spoil(b)
+		if res := VerifyBlockHashWithDetails(b); res.Reason != reason || VerifyBlockHash(b) {
+			t.Errorf("Expected reason %s, have %s", reason, res.Reason)
+		}
+	}
+}
+
+func TestGenesisFromConfig(t *testing.T) {
+	var gc = GenesisConfig{ChainId: 77, Difficulty: big.NewInt(4242), Nonce: 5, Timestamp: 1700000000000, GasLimit: 1000}
+	var g1, g2 = GenesisFromConfig(gc), GenesisFromConfig(gc)
+	if g1.Hash() != g2.Hash() {
+		t.Errorf("Genesis of same config should have same hash, have %s and %s", g1.Hash(), g2.Hash())
+	}
+	if g1.Head.Ctx != 77 || g1.Head.Difficulty.Uint64() != 4242 || g1.Nonce != 5 ||
+		g1.Head.Timestamp != 1700000000000 || g1.Head.GasLimit != 1000 {
+		t.Errorf("Genesis should have parameters of config, have %+v nonce %d", g1.Head, g1.Nonce)
+	}
+
+	gc.ChainId = 78
+	if g3 := GenesisFromConfig(gc); g3.Hash() == g1.Hash() {
+		t.Errorf("Genesis of different chains should differ")
+	}
+	var def, empty = Genesis(), GenesisFromConfig(GenesisConfig{})
+	if def.Hash() != empty.Hash() || GenesisHead(0).Hash() != def.Head.Hash() {
+		t.Errorf("Default genesis should be built from empty config")
+	}
+}
+
+func TestComputeTxRoot(t *testing.T) {
+	var txs = make([]types.GTransaction, 0)
+	for i := 0; i < 3; i++ {
+		txs = append(txs, *types.NewTransaction(uint64(i), addr1, big.NewInt(int64(10+i)), 1000, big.NewInt(5), nil))
+	}
+	if root := ComputeTxRoot(nil); root != (common.Hash{}) {
+		t.Errorf("Root without txs should be empty, have %s", root)
+	}
+	if root := ComputeTxRoot(txs[:1]); root != txs[0].Hash() {
+		t.Errorf("Root of single tx should be its hash, have %s", root)
+	}
+
+	var root = ComputeTxRoot(txs)
+	var reordered = []types.GTransaction{txs[1], txs[0], txs[2]}
+	if ComputeTxRoot(reordered) == root {
+		t.Errorf("Reordered txs should change root")
+	}
+	var mutated = append([]types.GTransaction{}, txs...)
+	mutated[2] = *types.NewTransaction(2, addr2, big.NewInt(12), 1000, big.NewInt(5), nil)
+	if ComputeTxRoot(mutated) == root {
+		t.Errorf("Mutated tx should change root")
+	}
+	if ComputeTxRoot(append(append([]types.GTransaction{}, txs...), txs[2])) == root {
+		t.Errorf("Duplicated last tx should change root")
+	}
+
+	var b = NewBlock(&Header{Number: big.NewInt(1), Root: root})
+	b.Transactions = txs
+	if !VerifyTxRoot(b) {
+		t.Errorf("Root of block txs should be verified")
+	}
+	b.Transactions = reordered
+	if VerifyTxRoot(b) {
+		t.Errorf("Root of tampered txs should not be verified")
+	}
+}
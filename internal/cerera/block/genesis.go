@@ -2,36 +2,99 @@ package block
 
 import (
 	"math/big"
-	"time"
 	"unsafe"
 
+	"github.com/cerera/internal/cerera/config"
 	"github.com/cerera/internal/cerera/types"
 )
 
-func Genesis() Block {
-	var genesisHeader = &Header{
-		Ctx:           17,
-		Difficulty:    big.NewInt(11111111111),
+// default parameters of genesis block
+const (
+	DefaultGenesisCtx      = 17
+	DefaultGenesisNonce    = 11
+	DefaultGenesisGasLimit = 250000
+)
+
+// GenesisConfig contains parameters of genesis block, zero values are replaced by defaults.
+// Nodes of private network with the same config build the same genesis.
+type GenesisConfig struct {
+	ChainId    int
+	Difficulty *big.Int
+	Nonce      uint64
+	Timestamp  uint64
+	GasLimit   uint64
+}
+
+// GenesisHeadFromConfig returns header of genesis block with parameters from config.
+func GenesisHeadFromConfig(gc GenesisConfig) *Header {
+	var head = &Header{
+		Ctx:           DefaultGenesisCtx,
+		Difficulty:    new(big.Int).Set(config.DefaultGenesisDifficulty),
 		Extra:         []byte("GENESYS BLOCK VAVILOV PROTOCOL"),
 		Height:        0,
-		Timestamp:     uint64(time.Now().UnixMilli()),
-		GasLimit:      250000,
+		Timestamp:     config.DefaultGenesisTimestamp,
+		GasLimit:      DefaultGenesisGasLimit,
 		GasUsed:       11,
 		Number:        big.NewInt(0),
 		Confirmations: 1,
 		Node:          types.EmptyAddress(),
 		Size:          0,
 	}
+	if gc.ChainId != 0 {
+		head.Ctx = gc.ChainId
+	}
+	if gc.Difficulty != nil {
+		head.Difficulty = new(big.Int).Set(gc.Difficulty)
+	}
+	if gc.Timestamp != 0 {
+		head.Timestamp = gc.Timestamp
+	}
+	if gc.GasLimit != 0 {
+		head.GasLimit = gc.GasLimit
+	}
+	return head
+}
+
+// GenesisHead returns header of genesis block with default parameters for chain.
+func GenesisHead(chainId int) *Header {
+	return GenesisHeadFromConfig(GenesisConfig{ChainId: chainId})
+}
 
-	// genesisHeader.HashH = rlpHeaderHash(*genesisHeader)
+// GenesisFromConfig returns genesis block with parameters from config.
+func GenesisFromConfig(gc GenesisConfig) Block {
 	var genesisBlock = Block{
-		Head:  genesisHeader,
-		Nonce: 11,
+		Head:  GenesisHeadFromConfig(gc),
+		Nonce: DefaultGenesisNonce,
+	}
+	if gc.Nonce != 0 {
+		genesisBlock.Nonce = int(gc.Nonce)
 	}
-	// genesisBlock.HashB = rlpBlockHash(*genesisBlock)
 	genesisBlock.Transactions = []types.GTransaction{}
-	//make([]common.Hash, 0)
 	var finalSize = unsafe.Sizeof(genesisBlock)
 	genesisBlock.Head.Size = int(finalSize)
 	return genesisBlock
 }
+
+// GenesisConfigOf returns parameters of genesis block from config of node.
+func GenesisConfigOf(cfg *config.Config) GenesisConfig {
+	var gc = GenesisConfig{
+		Difficulty: cfg.GetGenesisDifficulty(),
+		Nonce:      cfg.Chain.GenesisNonce,
+		Timestamp:  cfg.GetGenesisTimestamp(),
+		GasLimit:   cfg.Chain.GenesisGasLimit,
+	}
+	if cfg.Chain.ChainID != nil {
+		gc.ChainId = int(cfg.Chain.ChainID.Int64())
+	}
+	return gc
+}
+
+// GenesisBlock returns genesis block with parameters from config.
+// Every node with the same config builds the same genesis.
+func GenesisBlock(cfg *config.Config) Block {
+	return GenesisFromConfig(GenesisConfigOf(cfg))
+}
+
+func Genesis() Block {
+	return GenesisFromConfig(GenesisConfig{})
+}
@@ -0,0 +1,50 @@
+package block
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/cerera/internal/cerera/common"
+	"golang.org/x/crypto/blake2b"
+)
+
+var nonceKey = []byte(`"nonce":`)
+
+// HashState is serialized block split around nonce, so hash of block with
+// another nonce does not serialize header and transactions again. It is read
+// only and can be shared by several sealing workers.
+type HashState struct {
+	prefix []byte // serialized block up to value of nonce
+	suffix []byte // serialized block after value of nonce
+}
+
+// PrepareHashingState serializes block once for nonce search,
+// nil is returned if block can not be serialized.
+func (b *Block) PrepareHashingState() *HashState {
+	var data = b.ToBytes()
+	// nonce is the first string key after numeric confirmations
+	var i = bytes.Index(data, nonceKey)
+	if i < 0 {
+		return nil
+	}
+	i += len(nonceKey)
+	var j = i
+	if j < len(data) && data[j] == '-' {
+		j++
+	}
+	for j < len(data) && data[j] >= '0' && data[j] <= '9' {
+		j++
+	}
+	return &HashState{prefix: data[:i], suffix: data[j:]}
+}
+
+// HashWithNonce returns same hash as Hash of block with given nonce.
+func (hs *HashState) HashWithNonce(nonce int) (h common.Hash) {
+	hw, _ := blake2b.New256(nil)
+	var num [20]byte
+	hw.Write(hs.prefix)
+	hw.Write(strconv.AppendInt(num[:0], int64(nonce), 10))
+	hw.Write(hs.suffix)
+	h.SetBytes(hw.Sum(nil))
+	return h
+}
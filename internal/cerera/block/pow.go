@@ -0,0 +1,92 @@
+package block
+
+import (
+	"fmt"
+	"math/big"
+	"unsafe"
+
+	"github.com/cerera/internal/cerera/common"
+)
+
+var maxTarget = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// HashTarget returns max value of block hash for difficulty.
+func HashTarget(difficulty *big.Int) *big.Int {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return new(big.Int).Set(maxTarget)
+	}
+	return new(big.Int).Div(maxTarget, difficulty)
+}
+
+// HashCheckReason is cause of failed check of block hash
+type HashCheckReason int
+
+const (
+	HashValid HashCheckReason = iota
+	NonceTooLow
+	DifficultyZero
+	SizeMismatch
+	HashAboveTarget
+)
+
+func (r HashCheckReason) String() string {
+	switch r {
+	case HashValid:
+		return "valid"
+	case NonceTooLow:
+		return "nonce too low"
+	case DifficultyZero:
+		return "difficulty zero"
+	case SizeMismatch:
+		return "size mismatch"
+	case HashAboveTarget:
+		return "hash above target"
+	default:
+		return fmt.Sprintf("unknown reason %d", int(r))
+	}
+}
+
+// HashCheckResult contains details of check of block hash.
+type HashCheckResult struct {
+	Hash   common.Hash
+	Target *big.Int
+	Reason HashCheckReason
+}
+
+// Valid returns true if block hash passed the check.
+func (r HashCheckResult) Valid() bool {
+	return r.Reason == HashValid
+}
+
+func (r HashCheckResult) String() string {
+	if r.Valid() {
+		return fmt.Sprintf("hash %s OK", r.Hash)
+	}
+	return fmt.Sprintf("hash %s FAIL: %s, target %x", r.Hash, r.Reason, r.Target)
+}
+
+// VerifyBlockHashWithDetails checks that block is sealed: nonce is not
+// negative, difficulty is positive, size is the one set by generator and hash
+// is below target of difficulty. Reason of result tells first failed check.
+func VerifyBlockHashWithDetails(b *Block) HashCheckResult {
+	var res = HashCheckResult{Hash: b.Hash()}
+	if b.Head == nil || b.Head.Difficulty == nil || b.Head.Difficulty.Sign() <= 0 {
+		res.Reason = DifficultyZero
+		return res
+	}
+	res.Target = HashTarget(b.Head.Difficulty)
+	switch {
+	case b.Nonce < 0:
+		res.Reason = NonceTooLow
+	case b.Head.Size != int(unsafe.Sizeof(b)):
+		res.Reason = SizeMismatch
+	case new(big.Int).SetBytes(res.Hash.Bytes()).Cmp(res.Target) >= 0:
+		res.Reason = HashAboveTarget
+	}
+	return res
+}
+
+// VerifyBlockHash returns true if block hash passes all checks.
+func VerifyBlockHash(b *Block) bool {
+	return VerifyBlockHashWithDetails(b).Valid()
+}
@@ -0,0 +1,46 @@
+package block
+
+import (
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ComputeTxRoot returns root of binary merkle tree over hashes of txs in
+// their order. Root of single tx is its hash, block without txs has empty root.
+// Node without pair is moved to the next level unchanged, so duplicating
+// last tx does not give the same root.
+func ComputeTxRoot(txs []types.GTransaction) common.Hash {
+	if len(txs) == 0 {
+		return common.Hash{}
+	}
+	var level = make([]common.Hash, len(txs))
+	for i := range txs {
+		level[i] = txs[i].Hash()
+	}
+	for len(level) > 1 {
+		var next = make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+func hashPair(left, right common.Hash) (h common.Hash) {
+	hw, _ := blake2b.New256(nil)
+	hw.Write(left.Bytes())
+	hw.Write(right.Bytes())
+	h.SetBytes(hw.Sum(nil))
+	return h
+}
+
+// VerifyTxRoot returns true if root of block header matches its txs.
+func VerifyTxRoot(b *Block) bool {
+	return b.Head != nil && b.Head.Root == ComputeTxRoot(b.Transactions)
+}
@@ -0,0 +1,154 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cerera/internal/coinbase"
+)
+
+var (
+	ErrNoHeader          = errors.New("block has no header")
+	ErrInvalidPrevHash   = errors.New("previous hash does not match parent")
+	ErrInvalidNumber     = errors.New("number does not follow parent")
+	ErrInvalidTimestamp  = errors.New("timestamp is older than parent")
+	ErrGasUsedTooLow     = errors.New("gas used is lower than gas of transactions")
+	ErrInvalidDifficulty = errors.New("difficulty should be positive")
+	ErrInvalidCoinbase   = errors.New("coinbase transaction does not match block")
+	ErrInvalidPoW        = errors.New("proof of work is not valid")
+	ErrInvalidTxRoot     = errors.New("root does not match transactions")
+)
+
+// single named check of block
+type VerifyCheck struct {
+	Name string
+	Err  error
+}
+
+// VerifyReport contains results of all checks of block.
+type VerifyReport struct {
+	Checks []VerifyCheck
+}
+
+func (r *VerifyReport) add(name string, err error) {
+	r.Checks = append(r.Checks, VerifyCheck{Name: name, Err: err})
+}
+
+// Ok returns true if every check of block is passed.
+func (r *VerifyReport) Ok() bool {
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns names of not passed checks.
+func (r *VerifyReport) Failed() []string {
+	var res = make([]string, 0)
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			res = append(res, c.Name)
+		}
+	}
+	return res
+}
+
+func (r *VerifyReport) String() string {
+	var res = ""
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			res += fmt.Sprintf("%-10s FAIL: %s\r\n", c.Name, c.Err)
+		} else {
+			res += fmt.Sprintf("%-10s OK\r\n", c.Name)
+		}
+	}
+	return res
+}
+
+// VerifyBlock checks block itself and its linkage with parent.
+// Parent may be nil for genesis block or when it is unknown,
+// then linkage checks are skipped. Proof of work is checked only when
+// difficulty is valid, its target depends on difficulty.
+func VerifyBlock(b *Block, parent *Block) *VerifyReport {
+	var report = &VerifyReport{}
+	if b.Head == nil {
+		report.add("header", ErrNoHeader)
+		return report
+	}
+	report.add("header", nil)
+
+	if b.Head.Difficulty == nil || b.Head.Difficulty.Sign() <= 0 {
+		report.add("difficulty", ErrInvalidDifficulty)
+	} else {
+		report.add("difficulty", nil)
+		if res := VerifyBlockHashWithDetails(b); !res.Valid() {
+			report.add("pow", fmt.Errorf("%w: %s, hash %s, target %x", ErrInvalidPoW, res.Reason, res.Hash, res.Target))
+		} else {
+			report.add("pow", nil)
+		}
+	}
+
+	if !VerifyTxRoot(b) {
+		report.add("txroot", fmt.Errorf("%w: have %s, want %s", ErrInvalidTxRoot, b.Head.Root, ComputeTxRoot(b.Transactions)))
+	} else {
+		report.add("txroot", nil)
+	}
+
+	var txGas uint64
+	for _, tx := range b.Transactions {
+		txGas += tx.Gas()
+	}
+	if b.Head.GasUsed < txGas {
+		report.add("gas", ErrGasUsedTooLow)
+	} else {
+		report.add("gas", nil)
+	}
+
+	report.add("coinbase", VerifyCoinbase(b))
+
+	if parent == nil || parent.Head == nil {
+		return report
+	}
+
+	if b.Head.PrevHash != parent.Hash() {
+		report.add("linkage", ErrInvalidPrevHash)
+	} else {
+		report.add("linkage", nil)
+	}
+
+	var expectedNumber = new(big.Int).Add(parent.Head.Number, big.NewInt(1))
+	if b.Head.Number == nil || b.Head.Number.Cmp(expectedNumber) != 0 || b.Head.Height != parent.Head.Height+1 {
+		report.add("number", ErrInvalidNumber)
+	} else {
+		report.add("number", nil)
+	}
+
+	if b.Head.Timestamp < parent.Head.Timestamp {
+		report.add("timestamp", ErrInvalidTimestamp)
+	} else {
+		report.add("timestamp", nil)
+	}
+	return report
+}
+
+// VerifyCoinbase checks that coinbase tx of block, if any, is first tx and
+// equals to expected one for height, reward schedule and node of block.
+func VerifyCoinbase(b *Block) error {
+	for i := range b.Transactions {
+		var tx = &b.Transactions[i]
+		if !coinbase.IsCoinBaseTransaction(tx) {
+			continue
+		}
+		if i != 0 {
+			return fmt.Errorf("%w: coinbase at position %d", ErrInvalidCoinbase, i)
+		}
+		var expected = coinbase.CreateCoinBaseTransation(b.Head.Height, b.Head.Timestamp, b.Head.Node)
+		if tx.Hash() != expected.Hash() {
+			return fmt.Errorf("%w: have %s, want %s", ErrInvalidCoinbase, tx.Hash(), expected.Hash())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package chain
+
+import (
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+// blockFeed delivers every block generated by node to subscribers in order.
+// Unlike headFeed it drops nothing: blocks wait in queue of subscriber until
+// they are read, so blocks generated in burst are all broadcasted.
+type blockFeed struct {
+	mu   sync.Mutex
+	subs []*blockSub
+}
+
+type blockSub struct {
+	mu     sync.Mutex
+	queue  []*block.Block
+	signal chan struct{} // queue is not empty
+	out    chan *block.Block
+	done   chan struct{}
+}
+
+func newBlockFeed() *blockFeed {
+	return &blockFeed{
+		subs: make([]*blockSub, 0),
+	}
+}
+
+func (f *blockFeed) subscribe() (<-chan *block.Block, func()) {
+	var sub = &blockSub{
+		signal: make(chan struct{}, 1),
+		out:    make(chan *block.Block),
+		done:   make(chan struct{}),
+	}
+	f.mu.Lock()
+	f.subs = append(f.subs, sub)
+	f.mu.Unlock()
+	go sub.forward()
+
+	var once sync.Once
+	return sub.out, func() {
+		once.Do(func() { f.unsubscribe(sub) })
+	}
+}
+
+func (f *blockFeed) unsubscribe(sub *blockSub) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.subs {
+		if f.subs[i] == sub {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			break
+		}
+	}
+	close(sub.done)
+}
+
+func (f *blockFeed) send(b *block.Block) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, sub := range f.subs {
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, b)
+		sub.mu.Unlock()
+		select {
+		case sub.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// forward moves queued blocks to channel of subscriber until it unsubscribes
+func (s *blockSub) forward() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.signal:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		var b = s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.out <- b:
+		case <-s.done:
+			return
+		}
+	}
+}
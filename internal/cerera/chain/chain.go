@@ -4,18 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 	"unsafe"
 
 	"github.com/cerera/internal/cerera/block"
 	"github.com/cerera/internal/cerera/common"
 	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/logger"
 	"github.com/cerera/internal/cerera/pool"
+	"github.com/cerera/internal/cerera/safego"
+	"github.com/cerera/internal/cerera/storage"
 	"github.com/cerera/internal/cerera/trie"
 	"github.com/cerera/internal/cerera/types"
 	"github.com/cerera/internal/cerera/validator"
+	"github.com/cerera/internal/coinbase"
 )
 
+var minerLog = logger.Named("miner")
+
 type BlockChainStatus struct {
 	Total     int         `json:"total,omitempty"`
 	ChainWork int         `json:"chainWork,omitempty"`
@@ -23,19 +30,58 @@ type BlockChainStatus struct {
 	Size      int64       `json:"size,omitempty"`
 }
 
+// chainState keeps blocks of chain, shared by copies of chain. mu is held
+// for writing by generator and reorg while blocks change and for reading by
+// readers of blocks.
+type chainState struct {
+	mu            sync.RWMutex
+	blockInterval time.Duration // target time between blocks
+	currentBlock  *block.Block
+	info          BlockChainStatus
+	data          []block.Block
+	t             *trie.MerkleTree
+}
+
+// generatorState stops block generator of chain, shared by copies of chain.
+type generatorState struct {
+	once sync.Once
+	quit chan struct{} // closed to stop generator
+	done chan struct{} // closed when generator has returned
+}
+
+func newGeneratorState() *generatorState {
+	return &generatorState{quit: make(chan struct{}), done: make(chan struct{})}
+}
+
+// stop ends generator and waits until block being built is dropped or added
+func (g *generatorState) stop() {
+	g.once.Do(func() { close(g.quit) })
+	<-g.done
+}
+
 type Chain struct {
+	*chainState
+	gen            *generatorState
 	autoGen        bool
+	intervals      chan time.Duration // new block intervals applied by generator
 	chainId        *big.Int
 	chainWork      *big.Int
 	currentAddress types.Address
-	currentBlock   *block.Block
+	heads          *headFeed
+	blocks         *blockFeed // blocks generated by node, for broadcast
+	inMem          bool
+	memos          *memoIndex // nil when memo index is disabled
+	txs            *txCache   // txs found by hash
+	tracker        *syncTracker
+	consensus      *consensusState
+	nonceFile      string // file of consensus nonce, empty for in memory chain
+	miningPolicy   string // empty means chosen by count of voters
+	pause          *pauseState
+	sealWorkers    int         // goroutines searching nonce of generated block
+	index          *blockIndex // blocks by height and hash
+	forks          *forkState  // competing blocks and undo of applied blocks
 	// rootHash       common.Hash
 
-	// mu sync.Mutex
-	info BlockChainStatus
-	data []block.Block
-	t    *trie.MerkleTree
-
 	// tickers
 	maintainTicker *time.Ticker
 	blockTicker    *time.Ticker
@@ -49,17 +95,11 @@ func GetBlockChain() Chain {
 }
 func InitBlockChain(cfg *config.Config) Chain {
 
-	genesisBlock := block.Genesis()
 	dataBlocks := make([]block.Block, 0)
 
-	var t *trie.MerkleTree
-	if cfg.Chain.Path == "EMPTY" {
-		var list []trie.Content
-		list = append(list, genesisBlock)
-		t, _ = trie.NewTree(list)
-		// init with genesis empty cfg
-		InitChainVault(genesisBlock)
-		dataBlocks = append(dataBlocks, genesisBlock)
+	if cfg.Chain.MEM {
+		// in memory chain starts from genesis every run
+	} else if cfg.Chain.Path == "EMPTY" {
 		cfg.UpdateChainPath("./chain.dat")
 	} else {
 		var readBlock, err = SyncVault()
@@ -68,63 +108,141 @@ func InitBlockChain(cfg *config.Config) Chain {
 		}
 		dataBlocks = append(dataBlocks, readBlock...)
 		// validate added blocks
-		lastCorrect, errorBlock := ValidateBlocks(dataBlocks)
-		if errorBlock != nil {
-			fmt.Printf("ERROR BLOCK! %s\r\n", errorBlock)
+		if len(dataBlocks) > 0 {
+			lastCorrect, errorBlock := ValidateBlocks(dataBlocks)
+			if errorBlock != nil {
+				fmt.Printf("ERROR BLOCK! %s\r\n", errorBlock)
+			}
+			dataBlocks = dataBlocks[:lastCorrect]
 		}
-		dataBlocks = dataBlocks[:lastCorrect]
+	}
 
-		var list []trie.Content
-		for _, v := range dataBlocks {
-			list = append(list, v)
+	// empty chain starts from genesis, it is created only by genesis producer,
+	// other nodes wait for genesis from peers
+	if len(dataBlocks) == 0 && cfg.IsGenesisProducer() {
+		genesisBlock := block.GenesisBlock(cfg)
+		if !cfg.Chain.MEM {
+			InitChainVault(genesisBlock)
 		}
+		dataBlocks = append(dataBlocks, genesisBlock)
+	}
 
-		t, _ = trie.NewTree(list)
-		t.VerifyTree()
+	var memos *memoIndex
+	if cfg.Chain.MemoIndex {
+		memos = newMemoIndex()
+		for i := range dataBlocks {
+			memos.addBlock(&dataBlocks[i])
+		}
 	}
 
+	var t *trie.MerkleTree
+	var currentBlock *block.Block
 	stats := BlockChainStatus{
 		Total:     0,
 		ChainWork: 0,
-		Latest:    dataBlocks[len(dataBlocks)-1].Hash(),
 		Size:      0,
 	}
+	if len(dataBlocks) > 0 {
+		var list []trie.Content
+		for _, v := range dataBlocks {
+			list = append(list, v)
+		}
+		t, _ = trie.NewTree(list)
+		t.VerifyTree()
+
+		currentBlock = &dataBlocks[len(dataBlocks)-1]
+		stats.Latest = currentBlock.Hash()
+	} else {
+		fmt.Printf("Chain is empty, waiting for genesis block\r\n")
+	}
 
+	// chain of process is replaced, generator of previous one stops writing to vault
+	if bch.gen != nil {
+		bch.gen.stop()
+	}
 	bch = Chain{
+		chainState: &chainState{
+			blockInterval: cfg.GetTargetBlockInterval(),
+			currentBlock:  currentBlock,
+			info:          stats,
+			data:          dataBlocks,
+			t:             t,
+		},
+		gen:            newGeneratorState(),
 		autoGen:        cfg.AUTOGEN,
 		chainId:        cfg.Chain.ChainID,
 		chainWork:      big.NewInt(1),
-		currentBlock:   &dataBlocks[len(dataBlocks)-1],
-		blockTicker:    time.NewTicker(time.Duration(10 * time.Second)),
+		heads:          newHeadFeed(),
+		blocks:         newBlockFeed(),
+		inMem:          cfg.Chain.MEM,
+		memos:          memos,
+		txs:            newTxCache(cfg.GetTxCacheSize()),
+		tracker:        newSyncTracker(),
+		consensus:      newConsensusState(cfg.Chain.MinVoters),
+		nonceFile:      cfg.GetNonceFile(),
+		miningPolicy:   cfg.Chain.MiningPolicy,
+		pause:          &pauseState{},
+		sealWorkers:    cfg.GetSealWorkers(),
+		index:          newBlockIndex(dataBlocks),
+		forks:          newForkState(),
+		blockTicker:    time.NewTicker(cfg.GetTargetBlockInterval()),
+		intervals:      make(chan time.Duration, 1),
 		maintainTicker: time.NewTicker(time.Duration(5 * time.Minute)),
-		info:           stats,
-		data:           dataBlocks,
 		currentAddress: cfg.NetCfg.ADDR,
-		t:              t,
+	}
+	if bch.nonceFile != "" {
+		if err := bch.LoadNonce(bch.nonceFile); err != nil {
+			fmt.Printf("WARNING! Consensus nonce is not loaded, start from 0: %s\r\n", err)
+		}
 	}
 	// genesisBlock.Head.Node = bch.currentAddress
-	go bch.BlockGenerator()
+	// generator runs on own copy of chain, blocks are shared by chain state
+	var gen = bch
+	safego.Loop("block_generator", gen.BlockGenerator)
 	return bch
 }
 
 func (bc *Chain) GetInfo() interface{} {
-	var bcs, err = GetChainSourceSize()
-	if err != nil {
-		bc.info.Size = -1
-	} else {
-		bc.info.Size = bcs
+	bc.mu.RLock()
+	var info = bc.info
+	info.Total = len(bc.data)
+	if len(bc.data) > 0 {
+		info.Latest = bc.data[len(bc.data)-1].Hash()
 	}
-	bc.info.Total = len(bc.data)
-	bc.info.Latest = bc.data[len(bc.data)-1].Hash()
+	bc.mu.RUnlock()
 
-	return bc.info
+	if bc.inMem {
+		info.Size = 0
+	} else if bcs, err := GetChainSourceSize(); err != nil {
+		info.Size = -1
+	} else {
+		info.Size = bcs
+	}
+	return info
 }
 
 func (bc Chain) GetLatestBlock() *block.Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	return bc.currentBlock
 }
 
+// SubscribeHead returns channel which receives new tip of chain
+// every time it changes, so block producers can rebuild on it at once,
+// and function to unsubscribe which closes channel.
+func (bc Chain) SubscribeHead() (<-chan *block.Block, func()) {
+	return bc.heads.subscribe()
+}
+
+// SubscribeBlocks returns channel which receives every block generated by
+// node in order, none of them is dropped, and function to unsubscribe.
+func (bc Chain) SubscribeBlocks() (<-chan *block.Block, func()) {
+	return bc.blocks.subscribe()
+}
+
 func (bc Chain) GetBlockHash(number int) common.Hash {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	for _, b := range bc.data {
 		if b.Header().Number.Cmp(big.NewInt(int64(number))) == 0 {
 			return b.Hash()
@@ -135,6 +253,8 @@ func (bc Chain) GetBlockHash(number int) common.Hash {
 
 func (bc Chain) GetBlock(blockHash string) *block.Block {
 	var bHash = common.HexToHash(blockHash)
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	for _, b := range bc.data {
 		if b.Hash().Compare(bHash) == 0 {
 			return &b
@@ -145,6 +265,8 @@ func (bc Chain) GetBlock(blockHash string) *block.Block {
 
 func (bc Chain) GetBlockHeader(blockHash string) *block.Header {
 	var bHash = common.HexToHash(blockHash)
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	for _, b := range bc.data {
 		if b.Hash().Compare(bHash) == 0 {
 			return b.Header()
@@ -154,41 +276,92 @@ func (bc Chain) GetBlockHeader(blockHash string) *block.Header {
 }
 
 func (bc *Chain) BlockGenerator() {
+	heads, cancel := bc.SubscribeHead()
+	defer cancel()
 	for {
 		select {
+		case <-bc.gen.quit:
+			close(bc.gen.done)
+			return
 		case <-bc.blockTicker.C:
-			var latest = bc.GetLatestBlock()
-			if bc.autoGen {
-				bc.G(latest)
+			bc.generate()
+		case head := <-heads:
+			// tip of other node makes next block stale, it is rebuilt on new tip at once
+			if head.Head.Node != bc.currentAddress && bc.generate() {
+				bc.blockTicker.Reset(bc.blockInterval)
 			}
+		case d := <-bc.intervals:
+			bc.mu.Lock()
+			bc.blockInterval = d
+			bc.mu.Unlock()
+			bc.blockTicker.Reset(d)
 		case <-bc.maintainTicker.C:
 			continue
 		}
 	}
 }
 
-func (bc *Chain) G(latest *block.Block) {
+// generate builds next block when node is allowed to
+func (bc *Chain) generate() bool {
+	var latest = bc.GetLatestBlock()
+	// nothing to build on until genesis arrives
+	if latest == nil || !bc.autoGen {
+		return false
+	}
+	// blocks on top of stale tip would be orphaned
+	if !bc.IsSynced() {
+		return false
+	}
+	if !bc.canMine() || bc.pause.get() {
+		return false
+	}
+	return bc.G(latest)
+}
+
+// G builds block on top of latest and adds it to chain,
+// block is dropped when generation was paused meanwhile.
+func (bc *Chain) G(latest *block.Block) bool {
 	var vld = validator.Get()
 	var pool = pool.Get()
+	bc.mu.RLock()
+	var difficulty, mtp = bc.nextDifficulty(), medianTimestamp(bc.data)
+	bc.mu.RUnlock()
 	head := &block.Header{
 		Ctx:           latest.Header().Ctx,
-		Difficulty:    latest.Header().Difficulty,
+		Difficulty:    difficulty,
 		Extra:         []byte("OP_AUTO_GEN_BLOCK_DAT"),
 		Height:        latest.Header().Height + 1,
 		Index:         latest.Header().Index + 1,
 		Timestamp:     uint64(time.Now().UnixMilli()),
 		Number:        big.NewInt(0).Add(latest.Header().Number, big.NewInt(1)),
-		PrevHash:      bc.info.Latest,
+		PrevHash:      latest.Hash(),
 		Confirmations: 1,
 		Node:          bc.currentAddress,
-		Root:          latest.Header().Root,
 		// GasLimit:  bc.,
 	}
+	// clock of node may go back, block still should be after median time past
+	if head.Timestamp <= mtp {
+		head.Timestamp = mtp + 1
+	}
 	newBlock := block.NewBlockWithHeader(head)
-	// TODO refactor
-	if len(pool.Prepared) > 0 {
-		for _, tx := range pool.Prepared {
-			if vld.ValidateTransaction(tx, tx.From()) {
+	// tip which comes while block is built makes it stale
+	heads, unsubscribe := bc.SubscribeHead()
+	defer unsubscribe()
+	// vault file is written once for reward and all txs of block
+	var batch = storage.GetVault().BeginBatch()
+	// reward of block is first tx
+	var reward = coinbase.CreateCoinBaseTransation(head.Height, head.Timestamp, bc.currentAddress)
+	newBlock.Transactions = append(newBlock.Transactions, *reward)
+	if err := batch.Credit(*reward.To(), reward.Value()); err != nil {
+		minerLog.Warnf("Reward of block %d is not credited: %s", head.Height, err)
+		return false
+	}
+	// txs with higher gas price are included first, txs after nonce gap wait
+	var pending = pool.ReadyTransactions(storage.GetVault())
+	if len(pending) > 0 {
+		for i := range pending {
+			var tx = &pending[i]
+			if vld.ExecuteTransaction(tx, tx.From(), batch) {
 				newBlock.Transactions = append(newBlock.Transactions, *tx)
 				newBlock.Head.GasUsed += tx.Gas()
 				// newBlock.SetTransaction(tx)
@@ -196,34 +369,114 @@ func (bc *Chain) G(latest *block.Block) {
 		}
 	}
 
-	newBlock.Nonce = latest.Nonce
+	newBlock.Head.Root = block.ComputeTxRoot(newBlock.Transactions)
 
 	var finalSize = unsafe.Sizeof(newBlock)
 	newBlock.Head.Size = int(finalSize)
 	newBlock.Head.GasUsed += uint64(finalSize)
 
+	// search of nonce stops when new tip comes, block is rebuilt on it
+	var abort, sealed = make(chan struct{}), make(chan struct{})
+	go func() {
+		select {
+		case <-heads:
+			close(abort)
+		case <-bc.gen.quit:
+			close(abort)
+		case <-sealed:
+		}
+	}()
+	_, serr := Seal(newBlock, abort, bc.sealWorkers)
+	close(sealed)
+	if serr != nil {
+		minerLog.Warnf("Block %d is not sealed: %s", head.Height, serr)
+		batch.Rollback()
+		return false
+	}
+
+	if !bc.addGenerated(newBlock, batch) {
+		return false
+	}
+
+	// clear txs tried for block, queued ones stay
+	var tried = make([]common.Hash, 0, len(pending))
+	for i := range pending {
+		tried = append(tried, pending[i].Hash())
+	}
+	pool.RemovePrepared(tried)
+	// drop pending txs which became invalid after block
+	pool.Reconcile(storage.GetVault())
+	return true
+}
+
+// addGenerated adds sealed block on top of chain and commits batch of it.
+// Block is dropped with effects of its txs when generation was paused or tip
+// changed while block was built, txs stay in pool.
+func (bc *Chain) addGenerated(newBlock *block.Block, batch *storage.VaultBatch) bool {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.pause.get() {
+		batch.Rollback()
+		return false
+	}
+	if perr := bc.proposeBlock(newBlock); perr != nil {
+		minerLog.Warnf("Block %d is rejected: %s", newBlock.Head.Height, perr)
+		blocksRejected.Inc()
+		batch.Rollback()
+		return false
+	}
+	if err := batch.Commit(); err != nil {
+		minerLog.Warnf("Block is dropped, vault is not written: %s", err)
+		return false
+	}
+	bc.forks.keepUndo(newBlock, batch.Undo())
+
 	bc.data = append(bc.data, *newBlock)
+	bc.index.add(newBlock, len(bc.data)-1)
 
 	bc.t.Add(newBlock)
 	var t, err = bc.t.VerifyTree()
 	if err != nil || !t {
-		fmt.Printf("Verifying trie: %s\r\n", err)
+		minerLog.Warnf("Verifying trie: %s", err)
 	} else {
 		bc.info.Latest = newBlock.Hash()
 		bc.info.Total = bc.info.Total + 1
 		bc.info.ChainWork = bc.info.ChainWork + newBlock.Head.Size
 		bc.currentBlock = newBlock
-		SaveToVault(*newBlock)
+		if !bc.inMem {
+			SaveToVault(*newBlock)
+		}
+		bc.memos.addBlock(newBlock)
+		storage.GetVault().CommitHeight(newBlock.Head.Height)
+		bc.heads.send(newBlock)
+		bc.blocks.send(newBlock)
+		blocksMined.Inc()
 	}
-
-	// clear array with included txs
-	pool.Prepared = nil
+	return true
 }
 
 // change block generation time
 // val multiply by milliseconds (ms)
 func (bc *Chain) ChangeBlockInterval(val int) {
-	bc.blockTicker.Reset(time.Duration(time.Duration(val) * time.Millisecond))
+	bc.SetInterval(time.Duration(val) * time.Millisecond)
+}
+
+// SetInterval changes time between generated blocks of running chain.
+// Ticker is reset by generator goroutine, the latest interval wins.
+func (bc *Chain) SetInterval(d time.Duration) error {
+	if d <= 0 {
+		return config.ErrInvalidBlockInterval
+	}
+	// replace interval which is not applied yet
+	select {
+	case <-bc.intervals:
+	default:
+	}
+	select {
+	case bc.intervals <- d:
+	default:
+	}
+	return nil
 }
 
 // return lenght of array
@@ -232,9 +485,19 @@ func ValidateBlocks(blocks []block.Block) (int, error) {
 		return -1, errors.New("no blocks to validate")
 	}
 
+	var genesisTimestamp = blocks[0].Head.Timestamp
 	for i, blk := range blocks {
 		// Проверка целостности цепочки блоков
 		if i > 0 {
+			if blk.Head.Timestamp < genesisTimestamp {
+				return i - 1, fmt.Errorf("block %d is older than genesis", i)
+			}
+			if blk.Head.Timestamp <= medianTimestamp(blocks[:i]) {
+				return i - 1, fmt.Errorf("block %d: %w", i, ErrTimestampBelowMTP)
+			}
+			if err := block.VerifyCoinbase(&blk); err != nil {
+				return i - 1, fmt.Errorf("block %d: %w", i, err)
+			}
 			prevBlock := blocks[i-1]
 			fmt.Printf("%d-%d: %s - %s\r\n", i-1, i, blk.Head.PrevHash, prevBlock.Hash())
 			if blk.Head.PrevHash.String() != prevBlock.Hash().String() {
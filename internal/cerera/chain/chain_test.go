@@ -1 +1,1125 @@
 package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/pool"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/cerera/validator"
+	"github.com/cerera/internal/coinbase"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// stopGenerator stops generator of chain from previous test before vault of it is replaced
+func stopGenerator() {
+	if bch.gen != nil {
+		bch.gen.stop()
+	}
+}
+
+// prepareInMemChain returns chain with in memory vault, which receives rewards of generated blocks
+func prepareInMemChain() Chain {
+	nodeKey, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	// blocks of tests are sealed at once
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(nodeKey.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(nodeKey)
+	cfg.Vault.MEM = true
+	stopGenerator()
+	storage.NewD5Vault(cfg)
+	return InitBlockChain(cfg)
+}
+
+func TestInMemChain(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Vault.MEM = false
+	cfg.POOL.MEM = true
+
+	bc := InitBlockChain(cfg)
+	if bc.GetLatestBlock() == nil {
+		t.Fatalf("In memory chain should start from genesis")
+	}
+	if _, err := os.Stat("./chain.dat"); !os.IsNotExist(err) {
+		os.Remove("./chain.dat")
+		t.Errorf("In memory chain should not create chain file")
+	}
+	if cfg.Chain.Path != "EMPTY" {
+		t.Errorf("In memory chain should not update chain path, have %s", cfg.Chain.Path)
+	}
+
+	// pool follows own flag, in memory pool does not save txs on stop
+	cfg.POOL.File = filepath.Join(t.TempDir(), "pool.dat")
+	var p = pool.Get()
+	defer p.SetFile("")
+	p.SetUp(cfg)
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.POOL.File); !os.IsNotExist(err) {
+		t.Errorf("In memory pool should not create pool file")
+	}
+	cfg.POOL.MEM = false
+	p.SetUp(cfg)
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cfg.POOL.File); err != nil {
+		t.Errorf("Persistent pool should save pool file, have %s", err)
+	}
+}
+
+func TestSubscribeHead(t *testing.T) {
+	bc := prepareInMemChain()
+	heads, cancel := bc.SubscribeHead()
+	parent := bc.GetLatestBlock()
+
+	bc.G(parent)
+
+	select {
+	case head := <-heads:
+		if head.Head.PrevHash != parent.Hash() {
+			t.Errorf("New head should be built on parent %s, have %s", parent.Hash(), head.Head.PrevHash)
+		}
+		if head.Hash() != bc.GetLatestBlock().Hash() {
+			t.Errorf("Subscriber should receive latest block")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Head change was not delivered")
+	}
+
+	// unsubscribed channel is closed and dropped from feed
+	var subs = bc.heads.count()
+	cancel()
+	cancel()
+	if _, ok := <-heads; ok {
+		t.Errorf("Channel should be closed after cancel")
+	}
+	if bc.heads.count() != subs-1 {
+		t.Errorf("Expected %d subscribers, have %d", subs-1, bc.heads.count())
+	}
+	bc.G(bc.GetLatestBlock())
+}
+
+func TestSubscribeBlocks(t *testing.T) {
+	bc := prepareInMemChain()
+	blocks, cancel := bc.SubscribeBlocks()
+
+	// burst of blocks is delivered to slow subscriber without drops
+	var generated []common.Hash
+	for i := 0; i < 5; i++ {
+		if !bc.G(bc.GetLatestBlock()) {
+			t.Fatalf("Block %d should be generated", i)
+		}
+		generated = append(generated, bc.GetLatestBlock().Hash())
+	}
+	for i, hash := range generated {
+		select {
+		case b := <-blocks:
+			if b.Hash() != hash {
+				t.Errorf("Block %d: expected %s, have %s", i, hash, b.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Block %d was not delivered", i)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-blocks:
+		if ok {
+			t.Errorf("Channel should be closed after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Channel should be closed after cancel")
+	}
+}
+
+func TestRebuildOnNewHead(t *testing.T) {
+	nodeKey, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.Chain.TargetBlockInterval = int(time.Hour / time.Millisecond)
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(nodeKey.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(nodeKey)
+	cfg.Vault.MEM = true
+	cfg.AUTOGEN = true
+	stopGenerator()
+	storage.NewD5Vault(cfg)
+	validator.NewValidator(context.Background(), *cfg).SetUp(cfg.Chain.ChainID)
+	InitBlockChain(cfg)
+	// generator runs on chain of node
+	var bc = &bch
+	for i := 0; i < 100 && bc.heads.count() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	// tip of other node arrives while generator waits for ticker,
+	// generators of chains of previous tests may have built on it already
+	var latest = bc.GetLatestBlock()
+	var other = blockOn(latest, types.Address{0xa}, bc.NextDifficulty().Int64())
+	if _, err := bc.HandleCompetingBlock(other); err != nil {
+		t.Fatal(err)
+	}
+	var deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if next, err := bc.GetBlockByHeight(other.Head.Height + 1); err == nil {
+			if next.Head.PrevHash != other.Hash() {
+				t.Errorf("Block should be rebuilt on new tip %s, have parent %s", other.Hash(), next.Head.PrevHash)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Block should be rebuilt on new tip without waiting for ticker")
+}
+
+func TestBlockReward(t *testing.T) {
+	bc := prepareInMemChain()
+	var vlt = storage.GetVault()
+	var before = new(big.Int).Set(vlt.Get(bc.currentAddress).Balance)
+
+	if !bc.G(bc.GetLatestBlock()) {
+		t.Fatalf("Block should be generated")
+	}
+	var have = new(big.Int).Sub(vlt.Get(bc.currentAddress).Balance, before)
+	if want := coinbase.BlockReward(1); have.Cmp(want) != 0 {
+		t.Errorf("Node should receive reward %d, have %d", want, have)
+	}
+	if _, err := vlt.VerifySupply(); err != nil {
+		t.Errorf("Reward should be minted: %s", err)
+	}
+}
+
+func TestValidateBlocksTimestamp(t *testing.T) {
+	bc := prepareInMemChain()
+	genesis := bc.GetLatestBlock()
+	bc.G(genesis)
+
+	blocks := []block.Block{*genesis, *bc.GetLatestBlock()}
+	if n, err := ValidateBlocks(blocks); err != nil || n != 2 {
+		t.Errorf("Valid chain rejected: %d %s", n, err)
+	}
+
+	older := *bc.GetLatestBlock()
+	older.Head = block.CopyHeader(older.Head)
+	older.Head.Timestamp = genesis.Head.Timestamp - 1
+	blocks = []block.Block{*genesis, older}
+	if _, err := ValidateBlocks(blocks); err == nil {
+		t.Errorf("Block older than genesis should be rejected")
+	}
+}
+
+func TestGenesisProducer(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.MEM = true
+
+	bc := InitBlockChain(cfg)
+	latest := bc.GetLatestBlock()
+	if latest == nil {
+		t.Fatalf("Genesis producer should create genesis block")
+	}
+	genesis := block.GenesisBlock(cfg)
+	if latest.Head.Number.Sign() != 0 || latest.Hash() != genesis.Hash() {
+		t.Errorf("Latest block of fresh chain should be genesis, have %s", latest.Hash())
+	}
+
+	cfg.Chain.WaitGenesis = true
+	bc = InitBlockChain(cfg)
+	if bc.GetLatestBlock() != nil {
+		t.Errorf("Node which is not genesis producer should wait for genesis")
+	}
+	if info := bc.GetInfo().(BlockChainStatus); info.Total != 0 {
+		t.Errorf("Different chain size! Have %d, want %d", info.Total, 0)
+	}
+}
+
+func blocksWithTimestamps(timestamps ...uint64) []block.Block {
+	var blocks = make([]block.Block, 0)
+	for _, ts := range timestamps {
+		blocks = append(blocks, block.Block{Head: &block.Header{Timestamp: ts}})
+	}
+	return blocks
+}
+
+func TestMedianTimePast(t *testing.T) {
+	var cases = []struct {
+		timestamps []uint64
+		want       uint64
+	}{
+		{[]uint64{}, 0},
+		{[]uint64{5}, 5},
+		{[]uint64{1, 9, 5}, 5},
+		{[]uint64{10, 20, 30, 40}, 30},
+		// only last 11 blocks are counted
+		{[]uint64{100, 100, 100, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, 6},
+		// unordered timestamps
+		{[]uint64{11, 1, 10, 2, 9, 3, 8, 4, 7, 5, 6}, 6},
+	}
+	for _, c := range cases {
+		bc := Chain{chainState: &chainState{data: blocksWithTimestamps(c.timestamps...)}}
+		if mtp := bc.MedianTimePast(); mtp != c.want {
+			t.Errorf("Different median time past for %v! Have %d, want %d", c.timestamps, mtp, c.want)
+		}
+	}
+}
+
+func TestValidateTimestamp(t *testing.T) {
+	bc := Chain{chainState: &chainState{data: blocksWithTimestamps(1, 2, 3, 4, 5)}}
+	for ts, valid := range map[uint64]bool{2: false, 3: false, 4: true, 100: true} {
+		b := &block.Block{Head: &block.Header{Timestamp: ts}}
+		if err := bc.ValidateTimestamp(b); (err == nil) != valid {
+			t.Errorf("Block with timestamp %d: have %v, want valid %t", ts, err, valid)
+		}
+	}
+
+	genesis := bc.data[0]
+	blocks := []block.Block{genesis, {Head: &block.Header{Timestamp: genesis.Head.Timestamp, PrevHash: genesis.Hash()}}}
+	if _, err := ValidateBlocks(blocks); err == nil {
+		t.Errorf("Block at median time past should be rejected")
+	}
+}
+
+func TestFindByMemo(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.MemoIndex = true
+	bc := InitBlockChain(cfg)
+
+	var to = types.HexToAddress("0x9642903868c35526a8b34686a5d4184125562e7300a4556790379884e81ee30507352e49266351711164874923761a17")
+	var tx1 = types.NewTransaction(1, to, big.NewInt(1), 500, big.NewInt(250), []byte("invoice #42"))
+	var tx2 = types.NewTransaction(2, to, big.NewInt(2), 500, big.NewInt(250), []byte("invoice #42"))
+	var tx3 = types.NewTransaction(3, to, big.NewInt(3), 500, big.NewInt(250), []byte("other"))
+	var tx4 = types.NewTransaction(4, to, big.NewInt(4), 500, big.NewInt(250), []byte{0xff, 0xfe})
+
+	var b1 = block.NewBlockWithHeader(&block.Header{Height: 1, Number: big.NewInt(1)})
+	b1.Transactions = append(b1.Transactions, *tx1, *tx3)
+	var b2 = block.NewBlockWithHeader(&block.Header{Height: 2, Number: big.NewInt(2)})
+	b2.Transactions = append(b2.Transactions, *tx2, *tx4)
+	bc.memos.addBlock(b1)
+	bc.memos.addBlock(b2)
+
+	var found = bc.FindByMemo("invoice #42")
+	if len(found) != 2 || found[0] != tx1.Hash() || found[1] != tx2.Hash() {
+		t.Errorf("Different txs by memo, have %v, want %s, %s", found, tx1.Hash(), tx2.Hash())
+	}
+	if found := bc.FindByMemo("missing"); len(found) != 0 {
+		t.Errorf("Unknown memo should not match txs, have %v", found)
+	}
+
+	cfg.Chain.MemoIndex = false
+	bc = InitBlockChain(cfg)
+	if found := bc.FindByMemo("invoice #42"); found != nil {
+		t.Errorf("Disabled memo index should return nil, have %v", found)
+	}
+}
+
+func TestTargetBlockInterval(t *testing.T) {
+	var cases = []struct {
+		interval   int
+		difficulty int64
+		hashrate   int64
+	}{
+		// blocks come every second
+		{1000, 100, 100},
+		{2000, 200, 50},
+		{500, 50, 200},
+		// change is clamped
+		{10000, 400, 10},
+	}
+	for _, c := range cases {
+		cfg := &config.Config{}
+		cfg.Chain.ChainID = big.NewInt(11)
+		cfg.Chain.Path = "EMPTY"
+		cfg.Chain.MEM = true
+		cfg.Chain.TargetBlockInterval = c.interval
+		bc := InitBlockChain(cfg)
+		bc.data = blocksWithTimestamps(1000, 2000, 3000, 4000, 5000)
+		for i := range bc.data {
+			bc.data[i].Head.Difficulty = big.NewInt(100)
+		}
+		if d := bc.NextDifficulty(); d.Int64() != c.difficulty {
+			t.Errorf("Different next difficulty for interval %d, have %d, want %d", c.interval, d, c.difficulty)
+		}
+		if h := bc.EstimateHashrate(); h.Int64() != c.hashrate {
+			t.Errorf("Different hashrate for interval %d, have %d, want %d", c.interval, h, c.hashrate)
+		}
+	}
+}
+
+func TestRetargetFewBlocks(t *testing.T) {
+	if d := retarget(nil, time.Second); d != nil {
+		t.Errorf("Empty chain should not have difficulty, have %d", d)
+	}
+	// only genesis, nothing to compare with
+	var blocks = blocksWithTimestamps(1000)
+	blocks[0].Head.Difficulty = big.NewInt(100)
+	if d := retarget(blocks, time.Second); d.Int64() != 100 {
+		t.Errorf("Genesis difficulty should be kept, have %d", d)
+	}
+	// less than RetargetBlocks blocks, all of them are used
+	blocks = blocksWithTimestamps(1000, 1500, 2000)
+	for i := range blocks {
+		blocks[i].Head.Difficulty = big.NewInt(100)
+	}
+	if d := retarget(blocks, time.Second); d.Int64() != 200 {
+		t.Errorf("Different next difficulty, have %d, want %d", d, 200)
+	}
+	// slow blocks do not lower difficulty below floor
+	blocks = blocksWithTimestamps(1000, 9000)
+	for i := range blocks {
+		blocks[i].Head.Difficulty = new(big.Int).Set(MinDifficulty)
+	}
+	if d := retarget(blocks, time.Second); d.Cmp(MinDifficulty) != 0 {
+		t.Errorf("Difficulty should stay at floor %d, have %d", MinDifficulty, d)
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.AUTOGEN = true
+	bc := InitBlockChain(cfg)
+
+	if !bc.IsSynced() {
+		t.Errorf("Chain without peers should be synced, have %+v", bc.SyncState())
+	}
+
+	// peer announces longer chain
+	bc.UpdatePeerHeight(100)
+	bc.UpdatePeerHeight(50)
+	var state = bc.SyncState()
+	if !state.CatchingUp || state.PeerHeight != 100 || bc.IsSynced() {
+		t.Errorf("Behind chain should catch up, have %+v", state)
+	}
+	var height = bc.GetLatestBlock().Head.Height
+	if bc.generate() || bc.GetLatestBlock().Head.Height != height {
+		t.Errorf("Block should not be generated while catching up")
+	}
+
+	bc.tracker = newSyncTracker()
+	if !bc.generate() || bc.GetLatestBlock().Head.Height != height+1 {
+		t.Errorf("Synced chain should generate block")
+	}
+}
+
+func TestMiningPolicy(t *testing.T) {
+	var cases = []struct {
+		policy  string
+		started bool
+		voters  int
+		mine    bool
+	}{
+		{config.MiningPolicyStrict, false, 3, false},
+		{config.MiningPolicyStrict, true, 3, true},
+		{config.MiningPolicyPermissive, false, 3, true},
+		{config.MiningPolicyPermissive, true, 3, true},
+		{config.MiningPolicySolo, false, 3, true},
+		{config.MiningPolicySolo, false, 1, true},
+		{"", false, 1, true},
+		{"", false, 3, false},
+		{"", true, 3, true},
+	}
+	for _, c := range cases {
+		cfg := &config.Config{}
+		cfg.Chain.ChainID = big.NewInt(11)
+		cfg.Chain.Path = "EMPTY"
+		cfg.Chain.MEM = true
+		cfg.Chain.MiningPolicy = c.policy
+		cfg.Chain.GenesisDifficulty = big.NewInt(16)
+		cfg.AUTOGEN = true
+		bc := InitBlockChain(cfg)
+		bc.SetConsensus(c.started, c.voters)
+
+		var height = bc.GetLatestBlock().Head.Height
+		var mined = bc.generate()
+		if mined != c.mine {
+			t.Errorf("Policy %q with consensus %v and %d voters: expected mine %v, have %v",
+				c.policy, c.started, c.voters, c.mine, mined)
+		}
+		if mined && bc.GetLatestBlock().Head.Height != height+1 {
+			t.Errorf("Policy %q: block was not added", c.policy)
+		}
+	}
+}
+
+func TestMinVoters(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.MinVoters = 3
+	bc := InitBlockChain(cfg)
+
+	bc.SetConsensus(true, 2)
+	if bc.isConsensusStarted() {
+		t.Errorf("Consensus should not start with 2 of 3 voters")
+	}
+	bc.SetConsensus(true, 3)
+	if !bc.isConsensusStarted() {
+		t.Errorf("Consensus should start with 3 of 3 voters")
+	}
+	bc.SetConsensus(true, 1)
+	if bc.isConsensusStarted() {
+		t.Errorf("Consensus should stop when voters leave")
+	}
+}
+
+func TestConsensusSnapshot(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.MinVoters = 2
+	bc := InitBlockChain(cfg)
+
+	var empty = bc.ConsensusSnapshot()
+	bc.ObserveNode("peer-b", "/ip4/10.0.0.2/tcp/6116", 7)
+	bc.ObserveNode("peer-a", "/ip4/10.0.0.1/tcp/6116", 5)
+	bc.SetConsensus(true, 3)
+
+	var snap = bc.ConsensusSnapshot()
+	if !snap.Started || snap.Status != "started" || snap.Voters != 3 || snap.MinVoters != 2 {
+		t.Errorf("Snapshot should reflect started consensus, have %+v", snap)
+	}
+	if len(snap.Nodes) != 2 || snap.Nodes[0].ID != "peer-a" || snap.Nodes[1].Height != 7 {
+		t.Fatalf("Snapshot should contain observed nodes, have %+v", snap.Nodes)
+	}
+	if snap.Nodes[0].Addr != "/ip4/10.0.0.1/tcp/6116" || snap.Nodes[0].LastSeen.IsZero() {
+		t.Errorf("Node metadata is lost, have %+v", snap.Nodes[0])
+	}
+	if snap.Nonce != empty.Nonce+3 || snap.MembershipHash == empty.MembershipHash {
+		t.Errorf("Nonce and membership hash should change, have %+v", snap)
+	}
+
+	// later changes do not affect snapshot
+	bc.ObserveNode("peer-c", "/ip4/10.0.0.3/tcp/6116", 9)
+	bc.ObserveNode("peer-a", "/ip4/10.0.0.9/tcp/6116", 8)
+	bc.SetConsensus(false, 1)
+	if !snap.Started || snap.Voters != 3 || len(snap.Nodes) != 2 || snap.Nodes[0].Addr != "/ip4/10.0.0.1/tcp/6116" {
+		t.Errorf("Snapshot should be a copy, have %+v", snap)
+	}
+	snap.Nodes[0].Addr = "changed"
+	if bc.ConsensusSnapshot().Nodes[0].Addr == "changed" {
+		t.Errorf("Changes of snapshot should not affect consensus state")
+	}
+}
+
+func TestConsensusInfo(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.NetCfg.ADDR = types.Address{0x1, 0x2}
+	bc := InitBlockChain(cfg)
+
+	var start = bc.ConsensusInfo()
+	bc.ObserveNode("peer-a", "/ip4/10.0.0.1/tcp/6116", 5)
+	bc.SetConsensus(true, 2)
+
+	var want = ConsensusInfo{
+		Voters:  2,
+		Nodes:   1,
+		Status:  ConsensusStarted,
+		Nonce:   start.Nonce + 2,
+		Address: types.Address{0x1, 0x2}.String(),
+	}
+	if info := bc.ConsensusInfo(); info != want {
+		t.Errorf("Different consensus info, have %+v, want %+v", info, want)
+	}
+	bc.SetConsensus(false, 1)
+	want.Voters, want.Status, want.Nonce = 1, ConsensusStopped, want.Nonce+1
+	if info := bc.ConsensusInfo(); info != want {
+		t.Errorf("Different consensus info, have %+v, want %+v", info, want)
+	}
+}
+
+func TestSetInterval(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.TargetBlockInterval = int(time.Hour / time.Millisecond)
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.AUTOGEN = true
+	bc := InitBlockChain(cfg)
+
+	if err := bc.SetInterval(0); err != config.ErrInvalidBlockInterval {
+		t.Errorf("Expected %s, have %v", config.ErrInvalidBlockInterval, err)
+	}
+	var height = bc.GetLatestBlock().Head.Height
+	if err := bc.SetInterval(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	var deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var latest = GetBlockChain().GetLatestBlock()
+		if latest.Head.Height >= height+2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Blocks should be generated with new interval")
+}
+
+func TestSeal(t *testing.T) {
+	var b = block.GenerateGenesis(types.EmptyAddress())
+	b.Head.Difficulty = big.NewInt(64)
+	b.Nonce = 0
+
+	nonce, err := Seal(b, nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Nonce != nonce {
+		t.Errorf("Expected nonce %d set to block, have %d", nonce, b.Nonce)
+	}
+	var hash = new(big.Int).SetBytes(b.Hash().Bytes())
+	if hash.Cmp(block.HashTarget(b.Head.Difficulty)) >= 0 {
+		t.Errorf("Hash %x should be below target", hash)
+	}
+
+	// unreachable target, search is stopped only by abort
+	b.Head.Difficulty = new(big.Int).Lsh(big.NewInt(1), 255)
+	var abort = make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(abort) })
+	if _, err := Seal(b, abort, 2); err != ErrSealAborted {
+		t.Errorf("Expected %s, have %v", ErrSealAborted, err)
+	}
+}
+
+func TestGeneratedBlockSealed(t *testing.T) {
+	var bc = prepareInMemChain()
+	for i := 0; i < 3; i++ {
+		if !bc.G(bc.GetLatestBlock()) {
+			t.Fatalf("Block %d should be generated", i)
+		}
+		// nonce found from prepared hashing state gives same full hash
+		var b = bc.GetLatestBlock()
+		if res := block.VerifyBlockHashWithDetails(b); !res.Valid() {
+			t.Errorf("Block %d should be sealed, have %s", b.Head.Height, res)
+		}
+	}
+}
+
+func TestSealAbortOnHead(t *testing.T) {
+	var bc = prepareInMemChain()
+	// unreachable target, search is stopped only by new tip
+	bc.data[0].Head.Difficulty = new(big.Int).Lsh(big.NewInt(1), 255)
+	var height = bc.GetLatestBlock().Head.Height
+	var subs = bc.heads.count()
+
+	var done = make(chan bool)
+	go func() { done <- bc.G(bc.GetLatestBlock()) }()
+	for i := 0; i < 100 && bc.heads.count() == subs; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	bc.heads.send(blockOn(bc.GetLatestBlock(), types.Address{0xa}, 1))
+	select {
+	case mined := <-done:
+		if mined {
+			t.Errorf("Block should not be generated after new tip")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Nonce search should be aborted by new tip")
+	}
+	if h := bc.GetLatestBlock().Head.Height; h != height {
+		t.Errorf("Expected height %d, have %d", height, h)
+	}
+}
+
+func TestHashrate(t *testing.T) {
+	var w = &hashWindow{}
+	var now = time.Unix(1000, 0)
+	w.add(50, now)
+	w.add(30, now.Add(time.Second))
+	if r := w.rate(now.Add(time.Second)); r != 8 {
+		t.Errorf("Expected rate %d, have %f", 8, r)
+	}
+	// old buckets leave window
+	if r := w.rate(now.Add(HashrateWindow * time.Second)); r != 3 {
+		t.Errorf("Expected rate %d, have %f", 3, r)
+	}
+	w.add(10, now.Add(HashrateWindow*time.Second))
+	if r := w.rate(now.Add(HashrateWindow * time.Second)); r != 4 {
+		t.Errorf("Expected rate %d, have %f", 4, r)
+	}
+	if r := w.rate(now.Add(3 * HashrateWindow * time.Second)); r != 0 {
+		t.Errorf("Expected rate %d, have %f", 0, r)
+	}
+
+	var bc = prepareInMemChain()
+	if r := bc.Hashrate(); r != 0 {
+		t.Errorf("Node without generation should have zero hashrate, have %f", r)
+	}
+	// nonces tried while sealing generated block are counted
+	bc.autoGen = true
+	var before = testutil.ToFloat64(sealAttempts)
+	if !bc.G(bc.GetLatestBlock()) {
+		t.Fatalf("Block should be generated")
+	}
+	if testutil.ToFloat64(sealAttempts) <= before {
+		t.Errorf("Attempts of sealed block should be counted")
+	}
+	if r := bc.Hashrate(); r <= 0 {
+		t.Errorf("Generating node should have hashrate, have %f", r)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.TargetBlockInterval = int(time.Hour / time.Millisecond)
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.AUTOGEN = true
+	bc := InitBlockChain(cfg)
+	if s := bc.Status(); s != GeneratorRunning {
+		t.Errorf("Expected status %x, have %x", GeneratorRunning, s)
+	}
+
+	var height = bc.GetLatestBlock().Head.Height
+	bc.Pause()
+	if s := bc.Status(); s != GeneratorPaused {
+		t.Errorf("Expected status %x, have %x", GeneratorPaused, s)
+	}
+	if bc.generate() {
+		t.Errorf("Paused chain should not generate blocks")
+	}
+	// pause requested while block is built
+	if bc.G(bc.GetLatestBlock()) {
+		t.Errorf("Block built during pause should be dropped")
+	}
+	if h := bc.GetLatestBlock().Head.Height; h != height {
+		t.Errorf("Expected height %d, have %d", height, h)
+	}
+
+	bc.Resume()
+	if s := bc.Status(); s != GeneratorRunning {
+		t.Errorf("Expected status %x, have %x", GeneratorRunning, s)
+	}
+	if !bc.generate() {
+		t.Errorf("Resumed chain should generate blocks")
+	}
+	if h := bc.GetLatestBlock().Head.Height; h != height+1 {
+		t.Errorf("Expected height %d, have %d", height+1, h)
+	}
+
+	var stopped = prepareInMemChain()
+	if s := stopped.Status(); s != GeneratorStopped {
+		t.Errorf("Expected status %x, have %x", GeneratorStopped, s)
+	}
+}
+
+func TestFindTransaction(t *testing.T) {
+	var bc = prepareInMemChain()
+	for i := 0; i < 3; i++ {
+		bc.G(bc.GetLatestBlock())
+	}
+	var first = bc.data[1]
+	var hash = first.Transactions[0].Hash()
+
+	// block is deeper than search depth
+	if tx, height := bc.FindTransaction(hash, 2); tx != nil || height != -1 {
+		t.Errorf("Tx should not be found in last 2 blocks, have height %d", height)
+	}
+	tx, height := bc.FindTransaction(hash, 0)
+	if tx == nil || tx.Hash() != hash || height != first.Head.Height {
+		t.Fatalf("Tx should be found in block %d, have %d", first.Head.Height, height)
+	}
+	// found tx is served from cache
+	bc.data = bc.data[:1]
+	if tx, height := bc.FindTransaction(hash, 1); tx == nil || height != first.Head.Height {
+		t.Errorf("Tx should be found in cache, have height %d", height)
+	}
+	if tx, _ := bc.FindTransaction(common.EmptyHash(), 0); tx != nil {
+		t.Errorf("Unknown tx should not be found")
+	}
+}
+
+func TestPeerScore(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	bc := InitBlockChain(cfg)
+
+	if score := bc.PeerScore("peer-s"); score != 0 {
+		t.Errorf("Unknown peer should have zero score, have %d", score)
+	}
+	bc.AdjustPeerScore("peer-s", 3)
+	if score := bc.AdjustPeerScore("peer-s", -5); score != -2 || bc.PeerScore("peer-s") != -2 {
+		t.Errorf("Expected score -2, have %d", score)
+	}
+	if score := bc.AdjustPeerScore("peer-s", 10*MaxPeerScore); score != MaxPeerScore {
+		t.Errorf("Score should be capped by %d, have %d", MaxPeerScore, score)
+	}
+	if score := bc.AdjustPeerScore("peer-s", -10*MaxPeerScore); score != MinPeerScore {
+		t.Errorf("Score should be capped by %d, have %d", MinPeerScore, score)
+	}
+}
+
+func TestSaveLoadNonce(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	bc := InitBlockChain(cfg)
+	bc.SetConsensus(false, 3)
+	bc.ObserveNode("peer-n", "/ip4/10.0.0.1/tcp/6116", 1)
+
+	var path = filepath.Join(t.TempDir(), "consensus.nonce")
+	if err := bc.SaveNonce(path); err != nil {
+		t.Fatal(err)
+	}
+	restarted := InitBlockChain(cfg)
+	if err := restarted.LoadNonce(path); err != nil {
+		t.Fatal(err)
+	}
+	var info = restarted.ConsensusInfo()
+	if info.Nonce != 2 || info.Voters != 3 || info.Status != ConsensusStopped {
+		t.Errorf("Expected nonce 2 with 3 voters, have %d with %d", info.Nonce, info.Voters)
+	}
+
+	// corrupt and missing files leave nonce at 0
+	os.WriteFile(path, []byte("{nonce"), 0644)
+	restarted = InitBlockChain(cfg)
+	if err := restarted.LoadNonce(path); !errors.Is(err, ErrNonceFile) || restarted.ConsensusInfo().Nonce != 0 {
+		t.Errorf("Corrupt file should be reported, have %v", err)
+	}
+	if err := restarted.LoadNonce(path + ".missing"); err != nil || restarted.ConsensusInfo().Nonce != 0 {
+		t.Errorf("Missing file should be skipped, have %v", err)
+	}
+}
+
+func TestProposeBlock(t *testing.T) {
+	var bc = prepareInMemChain()
+	var genesis = bc.GetLatestBlock()
+	var mined = testutil.ToFloat64(blocksMined)
+	if !bc.G(genesis) {
+		t.Fatalf("Block should be generated")
+	}
+	if have := testutil.ToFloat64(blocksMined); have != mined+1 {
+		t.Errorf("Expected %v mined blocks, have %v", mined+1, have)
+	}
+	var tip = bc.GetLatestBlock()
+
+	var next = *tip
+	var head = *tip.Head
+	head.Height = tip.Head.Height + 1
+	head.PrevHash = tip.Hash()
+	head.Difficulty = bc.NextDifficulty()
+	next.Head = &head
+	Seal(&next, nil, 1)
+	if err := bc.ProposeBlock(&next); err != nil {
+		t.Errorf("Block on top of tip rejected: %s", err)
+	}
+
+	var zero = next
+	var zeroHead = head
+	zeroHead.Difficulty = big.NewInt(0)
+	zero.Head = &zeroHead
+
+	// hash of block with other nonce is above target
+	var unsealed = next
+	for unsealed.Nonce = next.Nonce + 1; block.VerifyBlockHash(&unsealed); unsealed.Nonce++ {
+	}
+
+	var heavy = next
+	var heavyHead = head
+	heavyHead.Difficulty = new(big.Int).Mul(head.Difficulty, big.NewInt(2))
+	heavy.Head = &heavyHead
+	Seal(&heavy, nil, 1)
+
+	var stale = next
+	var staleHead = head
+	staleHead.Height = tip.Head.Height
+	staleHead.Extra = []byte("OP_STALE")
+	stale.Head = &staleHead
+
+	for _, c := range []struct {
+		name string
+		b    *block.Block
+		err  error
+	}{
+		{"nil", nil, ErrNilBlock},
+		{"duplicate", tip, ErrDuplicateBlock},
+		{"stale", &stale, ErrStaleHeight},
+		{"pow", &zero, ErrInvalidPoW},
+		{"unsealed", &unsealed, ErrInvalidPoW},
+		{"difficulty", &heavy, ErrUnexpectedDifficulty},
+	} {
+		if err := bc.ProposeBlock(c.b); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %s, have %v", c.name, c.err, err)
+		}
+	}
+
+	bc.SetConsensus(false, 3)
+	if err := bc.ProposeBlock(&next); !errors.Is(err, ErrConsensusNotReady) {
+		t.Errorf("Expected %s, have %v", ErrConsensusNotReady, err)
+	}
+	var rejected = testutil.ToFloat64(blocksRejected)
+	if bc.G(tip) {
+		t.Errorf("Block without consensus should be rejected")
+	}
+	if h := bc.GetLatestBlock().Head.Height; h != tip.Head.Height {
+		t.Errorf("Expected height %d, have %d", tip.Head.Height, h)
+	}
+	if have := testutil.ToFloat64(blocksRejected); have != rejected+1 {
+		t.Errorf("Expected %v rejected blocks, have %v", rejected+1, have)
+	}
+}
+
+func TestGetBlockByHeightHash(t *testing.T) {
+	var bc = prepareInMemChain()
+	for i := 0; i < 3; i++ {
+		bc.G(bc.GetLatestBlock())
+	}
+	for _, want := range bc.data {
+		b, err := bc.GetBlockByHeight(want.Head.Height)
+		if err != nil || b.Hash() != want.Hash() {
+			t.Errorf("Expected block %s at height %d, have %v", want.Hash(), want.Head.Height, err)
+		}
+		b, err = bc.GetBlockByHash(want.Hash())
+		if err != nil || b.Head.Height != want.Head.Height {
+			t.Errorf("Expected block at height %d for %s, have %v", want.Head.Height, want.Hash(), err)
+		}
+	}
+	var tip = bc.GetLatestBlock()
+	if _, err := bc.GetBlockByHeight(tip.Head.Height + 1); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected %s, have %v", ErrBlockNotFound, err)
+	}
+	if _, err := bc.GetBlockByHash(common.EmptyHash()); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected %s, have %v", ErrBlockNotFound, err)
+	}
+	// returned block is a copy
+	b, _ := bc.GetBlockByHeight(tip.Head.Height)
+	b.Head = nil
+	if bc.GetLatestBlock().Head == nil {
+		t.Errorf("Block of chain should not be changed")
+	}
+}
+
+// blockOn builds block on top of parent with reward of node and txs
+func blockOn(parent *block.Block, node types.Address, difficulty int64, txs ...*types.GTransaction) *block.Block {
+	var head = *parent.Head
+	head.Height = parent.Head.Height + 1
+	head.Index = parent.Head.Index + 1
+	head.Number = big.NewInt(int64(head.Height))
+	head.Timestamp = parent.Head.Timestamp + 1
+	head.PrevHash = parent.Hash()
+	head.Node = node
+	head.Difficulty = big.NewInt(difficulty)
+	var b = block.NewBlockWithHeader(&head)
+	b.Transactions = append(b.Transactions, *coinbase.CreateCoinBaseTransation(head.Height, head.Timestamp, node))
+	for _, tx := range txs {
+		b.Transactions = append(b.Transactions, *tx)
+	}
+	b.Head.Root = block.ComputeTxRoot(b.Transactions)
+	b.Head.Size = int(unsafe.Sizeof(b))
+	Seal(b, nil, 1)
+	return b
+}
+
+func TestHandleCompetingBlock(t *testing.T) {
+	var bc = prepareInMemChain()
+	var genesis = bc.GetLatestBlock()
+
+	nodeKey, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(nodeKey.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(nodeKey)
+	cfg.Vault.MEM = true
+	stopGenerator()
+	var vlt = storage.NewD5Vault(cfg)
+	var vld = validator.NewValidator(context.Background(), *cfg)
+	vld.SetUp(cfg.Chain.ChainID)
+
+	fromKey, _ := types.GenerateAccount()
+	var from = types.PubkeyToAddress(fromKey.PublicKey)
+	vlt.Put(from, types.StateAccount{Address: from, Nonce: 1, Balance: types.FloatToBigInt(10)})
+	pa, _ := types.GenerateAccount()
+	pb, _ := types.GenerateAccount()
+	var a, b = types.PubkeyToAddress(pa.PublicKey), types.PubkeyToAddress(pb.PublicKey)
+	// blocks of branches are mined by different nodes
+	var minerA, minerB = types.Address{0xa}, types.Address{0xb}
+	var reward = func(height int) int64 { return coinbase.BlockReward(height).Int64() }
+	// first block keeps difficulty of genesis, next ones built 1 ms apart
+	// raise it by max factor of retarget
+	var diff = func(height int) int64 {
+		var d = genesis.Head.Difficulty.Int64()
+		for i := 1; i < height; i++ {
+			d *= MaxRetargetFactor
+		}
+		return d
+	}
+	var send = func(nonce uint64, to types.Address, value int64) *types.GTransaction {
+		var tx = types.NewTransaction(nonce, to, big.NewInt(value), 50000, big.NewInt(100), nil)
+		signTx, err := types.SignTx(tx, vld.Signer(), fromKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signTx
+	}
+	var balance = func(addr types.Address) int64 {
+		if bal := vlt.Get(addr).Balance; bal != nil {
+			return bal.Int64()
+		}
+		return 0
+	}
+
+	// block on top of tip extends chain, trie of chain is appended
+	var tree = bc.t
+	var a1 = blockOn(genesis, minerA, diff(1), send(1, a, 100))
+	if reorged, err := bc.HandleCompetingBlock(a1); reorged || err != nil {
+		t.Fatalf("Block on tip should extend chain, have %t, %v", reorged, err)
+	}
+	if bc.t != tree {
+		t.Errorf("Block on tip should be appended to trie of chain")
+	}
+	if bc.GetLatestBlock().Hash() != a1.Hash() || balance(a) != 100 {
+		t.Fatalf("Expected tip %s and balance 100, have %s and %d", a1.Hash(), bc.GetLatestBlock().Hash(), balance(a))
+	}
+	if balance(minerA) != reward(1) {
+		t.Errorf("Miner should receive reward %d, have %d", reward(1), balance(minerA))
+	}
+
+	// competing block of same work is kept aside
+	var b1 = blockOn(genesis, minerB, diff(1), send(1, b, 200))
+	if reorged, err := bc.HandleCompetingBlock(b1); reorged || err != nil {
+		t.Errorf("Branch of same work should not replace chain, have %t, %v", reorged, err)
+	}
+	if bc.GetLatestBlock().Hash() != a1.Hash() || balance(b) != 0 {
+		t.Errorf("Chain should keep tip %s, have %s", a1.Hash(), bc.GetLatestBlock().Hash())
+	}
+
+	// heavier branch replaces chain, effects of replaced block are reverted
+	var count = testutil.ToFloat64(reorgs)
+	var b2 = blockOn(b1, minerB, diff(2))
+	if reorged, err := bc.HandleCompetingBlock(b2); !reorged || err != nil {
+		t.Fatalf("Heavier branch should replace chain, have %t, %v", reorged, err)
+	}
+	if bc.GetLatestBlock().Hash() != b2.Hash() || len(bc.data) != 3 {
+		t.Errorf("Expected tip %s of 3 blocks, have %s of %d", b2.Hash(), bc.GetLatestBlock().Hash(), len(bc.data))
+	}
+	if bc.t == tree {
+		t.Errorf("Trie should be rebuilt after reorg")
+	}
+	if balance(a) != 0 || balance(b) != 200 || vlt.Get(from).Nonce != 2 {
+		t.Errorf("Expected balances 0 and 200, nonce 2, have %d, %d, %d", balance(a), balance(b), vlt.Get(from).Nonce)
+	}
+	if balance(minerA) != 0 || balance(minerB) != reward(1)+reward(2) {
+		t.Errorf("Rewards of replaced blocks should be reverted, have %d and %d", balance(minerA), balance(minerB))
+	}
+	if bal := storage.GetVault().ConfirmedBalance(a, 1); bal.Sign() != 0 {
+		t.Errorf("Balance of replaced block should not be confirmed, have %d", bal)
+	}
+
+	if _, err := bc.GetBlockByHash(a1.Hash()); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Replaced block should not be in chain, have %v", err)
+	}
+	if blk, err := bc.GetBlockByHeight(1); err != nil || blk.Hash() != b1.Hash() {
+		t.Errorf("Expected block %s at height 1, have %v", b1.Hash(), err)
+	}
+	if have := testutil.ToFloat64(reorgs); have != count+1 {
+		t.Errorf("Expected %v reorgs, have %v", count+1, have)
+	}
+
+	// heavier branch with invalid tx leaves chain as is
+	var a2 = blockOn(a1, minerA, diff(2), send(5, a, 100))
+	if reorged, err := bc.HandleCompetingBlock(a2); reorged || err != nil {
+		t.Errorf("Branch of same work should not replace chain, have %t, %v", reorged, err)
+	}
+	var a3 = blockOn(a2, minerA, diff(3))
+	if _, err := bc.HandleCompetingBlock(a3); !errors.Is(err, ErrInvalidBranch) {
+		t.Errorf("Expected %s, have %v", ErrInvalidBranch, err)
+	}
+	if bc.GetLatestBlock().Hash() != b2.Hash() || balance(a) != 0 || balance(b) != 200 {
+		t.Errorf("Chain should stay at %s, have %s, balances %d and %d", b2.Hash(), bc.GetLatestBlock().Hash(), balance(a), balance(b))
+	}
+
+	// hash of block with other nonce is above target
+	var unsealed = blockOn(b2, minerB, diff(3))
+	for unsealed.Nonce++; block.VerifyBlockHash(unsealed); unsealed.Nonce++ {
+	}
+
+	for _, c := range []struct {
+		name string
+		b    *block.Block
+		err  error
+	}{
+		{"nil", nil, ErrNilBlock},
+		{"duplicate", b1, ErrDuplicateBlock},
+		{"unknown parent", blockOn(&block.Block{Head: &block.Header{Difficulty: big.NewInt(1)}}, a, 1), ErrUnknownParent},
+		{"unsealed", unsealed, validator.ErrBlockPoW},
+		// branch can not become heavier by difficulty which was not retargeted
+		{"difficulty", blockOn(b1, minerA, diff(3)), ErrUnexpectedDifficulty},
+	} {
+		if _, err := bc.HandleCompetingBlock(c.b); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %s, have %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestTotalDifficulty(t *testing.T) {
+	var bc = prepareInMemChain()
+	var genesis = bc.GetLatestBlock()
+	if td := bc.TotalDifficulty(); td.Cmp(genesis.Head.Difficulty) != 0 {
+		t.Errorf("Expected genesis difficulty %d, have %d", genesis.Head.Difficulty, td)
+	}
+	for i := 0; i < 3; i++ {
+		bc.G(bc.GetLatestBlock())
+	}
+	var sum = big.NewInt(0)
+	for i := range bc.data {
+		sum.Add(sum, bc.data[i].Head.Difficulty)
+		td, err := bc.GetTotalDifficulty(bc.data[i].Hash())
+		if err != nil || td.Cmp(sum) != 0 {
+			t.Errorf("Expected total difficulty %d at height %d, have %d, %v", sum, i, td, err)
+		}
+	}
+	if td := bc.TotalDifficulty(); td.Cmp(sum) != 0 {
+		t.Errorf("Expected total difficulty %d, have %d", sum, td)
+	}
+	// returned value is a copy
+	bc.TotalDifficulty().SetInt64(0)
+	if td := bc.TotalDifficulty(); td.Cmp(sum) != 0 {
+		t.Errorf("Expected total difficulty %d, have %d", sum, td)
+	}
+	if _, err := bc.GetTotalDifficulty(common.EmptyHash()); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected %s, have %v", ErrBlockNotFound, err)
+	}
+	// index built from loaded blocks has same totals
+	bc.index = newBlockIndex(bc.data)
+	if td := bc.TotalDifficulty(); td.Cmp(sum) != 0 {
+		t.Errorf("Expected total difficulty %d after reload, have %d", sum, td)
+	}
+}
@@ -0,0 +1,263 @@
+package chain
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ConsensusNode is peer seen by node with its metadata
+type ConsensusNode struct {
+	ID       string    `json:"id"`
+	Addr     string    `json:"addr"`
+	LastSeen time.Time `json:"lastSeen"`
+	Height   int       `json:"height"` // last height announced by peer
+}
+
+// ConsensusSnapshot is copy of consensus state at some moment, for debugging
+type ConsensusSnapshot struct {
+	Status         string          `json:"status"`
+	Started        bool            `json:"started"`
+	Policy         string          `json:"policy"`
+	Voters         int             `json:"voters"`
+	MinVoters      int             `json:"minVoters"`
+	Nodes          []ConsensusNode `json:"nodes"`
+	Nonce          uint64          `json:"nonce"` // count of changes of state
+	MembershipHash common.Hash     `json:"membershipHash"`
+}
+
+// status of consensus in ConsensusInfo
+const (
+	ConsensusStopped = 0
+	ConsensusStarted = 1
+)
+
+// ConsensusInfo is short summary of consensus state, for logs
+type ConsensusInfo struct {
+	Voters  int
+	Nodes   int // count of seen peers
+	Status  int
+	Nonce   uint64
+	Address string // address of current node
+}
+
+// consensusState keeps whether consensus between voters is reached
+type consensusState struct {
+	mu        sync.RWMutex
+	started   bool
+	voters    int
+	minVoters int // consensus does not start with less voters
+	warned    bool
+	nodes     map[string]ConsensusNode
+	nonce     uint64
+	scores    map[string]int // behaviour of peers
+}
+
+// bounds of peer score
+const (
+	MaxPeerScore = 100
+	MinPeerScore = -100
+)
+
+func newConsensusState(minVoters int) *consensusState {
+	return &consensusState{
+		minVoters: minVoters,
+		nodes:     make(map[string]ConsensusNode),
+		scores:    make(map[string]int),
+	}
+}
+
+func (c *consensusState) set(started bool, voters int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if started && voters < c.minVoters {
+		minerLog.Warnf("Consensus is not started, need %d more voters", c.minVoters-voters)
+		started = false
+	}
+	c.started = started
+	c.voters = voters
+	c.nonce++
+	if started {
+		c.warned = false
+	}
+}
+
+func (c *consensusState) observe(id, addr string, height int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[id] = ConsensusNode{
+		ID:       id,
+		Addr:     addr,
+		LastSeen: time.Now().UTC(),
+		Height:   height,
+	}
+	c.nonce++
+}
+
+func (c *consensusState) adjustScore(id string, delta int) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var score = c.scores[id] + delta
+	if score > MaxPeerScore {
+		score = MaxPeerScore
+	}
+	if score < MinPeerScore {
+		score = MinPeerScore
+	}
+	c.scores[id] = score
+	return score
+}
+
+func (c *consensusState) score(id string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.scores[id]
+}
+
+func (c *consensusState) snapshot() ConsensusSnapshot {
+	if c == nil {
+		return ConsensusSnapshot{Status: "stopped", Nodes: []ConsensusNode{}}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var snap = ConsensusSnapshot{
+		Status:    "stopped",
+		Started:   c.started,
+		Voters:    c.voters,
+		MinVoters: c.minVoters,
+		Nodes:     make([]ConsensusNode, 0, len(c.nodes)),
+		Nonce:     c.nonce,
+	}
+	if c.started {
+		snap.Status = "started"
+	}
+	for _, n := range c.nodes {
+		snap.Nodes = append(snap.Nodes, n)
+	}
+	sort.Slice(snap.Nodes, func(i, j int) bool {
+		return snap.Nodes[i].ID < snap.Nodes[j].ID
+	})
+	// membership hash depends on set of nodes only
+	hw, _ := blake2b.New256(nil)
+	for _, n := range snap.Nodes {
+		hw.Write([]byte(n.ID))
+		hw.Write([]byte{0})
+	}
+	snap.MembershipHash.SetBytes(hw.Sum(nil))
+	return snap
+}
+
+func (c *consensusState) get() (bool, int) {
+	if c == nil {
+		return false, 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.started, c.voters
+}
+
+// warnOnce reports whether warning about missing consensus was not printed yet
+func (c *consensusState) warnOnce() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.warned {
+		return false
+	}
+	c.warned = true
+	return true
+}
+
+// SetConsensus updates state of consensus and count of known voters (including current node).
+// Consensus stays unstarted until count of voters reaches MinVoters from config.
+func (bc *Chain) SetConsensus(started bool, voters int) {
+	bc.consensus.set(started, voters)
+}
+
+// ObserveNode remembers peer with its address and announced height.
+func (bc *Chain) ObserveNode(id, addr string, height int) {
+	bc.consensus.observe(id, addr, height)
+}
+
+// AdjustPeerScore changes score of peer by delta and returns new score.
+func (bc *Chain) AdjustPeerScore(id string, delta int) int {
+	return bc.consensus.adjustScore(id, delta)
+}
+
+// PeerScore returns score of peer, 0 for unknown peers.
+func (bc *Chain) PeerScore(id string) int {
+	return bc.consensus.score(id)
+}
+
+// ConsensusSnapshot returns copy of whole consensus state, later changes do not affect it.
+func (bc *Chain) ConsensusSnapshot() ConsensusSnapshot {
+	var snap = bc.consensus.snapshot()
+	snap.Policy = bc.MiningPolicy()
+	return snap
+}
+
+// ConsensusInfo returns summary of consensus state.
+func (bc *Chain) ConsensusInfo() ConsensusInfo {
+	var snap = bc.consensus.snapshot()
+	var info = ConsensusInfo{
+		Voters:  snap.Voters,
+		Nodes:   len(snap.Nodes),
+		Status:  ConsensusStopped,
+		Nonce:   snap.Nonce,
+		Address: bc.currentAddress.String(),
+	}
+	if snap.Started {
+		info.Status = ConsensusStarted
+	}
+	return info
+}
+
+func (bc *Chain) isConsensusStarted() bool {
+	started, _ := bc.consensus.get()
+	return started
+}
+
+// MiningPolicy returns policy used by block generation for current count of voters.
+func (bc *Chain) MiningPolicy() string {
+	if bc.miningPolicy != "" {
+		return bc.miningPolicy
+	}
+	_, voters := bc.consensus.get()
+	return config.DefaultMiningPolicy(voters)
+}
+
+// canMine decides by mining policy whether block could be generated now.
+func (bc *Chain) canMine() bool {
+	if bc.isConsensusStarted() {
+		return true
+	}
+	switch bc.MiningPolicy() {
+	case config.MiningPolicySolo:
+		return true
+	case config.MiningPolicyPermissive:
+		if bc.consensus.warnOnce() {
+			minerLog.Warnf("Consensus is not reached, generate blocks anyway")
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,116 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+// count of latest blocks used for difficulty retargeting and hashrate estimation
+const RetargetBlocks = 10
+
+// max factor of difficulty change in one retarget
+const MaxRetargetFactor = 4
+
+// lowest difficulty given by retarget, slow nodes still produce blocks
+var MinDifficulty = big.NewInt(1)
+
+var ErrUnexpectedDifficulty = errors.New("block difficulty is not retargeted from its parent")
+
+// retarget returns difficulty of next block so that blocks come every interval.
+// Difficulty of latest block is scaled by ratio of expected to actual time of
+// last RetargetBlocks blocks, clamped by MaxRetargetFactor and MinDifficulty.
+func retarget(blocks []block.Block, interval time.Duration) *big.Int {
+	if len(blocks) == 0 {
+		return nil
+	}
+	var latest = blocks[len(blocks)-1].Head.Difficulty
+	if len(blocks) < 2 || interval <= 0 {
+		return new(big.Int).Set(latest)
+	}
+	var start = len(blocks) - 1 - RetargetBlocks
+	if start < 0 {
+		start = 0
+	}
+	var first = blocks[start].Head.Timestamp
+	var last = blocks[len(blocks)-1].Head.Timestamp
+	var expected = int64(len(blocks)-1-start) * interval.Milliseconds()
+	var actual = int64(last) - int64(first)
+	if actual < expected/MaxRetargetFactor {
+		actual = expected / MaxRetargetFactor
+	}
+	if actual > expected*MaxRetargetFactor {
+		actual = expected * MaxRetargetFactor
+	}
+	if actual <= 0 {
+		return new(big.Int).Set(latest)
+	}
+	var next = new(big.Int).Mul(latest, big.NewInt(expected))
+	next.Div(next, big.NewInt(actual))
+	if next.Cmp(MinDifficulty) < 0 {
+		next.Set(MinDifficulty)
+	}
+	return next
+}
+
+// estimateHashrate returns hashes per second needed to produce blocks of
+// latest difficulty every interval, averaged over last RetargetBlocks blocks.
+func estimateHashrate(blocks []block.Block, interval time.Duration) *big.Int {
+	if len(blocks) == 0 || interval <= 0 {
+		return big.NewInt(0)
+	}
+	var start = len(blocks) - RetargetBlocks
+	if start < 0 {
+		start = 0
+	}
+	var work = big.NewInt(0)
+	for _, b := range blocks[start:] {
+		if b.Head.Difficulty != nil {
+			work.Add(work, b.Head.Difficulty)
+		}
+	}
+	var seconds = int64(len(blocks)-start) * interval.Milliseconds()
+	work.Mul(work, big.NewInt(1000))
+	return work.Div(work, big.NewInt(seconds))
+}
+
+// checkDifficulty checks that each block of branch has difficulty retargeted
+// from blocks before it: blocks of chain up to position fork and previous
+// blocks of branch. Otherwise branch could claim any total difficulty.
+// Caller holds chain lock.
+func (bc *Chain) checkDifficulty(fork int, branch []block.Block) error {
+	var start = fork - RetargetBlocks
+	if start < 0 {
+		start = 0
+	}
+	var blocks = append(append([]block.Block(nil), bc.data[start:fork+1]...), branch...)
+	var offset = fork + 1 - start
+	for i := range branch {
+		var expected = retarget(blocks[:offset+i], bc.blockInterval)
+		if d := branch[i].Head.Difficulty; d == nil || expected == nil || d.Cmp(expected) != 0 {
+			return fmt.Errorf("%w: block %d has %v, expected %s", ErrUnexpectedDifficulty, branch[i].Head.Height, d, expected)
+		}
+	}
+	return nil
+}
+
+// NextDifficulty returns difficulty for next block of chain.
+func (bc *Chain) NextDifficulty() *big.Int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.nextDifficulty()
+}
+
+func (bc *Chain) nextDifficulty() *big.Int {
+	return retarget(bc.data, bc.blockInterval)
+}
+
+// EstimateHashrate returns estimated hashrate of network, hashes per second.
+func (bc *Chain) EstimateHashrate() *big.Int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return estimateHashrate(bc.data, bc.blockInterval)
+}
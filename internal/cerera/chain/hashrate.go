@@ -0,0 +1,55 @@
+package chain
+
+import (
+	"sync"
+	"time"
+)
+
+// seconds of nonce attempts used for local hashrate
+const HashrateWindow = 10
+
+// hashWindow counts nonce attempts in ring of one second buckets.
+type hashWindow struct {
+	mu      sync.Mutex
+	buckets [HashrateWindow]struct {
+		sec   int64
+		count uint64
+	}
+}
+
+var sealRate = &hashWindow{}
+
+func (w *hashWindow) add(n uint64, now time.Time) {
+	var sec = now.Unix()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var b = &w.buckets[sec%HashrateWindow]
+	if b.sec != sec {
+		b.sec = sec
+		b.count = 0
+	}
+	b.count += n
+}
+
+// rate returns attempts per second over last HashrateWindow seconds.
+func (w *hashWindow) rate(now time.Time) float64 {
+	var sec = now.Unix()
+	var total uint64
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range w.buckets {
+		if sec-b.sec < HashrateWindow {
+			total += b.count
+		}
+	}
+	return float64(total) / HashrateWindow
+}
+
+// Hashrate returns nonces tried by node per second while sealing blocks,
+// 0 if node does not generate blocks.
+func (bc *Chain) Hashrate() float64 {
+	if !bc.autoGen {
+		return 0
+	}
+	return sealRate.rate(time.Now())
+}
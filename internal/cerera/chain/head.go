@@ -0,0 +1,66 @@
+package chain
+
+import (
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+// headFeed delivers every new tip of chain to subscribers.
+// Slow subscribers do not block chain, they just miss intermediate tips.
+type headFeed struct {
+	mu   sync.Mutex
+	subs []chan *block.Block
+}
+
+func newHeadFeed() *headFeed {
+	return &headFeed{
+		subs: make([]chan *block.Block, 0),
+	}
+}
+
+func (f *headFeed) subscribe() (<-chan *block.Block, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ch = make(chan *block.Block, 1)
+	f.subs = append(f.subs, ch)
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { f.unsubscribe(ch) })
+	}
+}
+
+func (f *headFeed) unsubscribe(ch chan *block.Block) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.subs {
+		if f.subs[i] == ch {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			break
+		}
+	}
+	close(ch)
+}
+
+func (f *headFeed) send(b *block.Block) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs {
+		// drop stale tip, only the latest one matters
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
+
+func (f *headFeed) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
@@ -0,0 +1,145 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+)
+
+var ErrBlockNotFound = errors.New("block not found")
+
+// blockIndex keeps positions of blocks in chain data by height and by hash
+// and total difficulty of chain up to each block, shared by copies of chain
+type blockIndex struct {
+	mu       sync.RWMutex
+	byHeight map[int]int
+	byHash   map[common.Hash]int
+	td       map[common.Hash]*big.Int
+}
+
+func newBlockIndex(blocks []block.Block) *blockIndex {
+	var idx = &blockIndex{
+		byHeight: make(map[int]int, len(blocks)),
+		byHash:   make(map[common.Hash]int, len(blocks)),
+		td:       make(map[common.Hash]*big.Int, len(blocks)),
+	}
+	for i := range blocks {
+		idx.add(&blocks[i], i)
+	}
+	return idx
+}
+
+// add indexes block on top of its parent, total difficulty of block without
+// indexed parent, like genesis, is its own difficulty
+func (idx *blockIndex) add(b *block.Block, pos int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var hash = b.Hash()
+	var td = new(big.Int)
+	if parent, ok := idx.td[b.Head.PrevHash]; ok {
+		td.Set(parent)
+	}
+	if b.Head.Difficulty != nil {
+		td.Add(td, b.Head.Difficulty)
+	}
+	idx.byHeight[b.Head.Height] = pos
+	idx.byHash[hash] = pos
+	idx.td[hash] = td
+}
+
+// remove drops block which is not in chain anymore
+func (idx *blockIndex) remove(b *block.Block) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var hash = b.Hash()
+	if pos, ok := idx.byHash[hash]; ok && idx.byHeight[b.Head.Height] == pos {
+		delete(idx.byHeight, b.Head.Height)
+	}
+	delete(idx.byHash, hash)
+	delete(idx.td, hash)
+}
+
+func (idx *blockIndex) height(h int) (int, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	pos, ok := idx.byHeight[h]
+	return pos, ok
+}
+
+func (idx *blockIndex) totalDifficulty(h common.Hash) (*big.Int, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	td, ok := idx.td[h]
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).Set(td), true
+}
+
+func (idx *blockIndex) hash(h common.Hash) (int, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	pos, ok := idx.byHash[h]
+	return pos, ok
+}
+
+// blockAt returns copy of block at position, caller holds chain lock
+func (bc Chain) blockAt(pos int, ok bool) (*block.Block, bool) {
+	if !ok || pos >= len(bc.data) {
+		return nil, false
+	}
+	var b = bc.data[pos]
+	return &b, true
+}
+
+// GetBlockByHeight returns block of chain at height.
+func (bc Chain) GetBlockByHeight(h int) (*block.Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if b, ok := bc.blockAt(bc.index.height(h)); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: height %d", ErrBlockNotFound, h)
+}
+
+// GetBlockByHash returns block of chain with hash.
+func (bc Chain) GetBlockByHash(hash common.Hash) (*block.Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if b, ok := bc.blockAt(bc.index.hash(hash)); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: hash %s", ErrBlockNotFound, hash)
+}
+
+// TotalDifficulty returns sum of difficulties of blocks from genesis to tip,
+// zero for empty chain.
+func (bc *Chain) TotalDifficulty() *big.Int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.totalDifficulty()
+}
+
+func (bc *Chain) totalDifficulty() *big.Int {
+	var tip = bc.currentBlock
+	if tip == nil {
+		return big.NewInt(0)
+	}
+	if td, ok := bc.index.totalDifficulty(tip.Hash()); ok {
+		return td
+	}
+	return big.NewInt(0)
+}
+
+// GetTotalDifficulty returns sum of difficulties of blocks from genesis up to
+// block with hash.
+func (bc *Chain) GetTotalDifficulty(hash common.Hash) (*big.Int, error) {
+	if td, ok := bc.index.totalDifficulty(hash); ok {
+		return td, nil
+	}
+	return nil, fmt.Errorf("%w: hash %s", ErrBlockNotFound, hash)
+}
@@ -0,0 +1,89 @@
+package chain
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/coinbase"
+	"golang.org/x/crypto/blake2b"
+)
+
+// memoIndex maps hash of memo to hashes of txs tagged with it.
+// Memo is data of tx which is valid utf-8 text.
+type memoIndex struct {
+	mu  sync.RWMutex
+	txs map[common.Hash][]common.Hash
+}
+
+func newMemoIndex() *memoIndex {
+	return &memoIndex{
+		txs: make(map[common.Hash][]common.Hash),
+	}
+}
+
+func memoKey(memo []byte) common.Hash {
+	return common.Hash(blake2b.Sum256(memo))
+}
+
+// addBlock indexes txs of applied block, nil index means indexing is off
+func (m *memoIndex) addBlock(b *block.Block) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range b.Transactions {
+		var tx = &b.Transactions[i]
+		var data = tx.Data()
+		if tx.IsContractCreation() || coinbase.IsCoinBaseTransaction(tx) || len(data) == 0 || !utf8.Valid(data) {
+			continue
+		}
+		var key = memoKey(data)
+		m.txs[key] = append(m.txs[key], tx.Hash())
+	}
+}
+
+// removeBlock drops txs of block which is not in chain anymore
+func (m *memoIndex) removeBlock(b *block.Block) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range b.Transactions {
+		var tx = &b.Transactions[i]
+		var key = memoKey(tx.Data())
+		var hashes = m.txs[key]
+		for j := range hashes {
+			if hashes[j] == tx.Hash() {
+				hashes = append(hashes[:j:j], hashes[j+1:]...)
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			delete(m.txs, key)
+		} else {
+			m.txs[key] = hashes
+		}
+	}
+}
+
+func (m *memoIndex) find(memo string) []common.Hash {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var found = m.txs[memoKey([]byte(memo))]
+	var res = make([]common.Hash, len(found))
+	copy(res, found)
+	return res
+}
+
+// FindByMemo returns hashes of txs tagged with memo in order of inclusion.
+// Returns nil when memo index is disabled in config.
+func (bc *Chain) FindByMemo(memo string) []common.Hash {
+	return bc.memos.find(memo)
+}
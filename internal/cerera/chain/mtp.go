@@ -0,0 +1,50 @@
+package chain
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+// count of latest blocks for median time past
+const MedianTimeBlocks = 11
+
+var ErrTimestampBelowMTP = errors.New("block timestamp is not after median time past")
+
+// medianTimestamp returns median timestamp of last MedianTimeBlocks blocks.
+func medianTimestamp(blocks []block.Block) uint64 {
+	if len(blocks) == 0 {
+		return 0
+	}
+	var start = len(blocks) - MedianTimeBlocks
+	if start < 0 {
+		start = 0
+	}
+	var timestamps = make([]uint64, 0, MedianTimeBlocks)
+	for _, b := range blocks[start:] {
+		timestamps = append(timestamps, b.Head.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2]
+}
+
+// MedianTimePast returns median timestamp of latest blocks of chain,
+// timestamp of next block should be greater than it.
+func (bc *Chain) MedianTimePast() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return medianTimestamp(bc.data)
+}
+
+// ValidateTimestamp checks timestamp of next block against median time past.
+func (bc *Chain) ValidateTimestamp(b *block.Block) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if len(bc.data) > 0 && b.Head.Timestamp <= medianTimestamp(bc.data) {
+		return ErrTimestampBelowMTP
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrNonceFile = errors.New("invalid consensus nonce file")
+
+// nonceFile is consensus state kept across restarts of node
+type nonceFile struct {
+	Nonce  uint64 `json:"nonce"`
+	Voters int    `json:"voters"`
+}
+
+func (c *consensusState) persisted() nonceFile {
+	if c == nil {
+		return nonceFile{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return nonceFile{Nonce: c.nonce, Voters: c.voters}
+}
+
+// restore sets nonce and voters of previous run, consensus is not started
+// until voters are seen again
+func (c *consensusState) restore(f nonceFile) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonce = f.Nonce
+	c.voters = f.Voters
+}
+
+// SaveNonce writes consensus nonce and count of voters to file, file is
+// replaced at once so it is not left half written.
+func (bc *Chain) SaveNonce(path string) error {
+	data, err := json.Marshal(bc.consensus.persisted())
+	if err != nil {
+		return err
+	}
+	var tmp = path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadNonce resumes consensus nonce saved by SaveNonce. Missing file is not
+// an error; corrupt file is reported and nonce stays 0.
+func (bc *Chain) LoadNonce(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f nonceFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%w: %s", ErrNonceFile, err)
+	}
+	if f.Voters < 0 {
+		return fmt.Errorf("%w: negative voters", ErrNonceFile)
+	}
+	bc.consensus.restore(f)
+	return nil
+}
+
+// Stop ends block generator and saves consensus nonce, so restarted node
+// resumes from it.
+func (bc *Chain) Stop() error {
+	bc.gen.stop()
+	if bc.nonceFile == "" {
+		return nil
+	}
+	return bc.SaveNonce(bc.nonceFile)
+}
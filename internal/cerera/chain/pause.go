@@ -0,0 +1,56 @@
+package chain
+
+import "sync"
+
+// status of block generation
+const (
+	GeneratorStopped byte = 0x0 // node does not generate blocks
+	GeneratorRunning byte = 0x1
+	GeneratorPaused  byte = 0x2 // generation is halted until resume
+)
+
+// pauseState keeps whether block generation is paused, shared by copies of chain
+type pauseState struct {
+	mu     sync.RWMutex
+	paused bool
+}
+
+func (p *pauseState) set(paused bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = paused
+}
+
+func (p *pauseState) get() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.paused
+}
+
+// Pause halts block generation without stopping generator, for example
+// while chain is reorganized. Block which is being built is dropped.
+func (bc *Chain) Pause() {
+	bc.pause.set(true)
+}
+
+// Resume continues block generation after Pause.
+func (bc *Chain) Resume() {
+	bc.pause.set(false)
+}
+
+// Status returns status of block generation.
+func (bc *Chain) Status() byte {
+	if !bc.autoGen {
+		return GeneratorStopped
+	}
+	if bc.pause.get() {
+		return GeneratorPaused
+	}
+	return GeneratorRunning
+}
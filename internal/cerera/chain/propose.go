@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ErrNilBlock          = errors.New("nil block proposed")
+	ErrConsensusNotReady = errors.New("consensus is not ready for blocks")
+	ErrInvalidPoW        = errors.New("block proof of work is invalid")
+	ErrStaleHeight       = errors.New("block height is not above tip")
+	ErrStaleParent       = errors.New("block is not built on tip")
+	ErrDuplicateBlock    = errors.New("block is already in chain")
+)
+
+var (
+	blocksMined = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "chain_blocks_mined_total",
+			Help: "Count of generated blocks accepted by chain",
+		},
+	)
+	blocksRejected = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "chain_blocks_rejected_total",
+			Help: "Count of generated blocks rejected by chain",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(blocksMined, blocksRejected)
+}
+
+// ProposeBlock checks that generated block could be added on top of chain,
+// nil is returned when it is accepted. Block should be sealed with difficulty
+// retargeted from blocks of chain.
+func (bc *Chain) ProposeBlock(b *block.Block) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.proposeBlock(b)
+}
+
+// proposeBlock is ProposeBlock for holder of chain lock
+func (bc *Chain) proposeBlock(b *block.Block) error {
+	if b == nil || b.Head == nil {
+		return ErrNilBlock
+	}
+	if !bc.canMine() {
+		return ErrConsensusNotReady
+	}
+	if _, ok := bc.blockAt(bc.index.hash(b.Hash())); ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateBlock, b.Hash())
+	}
+	if tip := bc.currentBlock; tip != nil {
+		if b.Head.Height <= tip.Head.Height {
+			return fmt.Errorf("%w: %d, tip %d", ErrStaleHeight, b.Head.Height, tip.Head.Height)
+		}
+		if b.Head.PrevHash != tip.Hash() {
+			return fmt.Errorf("%w: %s, tip %s", ErrStaleParent, b.Head.PrevHash, tip.Hash())
+		}
+	}
+	if err := checkPoW(b); err != nil {
+		return err
+	}
+	return bc.checkDifficulty(len(bc.data)-1, []block.Block{*b})
+}
+
+// checkPoW checks proof of work of block, hash should be below target
+func checkPoW(b *block.Block) error {
+	if res := block.VerifyBlockHashWithDetails(b); !res.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidPoW, res.Reason)
+	}
+	return nil
+}
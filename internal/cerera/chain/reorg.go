@@ -0,0 +1,344 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/trie"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/cerera/validator"
+	"github.com/cerera/internal/coinbase"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// count of blocks below tip which may be replaced by competing branch
+var MaxReorgDepth = 64
+
+var (
+	ErrUnknownParent  = errors.New("parent of block is unknown")
+	ErrReorgTooDeep   = errors.New("competing branch forks too deep below tip")
+	ErrNoUndo         = errors.New("vault changes of block are unknown, it can not be reverted")
+	ErrInvalidBlockTx = errors.New("block has invalid transaction")
+	ErrInvalidBranch  = errors.New("competing branch can not be applied")
+)
+
+var reorgs = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "chain_reorgs_total",
+		Help: "Count of switches of chain to heavier competing branch",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(reorgs)
+}
+
+type undoEntry struct {
+	height int
+	undo   *storage.BlockUndo
+}
+
+// forkState keeps competing blocks which are not in chain and vault changes
+// of applied blocks, shared by copies of chain
+type forkState struct {
+	mu   sync.Mutex
+	side map[common.Hash]block.Block
+	undo map[common.Hash]undoEntry
+}
+
+func newForkState() *forkState {
+	return &forkState{
+		side: make(map[common.Hash]block.Block),
+		undo: make(map[common.Hash]undoEntry),
+	}
+}
+
+// keepUndo remembers vault changes of applied block, changes of blocks
+// deeper than MaxReorgDepth and competing blocks that deep are dropped
+func (f *forkState) keepUndo(b *block.Block, u *storage.BlockUndo) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.undo[b.Hash()] = undoEntry{height: b.Head.Height, undo: u}
+	var cutoff = b.Head.Height - MaxReorgDepth
+	for hash, entry := range f.undo {
+		if entry.height < cutoff {
+			delete(f.undo, hash)
+		}
+	}
+	for hash, side := range f.side {
+		if side.Head.Height < cutoff {
+			delete(f.side, hash)
+		}
+	}
+}
+
+func (f *forkState) getUndo(hash common.Hash) (*storage.BlockUndo, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.undo[hash]
+	return entry.undo, ok
+}
+
+func (f *forkState) addSide(b block.Block) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.side[b.Hash()] = b
+}
+
+func (f *forkState) getSide(hash common.Hash) (block.Block, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.side[hash]
+	return b, ok
+}
+
+func (f *forkState) dropSide(hash common.Hash) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.side, hash)
+}
+
+// work returns cumulative difficulty of blocks
+func work(blocks []block.Block) *big.Int {
+	var sum = big.NewInt(0)
+	for i := range blocks {
+		if d := blocks[i].Head.Difficulty; d != nil {
+			sum.Add(sum, d)
+		}
+	}
+	return sum
+}
+
+// parentOf returns block with hash from chain or from competing blocks
+func (bc *Chain) parentOf(hash common.Hash) (*block.Block, error) {
+	if b, ok := bc.blockAt(bc.index.hash(hash)); ok {
+		return b, nil
+	}
+	if b, ok := bc.forks.getSide(hash); ok {
+		return &b, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownParent, hash)
+}
+
+// branchOf returns blocks from block of chain where branch of b forks up to
+// b, and position of that block of chain
+func (bc *Chain) branchOf(b *block.Block) ([]block.Block, int, error) {
+	var branch = []block.Block{*b}
+	var prev = b.Head.PrevHash
+	for {
+		if pos, ok := bc.index.hash(prev); ok && pos < len(bc.data) {
+			return branch, pos, nil
+		}
+		if len(branch) > MaxReorgDepth {
+			return nil, 0, ErrReorgTooDeep
+		}
+		side, ok := bc.forks.getSide(prev)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %s", ErrUnknownParent, prev)
+		}
+		branch = append([]block.Block{side}, branch...)
+		prev = side.Head.PrevHash
+	}
+}
+
+// HandleCompetingBlock accepts block which is built on block of chain or on
+// other competing block. Block should be sealed and each block of its branch
+// should have difficulty retargeted from its parent. Branch of block replaces
+// top blocks of chain when total difficulty of block is higher than total
+// difficulty of chain, on equal difficulty chain keeps its blocks. Replaced
+// blocks are reverted in vault and kept as competing blocks, so chain may
+// switch back. Block on top of tip just extends chain.
+// Reports whether blocks of chain were replaced.
+func (bc *Chain) HandleCompetingBlock(b *block.Block) (reorged bool, err error) {
+	if b == nil || b.Head == nil {
+		return false, ErrNilBlock
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	var tip = bc.currentBlock
+	if tip == nil {
+		return false, fmt.Errorf("%w: chain is empty", ErrUnknownParent)
+	}
+	if _, ok := bc.blockAt(bc.index.hash(b.Hash())); ok {
+		return false, fmt.Errorf("%w: %s", ErrDuplicateBlock, b.Hash())
+	}
+	parent, err := bc.parentOf(b.Head.PrevHash)
+	if err != nil {
+		return false, err
+	}
+	if parent.Head.Height < tip.Head.Height-MaxReorgDepth {
+		return false, fmt.Errorf("%w: %d, tip %d", ErrReorgTooDeep, parent.Head.Height, tip.Head.Height)
+	}
+	// proof of work and chain id are checked with parent
+	if err := validator.CheckBlock(*b, parent); err != nil {
+		return false, err
+	}
+	if err := block.VerifyCoinbase(b); err != nil {
+		return false, err
+	}
+	branch, fork, err := bc.branchOf(b)
+	if err != nil {
+		return false, err
+	}
+	// total difficulty counts only difficulty which blocks had to meet
+	if err := bc.checkDifficulty(fork, branch); err != nil {
+		return false, err
+	}
+	bc.forks.addSide(*b)
+
+	var replaced = bc.data[fork+1:]
+	forkTD, _ := bc.GetTotalDifficulty(bc.data[fork].Hash())
+	var branchTD = forkTD.Add(forkTD, work(branch))
+	if branchTD.Cmp(bc.totalDifficulty()) <= 0 {
+		fmt.Printf("Competing block %d %s is kept, its branch is not heavier\r\n", b.Head.Height, b.Hash())
+		return false, nil
+	}
+	if err := bc.switchBranch(fork, branch); err != nil {
+		return false, err
+	}
+	if len(replaced) > 0 {
+		fmt.Printf("Chain reorg: %d blocks replaced by %d from height %d\r\n", len(replaced), len(branch), fork+1)
+		reorgs.Inc()
+	}
+	return len(replaced) > 0, nil
+}
+
+// switchBranch replaces blocks of chain above position fork with branch.
+// If some block of branch can not be applied, chain returns to its blocks.
+func (bc *Chain) switchBranch(fork int, branch []block.Block) error {
+	var old = make([]block.Block, len(bc.data)-fork-1)
+	copy(old, bc.data[fork+1:])
+	// vault is not changed unless all blocks can be reverted
+	for i := range old {
+		if _, ok := bc.forks.getUndo(old[i].Hash()); !ok {
+			return fmt.Errorf("%w: %d", ErrNoUndo, old[i].Head.Height)
+		}
+	}
+	if err := bc.revertTo(fork); err != nil {
+		return err
+	}
+	for i := range branch {
+		var err = bc.applyBlock(&branch[i])
+		if err == nil {
+			continue
+		}
+		if rerr := bc.revertTo(fork); rerr != nil {
+			fmt.Printf("Failed to revert branch: %s\r\n", rerr)
+		}
+		for j := range old {
+			if aerr := bc.applyBlock(&old[j]); aerr != nil {
+				fmt.Printf("Failed to restore block %d: %s\r\n", old[j].Head.Height, aerr)
+				break
+			}
+		}
+		bc.setTip(fork, old)
+		return fmt.Errorf("%w: block %d: %s", ErrInvalidBranch, branch[i].Head.Height, err)
+	}
+	bc.setTip(fork, old)
+	return nil
+}
+
+// revertTo reverts blocks of chain above position in vault and keeps them
+// as competing blocks
+func (bc *Chain) revertTo(pos int) error {
+	for len(bc.data)-1 > pos {
+		var last = bc.data[len(bc.data)-1]
+		undo, ok := bc.forks.getUndo(last.Hash())
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrNoUndo, last.Head.Height)
+		}
+		if err := storage.GetVault().Revert(undo); err != nil {
+			return err
+		}
+		bc.data = bc.data[:len(bc.data)-1]
+		bc.index.remove(&last)
+		bc.memos.removeBlock(&last)
+		bc.forks.addSide(last)
+		bc.info.ChainWork = bc.info.ChainWork - last.Head.Size
+	}
+	// balances of reverted blocks must not be confirmed later
+	storage.GetVault().RewindHistory(bc.data[pos].Head.Height)
+	return nil
+}
+
+// applyBlock executes reward and txs of block in vault and adds it on top
+// of chain, reward is reverted with txs when block is replaced
+func (bc *Chain) applyBlock(b *block.Block) error {
+	var vld = validator.Get()
+	var batch = storage.GetVault().BeginBatch()
+	for i := range b.Transactions {
+		var tx = &b.Transactions[i]
+		if coinbase.IsCoinBaseTransaction(tx) {
+			if err := batch.Credit(*tx.To(), tx.Value()); err != nil {
+				batch.Rollback()
+				return err
+			}
+			continue
+		}
+		if vld == nil {
+			batch.Rollback()
+			return fmt.Errorf("%w: %s, no validator", ErrInvalidBlockTx, tx.Hash())
+		}
+		from, err := types.Sender(vld.Signer(), tx)
+		if err != nil || !vld.ExecuteTransaction(tx, from, batch) {
+			batch.Rollback()
+			return fmt.Errorf("%w: %s", ErrInvalidBlockTx, tx.Hash())
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+	storage.GetVault().CommitHeight(b.Head.Height)
+	bc.data = append(bc.data, *b)
+	bc.index.add(b, len(bc.data)-1)
+	bc.forks.keepUndo(b, batch.Undo())
+	bc.forks.dropSide(b.Hash())
+	bc.memos.addBlock(b)
+	bc.info.ChainWork = bc.info.ChainWork + b.Head.Size
+	return nil
+}
+
+// setTip updates tip of chain and everything derived from blocks after
+// blocks above position fork were switched, old are blocks which were there
+// before. Blocks on top of old ones are appended to trie and chain file, both
+// are rebuilt only when some of old blocks is gone.
+func (bc *Chain) setTip(fork int, old []block.Block) {
+	var tip = bc.data[len(bc.data)-1]
+	bc.currentBlock = &tip
+	bc.info.Latest = tip.Hash()
+	bc.info.Total = len(bc.data)
+
+	var kept = 0
+	for kept < len(old) && fork+1+kept < len(bc.data) && bc.data[fork+1+kept].Hash() == old[kept].Hash() {
+		kept++
+	}
+	if kept == len(old) {
+		for i := fork + 1 + kept; i < len(bc.data); i++ {
+			bc.t.Add(bc.data[i])
+			if !bc.inMem {
+				SaveToVault(bc.data[i])
+			}
+		}
+	} else {
+		bc.txs.clear()
+		var list []trie.Content
+		for _, v := range bc.data {
+			list = append(list, v)
+		}
+		bc.t, _ = trie.NewTree(list)
+		if !bc.inMem {
+			if err := ReplaceChainVault(bc.data); err != nil {
+				fmt.Printf("Chain file is not rewritten: %s\r\n", err)
+			}
+		}
+	}
+	bc.heads.send(&tip)
+}
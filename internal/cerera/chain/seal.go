@@ -0,0 +1,106 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// count of attempts of worker added to metric at once
+const sealReportBatch = 1024
+
+var (
+	ErrSealAborted  = errors.New("nonce search aborted")
+	ErrSealEncoding = errors.New("block can not be serialized for sealing")
+)
+
+var sealAttempts = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "chain_seal_attempts_total",
+		Help: "Count of nonces tried while sealing blocks",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(sealAttempts)
+}
+
+// reportAttempts adds attempts of worker to metric and hashrate window
+func reportAttempts(n int) {
+	if n == 0 {
+		return
+	}
+	sealAttempts.Add(float64(n))
+	sealRate.add(uint64(n), time.Now())
+}
+
+// Seal searches nonce of block with hash below target of its difficulty.
+// Search runs in workers goroutines (count of cpu if not set), each worker
+// scans its own stride of nonces, block is serialized once for all of them.
+// Closing abort stops all workers, for example when block of same height came
+// from network.
+// Found nonce is set to b.
+func Seal(b *block.Block, abort <-chan struct{}, workers int) (int, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var target = block.HashTarget(b.Head.Difficulty)
+	var hs = b.PrepareHashingState()
+	if hs == nil {
+		return 0, ErrSealEncoding
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var found = make(chan int, 1)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start int) {
+			defer wg.Done()
+			var attempts = 0
+			defer func() { reportAttempts(attempts) }()
+			for nonce := start; ; nonce += workers {
+				if attempts == sealReportBatch {
+					reportAttempts(attempts)
+					attempts = 0
+				}
+				if attempts == 0 && ctx.Err() != nil {
+					return
+				}
+				var hash = hs.HashWithNonce(nonce)
+				attempts++
+				if new(big.Int).SetBytes(hash.Bytes()).Cmp(target) < 0 {
+					select {
+					case found <- nonce:
+						cancel()
+					default:
+					}
+					return
+				}
+			}
+		}(b.Nonce + w)
+	}
+	wg.Wait()
+
+	select {
+	case nonce := <-found:
+		b.Nonce = nonce
+		return nonce, nil
+	default:
+		return 0, ErrSealAborted
+	}
+}
@@ -73,6 +73,33 @@ func SaveToVault(newBlock block.Block) {
 	}
 }
 
+// ReplaceChainVault rewrites chain file with blocks, file is replaced at
+// once so it is not left half written.
+func ReplaceChainVault(blocks []block.Block) error {
+	var tmp = "./chain.dat.tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	for i := range blocks {
+		buf, err := json.Marshal(blocks[i])
+		if err != nil {
+			f.Close()
+			return err
+		}
+		writer.Write(append(buf, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, "./chain.dat")
+}
+
 // UpdateVault updates an account in the vault file.
 func UpdateVault(account []byte) error {
 	filePath := "./chain.dat"
@@ -0,0 +1,62 @@
+package chain
+
+import "sync"
+
+// SyncState tells whether node is catching up with peers.
+// Node which is catching up should not be trusted for balances and does not produce blocks.
+type SyncState struct {
+	CatchingUp bool `json:"catchingUp"`
+	Height     int  `json:"height"`
+	PeerHeight int  `json:"peerHeight"` // best known height of peers
+}
+
+// syncTracker keeps best known height of peers
+type syncTracker struct {
+	mu         sync.RWMutex
+	peerHeight int
+}
+
+func newSyncTracker() *syncTracker {
+	return &syncTracker{}
+}
+
+func (t *syncTracker) update(height int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if height > t.peerHeight {
+		t.peerHeight = height
+	}
+}
+
+func (t *syncTracker) best() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.peerHeight
+}
+
+// UpdatePeerHeight remembers height announced by peer or received with range of blocks.
+func (bc *Chain) UpdatePeerHeight(height int) {
+	bc.tracker.update(height)
+}
+
+// SyncState returns current sync state of chain.
+func (bc *Chain) SyncState() SyncState {
+	var state = SyncState{Height: -1}
+	if latest := bc.GetLatestBlock(); latest != nil {
+		state.Height = latest.Head.Height
+	}
+	state.PeerHeight = bc.tracker.best()
+	state.CatchingUp = state.PeerHeight > state.Height
+	return state
+}
+
+// IsSynced reports whether chain reached best known height of peers.
+func (bc *Chain) IsSynced() bool {
+	return !bc.SyncState().CatchingUp
+}
@@ -0,0 +1,141 @@
+package chain
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/cerera/validator"
+)
+
+// txCache is lru cache of txs found in blocks of chain
+type txCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently used
+	items map[common.Hash]*list.Element
+}
+
+type txEntry struct {
+	hash   common.Hash
+	tx     types.GTransaction
+	height int
+}
+
+func newTxCache(size int) *txCache {
+	if size < 1 {
+		size = 1
+	}
+	return &txCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[common.Hash]*list.Element),
+	}
+}
+
+func (c *txCache) get(hash common.Hash) (*types.GTransaction, int, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[hash]; ok {
+		c.order.MoveToFront(el)
+		var entry = el.Value.(*txEntry)
+		var tx = entry.tx
+		return &tx, entry.height, true
+	}
+	return nil, 0, false
+}
+
+func (c *txCache) add(hash common.Hash, tx types.GTransaction, height int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[hash]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[hash] = c.order.PushFront(&txEntry{hash: hash, tx: tx, height: height})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*txEntry).hash)
+	}
+}
+
+// clear drops all cached txs, blocks including them may be not in chain anymore
+func (c *txCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[common.Hash]*list.Element)
+}
+
+// LocateTransaction returns tx with hash, its sender and block including it.
+// Sender is recovered from signature when validator is set up.
+func (bc *Chain) LocateTransaction(hash common.Hash) (*types.GTransaction, types.Address, *block.Block, bool) {
+	tx, height := bc.FindTransaction(hash, 0)
+	if tx == nil {
+		return nil, types.Address{}, nil, false
+	}
+	b, err := bc.GetBlockByHeight(height)
+	if err != nil {
+		return nil, types.Address{}, nil, false
+	}
+	var from = tx.From()
+	if v := validator.Get(); v != nil {
+		if sender, err := types.Sender(v.Signer(), tx); err == nil {
+			from = sender
+		}
+	}
+	return tx, from, b, true
+}
+
+// runningChain locates txs in chain run by node, copy of chain does not see
+// blocks added after it was made
+type runningChain struct{}
+
+func (runningChain) LocateTransaction(hash common.Hash) (*types.GTransaction, types.Address, *block.Block, bool) {
+	return bch.LocateTransaction(hash)
+}
+
+// RunningTxLocator returns locator of txs in chain run by node.
+func RunningTxLocator() storage.TxLocator {
+	return runningChain{}
+}
+
+// FindTransaction returns tx with hash and height of block including it.
+// Only last depth blocks are scanned, depth <= 0 scans whole chain.
+// Found txs are cached, nil and -1 are returned when tx is not found.
+func (bc *Chain) FindTransaction(hash common.Hash, depth int) (*types.GTransaction, int) {
+	if tx, height, ok := bc.txs.get(hash); ok {
+		return tx, height
+	}
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	var stop = 0
+	if depth > 0 && len(bc.data) > depth {
+		stop = len(bc.data) - depth
+	}
+	for i := len(bc.data) - 1; i >= stop; i-- {
+		var b = &bc.data[i]
+		for j := range b.Transactions {
+			if b.Transactions[j].Hash() != hash {
+				continue
+			}
+			var tx = b.Transactions[j]
+			bc.txs.add(hash, tx, b.Head.Height)
+			return &tx, b.Head.Height
+		}
+	}
+	return nil, -1
+}
@@ -3,9 +3,12 @@ package config
 import (
 	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
+	"runtime"
+	"time"
 
 	"github.com/cerera/internal/cerera/types"
 	"github.com/libp2p/go-libp2p/core/protocol"
@@ -16,29 +19,167 @@ const DefaultRpcPort = int(1337)
 
 var ChainId = big.NewInt(133707331)
 
+// difficulty of genesis block when it is not set in config, about 4M hashes
+// which one core seals within DefaultTargetBlockInterval, retarget adjusts it
+var DefaultGenesisDifficulty = big.NewInt(1 << 22)
+
+// count of account trie shards when it is not set in config
+const DefaultVaultShards = 16
+
+// target time between blocks (ms) when it is not set in config
+const DefaultTargetBlockInterval = 10000
+
+// max count of addresses in one bulk balance request when it is not set in config
+const DefaultMaxBulkBalances = 100
+
+// count of contracts which code is kept in vault cache
+const DefaultCodeCacheSize = 256
+
+// count of found txs kept in chain lookup cache
+const DefaultTxCacheSize = 128
+
+// intrinsic gas of tx: base cost and cost of each byte of data
+const (
+	DefaultTxBaseGas = 10
+	DefaultTxDataGas = 1
+)
+
+// file of consensus nonce kept across restarts of node
+const DefaultNonceFile = "./consensus.nonce"
+
+// file of pending txs of pool kept across restarts of node
+const DefaultPoolFile = "./pool.dat"
+
+// time tx with future nonce waits in pool for missing nonces
+const DefaultQueueTTL = 10 * time.Minute
+
+// time tx waits in pool for being prepared for block before eviction
+const DefaultTxMaxAge = 3 * time.Hour
+
+// percent by which gas price of tx should exceed gas price of pending tx
+// with same sender and nonce to replace it
+const DefaultPriceBump = 10
+
+// count of hashes of received blocks remembered to drop duplicates
+const DefaultSeenBlocks = 1024
+
+// score of peer below which it is banned
+const DefaultBanScore = -20
+
+// delays and retries of connection to swarm bootstrap when they are not set in config
+const (
+	DefaultBootstrapBaseDelay  = 3 * time.Second
+	DefaultBootstrapMaxDelay   = 60 * time.Second
+	DefaultBootstrapMaxRetries = 10
+)
+
+// time before unanswered WHO_IS request is re-sent and max count of its sends
+const (
+	DefaultWhoIsTimeout  = 10 * time.Second
+	DefaultWhoIsAttempts = 3
+)
+
+// max count of state syncs served at once and min interval between syncs of one peer
+const (
+	DefaultMaxSyncs     = 4
+	DefaultSyncInterval = 30 * time.Second
+)
+
+// time to wait for answer of peer to block request
+const DefaultBlockRequestTimeout = 5 * time.Second
+
+// timestamp of genesis block (ms) when it is not set in config, 2024-01-01 00:00:00 UTC
+const DefaultGenesisTimestamp = uint64(1704067200000)
+
+// policies of block generation when consensus is not reached
+const (
+	MiningPolicyStrict     = "strict"     // never generate blocks without consensus
+	MiningPolicyPermissive = "permissive" // generate blocks anyway, warn about missing consensus
+	MiningPolicySolo       = "solo"       // generate blocks regardless of consensus
+)
+
+// upper bound for genesis difficulty, anything above is unreachable
+var MaxGenesisDifficulty = new(big.Int).Lsh(big.NewInt(1), 64)
+
+var (
+	ErrGenesisDifficultyTooLow  = errors.New("genesis difficulty should be positive")
+	ErrGenesisDifficultyTooHigh = errors.New("genesis difficulty is too high")
+	ErrInvalidBlockInterval     = errors.New("target block interval should be positive")
+	ErrUnknownMiningPolicy      = errors.New("unknown mining policy")
+	ErrInvalidMinVoters         = errors.New("min voters should not be negative")
+	ErrInvalidChainID           = errors.New("chain id should be positive")
+	ErrInvalidPoolSize          = errors.New("pool size should not be negative")
+	ErrInvalidPort              = errors.New("port is out of range")
+)
+
 type ChainConfig struct {
 	ChainID *big.Int
 	Path    string
 	Type    string
+	MEM     bool // keep blocks in memory only, without chain file
+
+	GenesisDifficulty *big.Int // difficulty of genesis block, same for all nodes
+	GenesisTimestamp  uint64   // timestamp of genesis block (ms), start epoch of chain
+	GenesisNonce      uint64   // nonce of genesis block, zero means default
+	GenesisGasLimit   uint64   // gas limit of genesis block, zero means default
+	WaitGenesis       bool     // do not create genesis block, wait for it from peers
+	MemoIndex         bool     // index txs by utf-8 memo in data field
+
+	TargetBlockInterval int    // target time between blocks (ms), used by block generation and retargeting
+	MiningPolicy        string // strict, permissive or solo, empty means chosen by count of voters
+	MinVoters           int    // count of voters required to start consensus, zero means any
+	TxCache             int    // size of cache of txs found in blocks
+	NonceFile           string // file of consensus nonce kept across restarts
+	TxBaseGas           uint64 // intrinsic gas of every tx, zero means default
+	TxDataGas           uint64 // intrinsic gas of each byte of tx data, zero means default
+	SealWorkers         int    // goroutines searching nonce of generated block, zero means count of cpu
 }
 type NetworkConfig struct {
-	PID  protocol.ID
-	P2P  int
-	RPC  int
-	ADDR types.Address // address of running node
-	PRIV string        // private key of current running node
-	PUB  []byte        // public key of current running node
+	PID    protocol.ID
+	P2P    int
+	RPC    int
+	ADDR   types.Address   // address of running node
+	PRIV   string          // private key of current running node
+	PUB    []byte          // public key of current running node
+	SKEW   int             // max offset of node clock with peers (seconds)
+	BULK   int             // max count of addresses in one bulk balance request
+	SEEN   int             // count of hashes of received blocks kept to drop duplicates
+	BAN    int             // score of peer below which it is banned, zero means default
+	BOOT   BootstrapConfig // retries of connection to swarm bootstrap
+	WHOIS  int             // seconds before unanswered WHO_IS request is re-sent
+	WHOISN int             // max count of sends of WHO_IS request for one address
+	SYNCS  int             // max count of state syncs served at once
+	SYNCI  int             // min seconds between state syncs of one peer
+	BLOCKT int             // seconds to wait for answer to block request
+}
+type BootstrapConfig struct {
+	BaseDelay             int      // first delay between dials (ms), doubled by each retry
+	MaxDelay              int      // max delay between dials (ms)
+	MaxRetries            int      // count of retries with growing delay
+	GiveUpAfterMaxRetries bool     // stop dialing after max retries instead of dialing at max delay
+	BootstrapAddrs        []string // multiaddrs of bootstrap nodes in order of preference, swarm file if empty
 }
 type VaultConfig struct {
-	MEM  bool
-	PATH string
+	MEM    bool // keep accounts in memory only, without vault file
+	PATH   string
+	SHARDS int // count of account trie shards, each with own lock
+	CODE   int // size of contract code cache
 }
 type PoolConfig struct {
 	MinGas  uint64
 	MaxSize int
+	MEM     bool   // keep pending transactions in memory only
+	File    string // file of pending transactions kept across restarts
+	TTL     int    // seconds tx with future nonce waits for missing nonces
+	MaxAge  int    // seconds tx waits in pool before eviction
+	// percent by which gas price of replacing tx exceeds replaced one
+	PriceBump int
+	// gas price floor of contract creation, zero means floor of other txs
+	CreateGasPrice uint64
 }
 type HttpSecConfig struct {
-	TLS bool
+	TLS        bool
+	AdminToken string // bearer token of /admin endpoints, empty disables them
 }
 type Sec struct {
 	HTTP HttpSecConfig
@@ -57,36 +198,51 @@ type Config struct {
 	VER     int    // other version field
 }
 
+// DefaultConfig returns config of node used when there is no config file.
+func DefaultConfig() *Config {
+	return &Config{
+		TlsFlag: false,
+		POOL: PoolConfig{
+			MinGas:  3,
+			MaxSize: 1000,
+			MEM:     false,
+		},
+		Vault: VaultConfig{
+			MEM:    false,
+			PATH:   "EMPTY",
+			SHARDS: DefaultVaultShards,
+			CODE:   DefaultCodeCacheSize,
+		},
+		SEC: Sec{
+			HTTP: HttpSecConfig{
+				TLS: false,
+			},
+		},
+		NetCfg: NetworkConfig{
+			PID: "/vavilov/1.0.0",
+		},
+		Chain: ChainConfig{
+			ChainID: big.NewInt(11),
+			Path:    "EMPTY",
+			Type:    "VAVILOV",
+			MEM:     false,
+
+			GenesisDifficulty: new(big.Int).Set(DefaultGenesisDifficulty),
+			GenesisTimestamp:  DefaultGenesisTimestamp,
+
+			TargetBlockInterval: DefaultTargetBlockInterval,
+			TxCache:             DefaultTxCacheSize,
+		},
+		VERSION: "ALPHA",
+		VER:     1,
+	}
+}
+
 func GenerageConfig() *Config {
 	configFilePath := "config.json"
 	cfg := &Config{}
 	if _, err := os.Stat(configFilePath); os.IsNotExist(err) {
-		cfg = &Config{
-			TlsFlag: false,
-			POOL: PoolConfig{
-				MinGas:  3,
-				MaxSize: 1000,
-			},
-			Vault: VaultConfig{
-				MEM:  true,
-				PATH: "EMPTY",
-			},
-			SEC: Sec{
-				HTTP: HttpSecConfig{
-					TLS: false,
-				},
-			},
-			NetCfg: NetworkConfig{
-				PID: "/vavilov/1.0.0",
-			},
-			Chain: ChainConfig{
-				ChainID: big.NewInt(11),
-				Path:    "EMPTY",
-				Type:    "VAVILOV",
-			},
-			VERSION: "ALPHA",
-			VER:     1,
-		}
+		cfg = DefaultConfig()
 		cfg.WriteConfigToFile()
 	} else {
 		cfg, err = ReadConfig(configFilePath)
@@ -156,6 +312,283 @@ func (cfg *Config) SetAutoGen(f bool) {
 	cfg.AUTOGEN = f
 	cfg.WriteConfigToFile()
 }
+
+// SetInMem switches vault, pool and chain to in-memory mode at once.
+// Each subsystem can still be configured separately via its own MEM field.
+func (cfg *Config) SetInMem(f bool) {
+	cfg.Vault.MEM = f
+	cfg.POOL.MEM = f
+	cfg.Chain.MEM = f
+	cfg.WriteConfigToFile()
+}
+
+// fieldError names field of config with invalid value
+func fieldError(field string, err error) error {
+	return fmt.Errorf("config field %s: %w", field, err)
+}
+
+// Validate checks values of config which can not be fixed at runtime.
+// Error names the field with invalid value.
+func (cfg *Config) Validate() error {
+	if id := cfg.Chain.ChainID; id != nil && id.Sign() <= 0 {
+		return fieldError("Chain.ChainID", ErrInvalidChainID)
+	}
+	if d := cfg.Chain.GenesisDifficulty; d != nil {
+		if d.Sign() <= 0 {
+			return fieldError("Chain.GenesisDifficulty", ErrGenesisDifficultyTooLow)
+		}
+		if d.Cmp(MaxGenesisDifficulty) > 0 {
+			return fieldError("Chain.GenesisDifficulty", ErrGenesisDifficultyTooHigh)
+		}
+	}
+	if cfg.Chain.TargetBlockInterval < 0 {
+		return fieldError("Chain.TargetBlockInterval", ErrInvalidBlockInterval)
+	}
+	if cfg.Chain.MinVoters < 0 {
+		return fieldError("Chain.MinVoters", ErrInvalidMinVoters)
+	}
+	switch cfg.Chain.MiningPolicy {
+	case "", MiningPolicyStrict, MiningPolicyPermissive, MiningPolicySolo:
+	default:
+		return fieldError("Chain.MiningPolicy", ErrUnknownMiningPolicy)
+	}
+	if cfg.POOL.MaxSize < 0 {
+		return fieldError("POOL.MaxSize", ErrInvalidPoolSize)
+	}
+	// -1 means default port, see SetPorts
+	if cfg.NetCfg.RPC < -1 || cfg.NetCfg.RPC > 65535 {
+		return fieldError("NetCfg.RPC", ErrInvalidPort)
+	}
+	if cfg.NetCfg.P2P < -1 || cfg.NetCfg.P2P > 65535 {
+		return fieldError("NetCfg.P2P", ErrInvalidPort)
+	}
+	return nil
+}
+
+// GetMiningPolicy returns mining policy from config.
+// If policy is not set, single voter mines solo and multi-node network is strict.
+func (cfg *Config) GetMiningPolicy(voters int) string {
+	if cfg.Chain.MiningPolicy != "" {
+		return cfg.Chain.MiningPolicy
+	}
+	return DefaultMiningPolicy(voters)
+}
+
+// DefaultMiningPolicy returns mining policy for given count of voters.
+func DefaultMiningPolicy(voters int) string {
+	if voters <= 1 {
+		return MiningPolicySolo
+	}
+	return MiningPolicyStrict
+}
+
+// GetTargetBlockInterval returns target time between blocks or default one if not set.
+func (cfg *Config) GetTargetBlockInterval() time.Duration {
+	if cfg.Chain.TargetBlockInterval == 0 {
+		return DefaultTargetBlockInterval * time.Millisecond
+	}
+	return time.Duration(cfg.Chain.TargetBlockInterval) * time.Millisecond
+}
+
+// GetGenesisDifficulty returns difficulty of genesis block or default one if not set.
+func (cfg *Config) GetGenesisDifficulty() *big.Int {
+	if cfg.Chain.GenesisDifficulty == nil {
+		return new(big.Int).Set(DefaultGenesisDifficulty)
+	}
+	return new(big.Int).Set(cfg.Chain.GenesisDifficulty)
+}
+
+// GetSealWorkers returns count of goroutines searching nonce of block or count of cpu if not set.
+func (cfg *Config) GetSealWorkers() int {
+	if cfg.Chain.SealWorkers <= 0 {
+		return runtime.NumCPU()
+	}
+	return cfg.Chain.SealWorkers
+}
+
+// GetGenesisTimestamp returns timestamp of genesis block or default one if not set.
+func (cfg *Config) GetGenesisTimestamp() uint64 {
+	if cfg.Chain.GenesisTimestamp == 0 {
+		return DefaultGenesisTimestamp
+	}
+	return cfg.Chain.GenesisTimestamp
+}
+
+// GetVaultShards returns count of account trie shards or default one if not set.
+func (cfg *Config) GetVaultShards() int {
+	if cfg.Vault.SHARDS <= 0 {
+		return DefaultVaultShards
+	}
+	return cfg.Vault.SHARDS
+}
+
+// GetMaxBulkBalances returns max count of addresses in bulk balance request or default one if not set.
+func (cfg *Config) GetMaxBulkBalances() int {
+	if cfg.NetCfg.BULK <= 0 {
+		return DefaultMaxBulkBalances
+	}
+	return cfg.NetCfg.BULK
+}
+
+// GetCodeCacheSize returns size of contract code cache or default one if not set.
+func (cfg *Config) GetCodeCacheSize() int {
+	if cfg.Vault.CODE <= 0 {
+		return DefaultCodeCacheSize
+	}
+	return cfg.Vault.CODE
+}
+
+// GetTxCacheSize returns size of tx lookup cache or default one if not set.
+func (cfg *Config) GetTxCacheSize() int {
+	if cfg.Chain.TxCache <= 0 {
+		return DefaultTxCacheSize
+	}
+	return cfg.Chain.TxCache
+}
+
+// GetGasSchedule returns intrinsic gas costs of tx, default ones for unset values.
+func (cfg *Config) GetGasSchedule() types.GasSchedule {
+	var s = types.GasSchedule{Base: cfg.Chain.TxBaseGas, PerByte: cfg.Chain.TxDataGas}
+	if s.Base == 0 {
+		s.Base = DefaultTxBaseGas
+	}
+	if s.PerByte == 0 {
+		s.PerByte = DefaultTxDataGas
+	}
+	return s
+}
+
+// GetCreateGasPrice returns gas price floor of contract creation, nil if not set.
+func (cfg *Config) GetCreateGasPrice() *big.Int {
+	if cfg.POOL.CreateGasPrice == 0 {
+		return nil
+	}
+	return new(big.Int).SetUint64(cfg.POOL.CreateGasPrice)
+}
+
+// GetPoolFile returns file of pending txs or default one if not set,
+// in memory pool does not keep txs.
+func (cfg *Config) GetPoolFile() string {
+	if cfg.POOL.MEM {
+		return ""
+	}
+	if cfg.POOL.File == "" {
+		return DefaultPoolFile
+	}
+	return cfg.POOL.File
+}
+
+// GetNonceFile returns file of consensus nonce or default one if not set,
+// in memory chain does not keep nonce.
+func (cfg *Config) GetNonceFile() string {
+	if cfg.Chain.MEM {
+		return ""
+	}
+	if cfg.Chain.NonceFile == "" {
+		return DefaultNonceFile
+	}
+	return cfg.Chain.NonceFile
+}
+
+// GetQueueTTL returns time tx with future nonce is kept in pool or default one if not set.
+func (cfg *Config) GetQueueTTL() time.Duration {
+	if cfg.POOL.TTL <= 0 {
+		return DefaultQueueTTL
+	}
+	return time.Duration(cfg.POOL.TTL) * time.Second
+}
+
+// GetTxMaxAge returns time tx is kept in pool or default one if not set.
+func (cfg *Config) GetTxMaxAge() time.Duration {
+	if cfg.POOL.MaxAge <= 0 {
+		return DefaultTxMaxAge
+	}
+	return time.Duration(cfg.POOL.MaxAge) * time.Second
+}
+
+// GetPriceBump returns percent of gas price bump of replacing tx or default one if not set.
+func (cfg *Config) GetPriceBump() int {
+	if cfg.POOL.PriceBump <= 0 {
+		return DefaultPriceBump
+	}
+	return cfg.POOL.PriceBump
+}
+
+// GetSeenBlocks returns count of remembered hashes of received blocks or default one if not set.
+func (cfg *Config) GetSeenBlocks() int {
+	if cfg.NetCfg.SEEN <= 0 {
+		return DefaultSeenBlocks
+	}
+	return cfg.NetCfg.SEEN
+}
+
+// GetBanScore returns score of peer below which it is banned or default one if not set.
+func (cfg *Config) GetBanScore() int {
+	if cfg.NetCfg.BAN == 0 {
+		return DefaultBanScore
+	}
+	return cfg.NetCfg.BAN
+}
+
+// GetBootstrap returns retries of connection to swarm bootstrap, unset values are default.
+func (cfg *Config) GetBootstrap() BootstrapConfig {
+	var boot = cfg.NetCfg.BOOT
+	if boot.BaseDelay <= 0 {
+		boot.BaseDelay = int(DefaultBootstrapBaseDelay / time.Millisecond)
+	}
+	if boot.MaxDelay <= 0 {
+		boot.MaxDelay = int(DefaultBootstrapMaxDelay / time.Millisecond)
+	}
+	if boot.MaxRetries <= 0 {
+		boot.MaxRetries = DefaultBootstrapMaxRetries
+	}
+	return boot
+}
+
+// GetWhoIsTimeout returns time before unanswered WHO_IS request is re-sent or default one if not set.
+func (cfg *Config) GetWhoIsTimeout() time.Duration {
+	if cfg.NetCfg.WHOIS <= 0 {
+		return DefaultWhoIsTimeout
+	}
+	return time.Duration(cfg.NetCfg.WHOIS) * time.Second
+}
+
+// GetWhoIsAttempts returns max count of sends of WHO_IS request or default one if not set.
+func (cfg *Config) GetWhoIsAttempts() int {
+	if cfg.NetCfg.WHOISN <= 0 {
+		return DefaultWhoIsAttempts
+	}
+	return cfg.NetCfg.WHOISN
+}
+
+// GetMaxSyncs returns max count of state syncs served at once or default one if not set.
+func (cfg *Config) GetMaxSyncs() int {
+	if cfg.NetCfg.SYNCS <= 0 {
+		return DefaultMaxSyncs
+	}
+	return cfg.NetCfg.SYNCS
+}
+
+// GetSyncInterval returns min interval between state syncs of one peer or default one if not set.
+func (cfg *Config) GetSyncInterval() time.Duration {
+	if cfg.NetCfg.SYNCI <= 0 {
+		return DefaultSyncInterval
+	}
+	return time.Duration(cfg.NetCfg.SYNCI) * time.Second
+}
+
+// GetBlockRequestTimeout returns time to wait for answer to block request or default one if not set.
+func (cfg *Config) GetBlockRequestTimeout() time.Duration {
+	if cfg.NetCfg.BLOCKT <= 0 {
+		return DefaultBlockRequestTimeout
+	}
+	return time.Duration(cfg.NetCfg.BLOCKT) * time.Second
+}
+
+// IsGenesisProducer reports whether node creates genesis block of empty chain.
+func (cfg *Config) IsGenesisProducer() bool {
+	return !cfg.Chain.WaitGenesis
+}
 func (cfg *Config) CheckVersion(version string, ver int) bool {
 	return (cfg.VER == ver) && (cfg.VERSION == version)
 }
@@ -1,20 +1,39 @@
 package config
 
 import (
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// inTempDir runs test in temp dir, so config.json written by config setters
+// does not land in package dir
+func inTempDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
 func TestGenerateConfig(t *testing.T) {
+	inTempDir(t)
 	cfg := GenerageConfig()
 
 	assert.NotNil(t, cfg)
 	assert.False(t, cfg.TlsFlag)
 	assert.Equal(t, uint64(3), cfg.POOL.MinGas)
 	assert.Equal(t, 1000, cfg.POOL.MaxSize)
-	assert.True(t, cfg.Vault.MEM)
-	assert.Equal(t, "", cfg.Vault.PATH)
+	assert.False(t, cfg.Vault.MEM, "default vault keeps accounts on disk")
+	assert.False(t, cfg.POOL.MEM)
+	assert.Equal(t, "EMPTY", cfg.Vault.PATH)
 	assert.False(t, cfg.SEC.HTTP.TLS)
 	assert.Equal(t, "/vavilov/1.0.0", string(cfg.NetCfg.PID))
 	assert.Equal(t, "ALPHA", cfg.VERSION)
@@ -22,6 +41,7 @@ func TestGenerateConfig(t *testing.T) {
 }
 
 func TestSetPorts(t *testing.T) {
+	inTempDir(t)
 	cfg := &Config{}
 	cfg.SetPorts(8080, 30303)
 	assert.Equal(t, 8080, cfg.NetCfg.RPC)
@@ -33,6 +53,7 @@ func TestSetPorts(t *testing.T) {
 }
 
 func TestSetAutoGen(t *testing.T) {
+	inTempDir(t)
 	cfg := &Config{}
 	cfg.SetAutoGen(true)
 	assert.True(t, cfg.AUTOGEN)
@@ -54,7 +75,118 @@ func TestGetVersion(t *testing.T) {
 }
 
 func TestUpdateVaultPath(t *testing.T) {
+	inTempDir(t)
 	cfg := &Config{}
 	cfg.UpdateVaultPath("/new/path")
 	assert.Equal(t, "/new/path", cfg.Vault.PATH)
 }
+
+func TestSetInMem(t *testing.T) {
+	inTempDir(t)
+	cfg := &Config{}
+	cfg.SetInMem(true)
+	assert.True(t, cfg.Vault.MEM)
+	assert.True(t, cfg.POOL.MEM)
+	assert.True(t, cfg.Chain.MEM)
+
+	cfg.SetInMem(false)
+	cfg.POOL.MEM = true
+	assert.False(t, cfg.Vault.MEM)
+	assert.True(t, cfg.POOL.MEM)
+	assert.False(t, cfg.Chain.MEM)
+}
+
+func TestValidateGenesisDifficulty(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.Validate(), "empty genesis difficulty falls back to default")
+	assert.Equal(t, DefaultGenesisDifficulty, cfg.GetGenesisDifficulty())
+
+	cfg.Chain.GenesisDifficulty = big.NewInt(0)
+	assert.ErrorIs(t, cfg.Validate(), ErrGenesisDifficultyTooLow)
+
+	cfg.Chain.GenesisDifficulty = new(big.Int).Add(MaxGenesisDifficulty, big.NewInt(1))
+	assert.ErrorIs(t, cfg.Validate(), ErrGenesisDifficultyTooHigh)
+
+	cfg.Chain.GenesisDifficulty = big.NewInt(1000)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, big.NewInt(1000), cfg.GetGenesisDifficulty())
+}
+
+func TestTargetBlockInterval(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.Validate(), "empty interval falls back to default")
+	assert.Equal(t, DefaultTargetBlockInterval*time.Millisecond, cfg.GetTargetBlockInterval())
+
+	cfg.Chain.TargetBlockInterval = -1
+	assert.ErrorIs(t, cfg.Validate(), ErrInvalidBlockInterval)
+
+	cfg.Chain.TargetBlockInterval = 2500
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 2500*time.Millisecond, cfg.GetTargetBlockInterval())
+}
+
+func TestMiningPolicy(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, MiningPolicySolo, cfg.GetMiningPolicy(1), "single voter mines solo")
+	assert.Equal(t, MiningPolicyStrict, cfg.GetMiningPolicy(3), "multi-node waits for consensus")
+
+	cfg.Chain.MiningPolicy = MiningPolicyPermissive
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, MiningPolicyPermissive, cfg.GetMiningPolicy(3))
+
+	cfg.Chain.MiningPolicy = "greedy"
+	assert.ErrorIs(t, cfg.Validate(), ErrUnknownMiningPolicy)
+
+	cfg.Chain.MiningPolicy = ""
+	cfg.Chain.MinVoters = -1
+	assert.ErrorIs(t, cfg.Validate(), ErrInvalidMinVoters)
+}
+
+func TestLoadConfig(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "node.json")
+	var write = func(data string) {
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+
+	// missing fields keep defaults
+	write(`{"NetCfg": {"RPC": 1400}, "POOL": {"MaxSize": 50}}`)
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1400, cfg.NetCfg.RPC)
+	assert.Equal(t, 50, cfg.POOL.MaxSize)
+	assert.Equal(t, uint64(3), cfg.POOL.MinGas)
+	assert.Equal(t, int64(11), cfg.Chain.ChainID.Int64())
+
+	// environment overrides file
+	t.Setenv("CERERA_HTTP_PORT", "1500")
+	t.Setenv("CERERA_CHAIN_ID", "77")
+	cfg, err = LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500, cfg.NetCfg.RPC)
+	assert.Equal(t, int64(77), cfg.Chain.ChainID.Int64())
+
+	t.Setenv("CERERA_HTTP_PORT", "http")
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "NetCfg.RPC")
+	assert.ErrorContains(t, err, "CERERA_HTTP_PORT")
+	t.Setenv("CERERA_HTTP_PORT", "1500")
+
+	t.Setenv("CERERA_CHAIN_ID", "0")
+	_, err = LoadConfig(path)
+	assert.ErrorIs(t, err, ErrInvalidChainID)
+	assert.ErrorContains(t, err, "Chain.ChainID")
+	t.Setenv("CERERA_CHAIN_ID", "77")
+
+	write(`{"POOL": {"MaxSize": -1}}`)
+	_, err = LoadConfig(path)
+	assert.ErrorIs(t, err, ErrInvalidPoolSize)
+	assert.ErrorContains(t, err, "POOL.MaxSize")
+
+	write(`{"POOL": {"MaxSize": "big"}}`)
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "POOL.MaxSize")
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envOverride sets field of config from environment variable
+type envOverride struct {
+	name  string
+	field string
+	set   func(cfg *Config, value string) error
+}
+
+func intField(get func(cfg *Config) *int) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*get(cfg) = v
+		return nil
+	}
+}
+
+func uintField(get func(cfg *Config) *uint64) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*get(cfg) = v
+		return nil
+	}
+}
+
+func boolField(get func(cfg *Config) *bool) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*get(cfg) = v
+		return nil
+	}
+}
+
+func stringField(get func(cfg *Config) *string) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		*get(cfg) = value
+		return nil
+	}
+}
+
+// environment variables which override values of config file
+var envOverrides = []envOverride{
+	{"CERERA_HTTP_PORT", "NetCfg.RPC", intField(func(cfg *Config) *int { return &cfg.NetCfg.RPC })},
+	{"CERERA_P2P_PORT", "NetCfg.P2P", intField(func(cfg *Config) *int { return &cfg.NetCfg.P2P })},
+	{"CERERA_BOOTSTRAP", "NetCfg.BOOT.BootstrapAddrs", func(cfg *Config, value string) error {
+		// comma separated multiaddrs in order of preference
+		cfg.NetCfg.BOOT.BootstrapAddrs = strings.Split(value, ",")
+		return nil
+	}},
+	{"CERERA_CHAIN_ID", "Chain.ChainID", func(cfg *Config, value string) error {
+		id, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		cfg.Chain.ChainID = id
+		return nil
+	}},
+	{"CERERA_CHAIN_PATH", "Chain.Path", stringField(func(cfg *Config) *string { return &cfg.Chain.Path })},
+	{"CERERA_SEAL_WORKERS", "Chain.SealWorkers", intField(func(cfg *Config) *int { return &cfg.Chain.SealWorkers })},
+	{"CERERA_MINING_POLICY", "Chain.MiningPolicy", stringField(func(cfg *Config) *string { return &cfg.Chain.MiningPolicy })},
+	{"CERERA_VAULT_PATH", "Vault.PATH", stringField(func(cfg *Config) *string { return &cfg.Vault.PATH })},
+	{"CERERA_VAULT_MEM", "Vault.MEM", boolField(func(cfg *Config) *bool { return &cfg.Vault.MEM })},
+	{"CERERA_POOL_MIN_GAS", "POOL.MinGas", uintField(func(cfg *Config) *uint64 { return &cfg.POOL.MinGas })},
+	{"CERERA_POOL_MAX_SIZE", "POOL.MaxSize", intField(func(cfg *Config) *int { return &cfg.POOL.MaxSize })},
+	{"CERERA_POOL_MEM", "POOL.MEM", boolField(func(cfg *Config) *bool { return &cfg.POOL.MEM })},
+	{"CERERA_ADMIN_TOKEN", "SEC.HTTP.AdminToken", stringField(func(cfg *Config) *string { return &cfg.SEC.HTTP.AdminToken })},
+	{"CERERA_AUTOGEN", "AUTOGEN", boolField(func(cfg *Config) *bool { return &cfg.AUTOGEN })},
+}
+
+// applyEnv overrides values of config by set environment variables.
+func (cfg *Config) applyEnv() error {
+	for _, o := range envOverrides {
+		value, ok := os.LookupEnv(o.name)
+		if !ok {
+			continue
+		}
+		if err := o.set(cfg, value); err != nil {
+			return fieldError(o.field, fmt.Errorf("environment variable %s: %w", o.name, err))
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads config file and merges it over default config, fields
+// missing in file keep default values. Then environment variables like
+// CERERA_HTTP_PORT override values of file and result is validated, so
+// several nodes can run from one binary with own config files.
+func LoadConfig(path string) (*Config, error) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from file: %w", err)
+	}
+	var cfg = DefaultConfig()
+	if err := json.Unmarshal(fileData, cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fieldError(typeErr.Field, err)
+		}
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
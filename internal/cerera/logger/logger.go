@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// name of global level in GetLevels and SetLevel
+const GlobalName = "*"
+
+var (
+	ErrInvalidLevel  = errors.New("invalid log level")
+	ErrUnknownLogger = errors.New("unknown logger")
+)
+
+// nameLevel is level of named logger, it follows global level until it is
+// overridden
+type nameLevel struct {
+	level      zap.AtomicLevel
+	overridden atomic.Bool
+}
+
+func (l *nameLevel) Enabled(lvl zapcore.Level) bool {
+	if l.overridden.Load() {
+		return l.level.Enabled(lvl)
+	}
+	return global.Enabled(lvl)
+}
+
+func (l *nameLevel) String() string {
+	if l.overridden.Load() {
+		return l.level.String()
+	}
+	return global.String()
+}
+
+var (
+	global = zap.NewAtomicLevelAt(zap.InfoLevel)
+	out    = zapcore.Lock(os.Stdout)
+
+	mu    sync.Mutex
+	names = make(map[string]*nameLevel)
+)
+
+func parseLevel(level string) (zapcore.Level, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return lvl, fmt.Errorf("%w: %q", ErrInvalidLevel, level)
+	}
+	return lvl, nil
+}
+
+// register returns level of name, creating it on first use. Only Named
+// registers names, so set of names is bounded by code.
+func register(name string) *nameLevel {
+	mu.Lock()
+	defer mu.Unlock()
+	l, ok := names[name]
+	if !ok {
+		l = &nameLevel{level: zap.NewAtomicLevel()}
+		names[name] = l
+	}
+	return l
+}
+
+// Init sets global level of loggers, named loggers without own level follow it.
+func Init(level string) error {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	global.SetLevel(lvl)
+	return nil
+}
+
+// Named returns logger of subsystem, its level may be changed by SetLevel
+// at any time.
+func Named(name string) *zap.SugaredLogger {
+	var encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	var core = zapcore.NewCore(encoder, out, register(name))
+	return zap.New(core).Named(name).Sugar()
+}
+
+// SetLevel changes level of named logger, loggers of name created before
+// get new level too. GlobalName changes global level. Name never passed
+// to Named is rejected with ErrUnknownLogger.
+func SetLevel(namedLogger string, level string) error {
+	if namedLogger == GlobalName {
+		return Init(level)
+	}
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	l, ok := names[namedLogger]
+	mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownLogger, namedLogger)
+	}
+	l.level.SetLevel(lvl)
+	l.overridden.Store(true)
+	return nil
+}
+
+// GetLevels returns level of every named logger and global level under
+// GlobalName.
+func GetLevels() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	var res = make(map[string]string, len(names)+1)
+	for name, l := range names {
+		res[name] = l.String()
+	}
+	res[GlobalName] = global.String()
+	return res
+}
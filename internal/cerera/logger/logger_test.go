@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetLevel(t *testing.T) {
+	defer Init("info")
+	// logger created before override gets it
+	var miner = Named("miner").Desugar().Core()
+	var pool = Named("pool").Desugar().Core()
+	if miner.Enabled(zapcore.DebugLevel) {
+		t.Fatalf("Debug should be off by default")
+	}
+
+	if err := SetLevel("miner", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if !miner.Enabled(zapcore.DebugLevel) || pool.Enabled(zapcore.DebugLevel) {
+		t.Errorf("Debug should be on only for miner")
+	}
+
+	// logger without own level follows global one
+	if err := SetLevel(GlobalName, "error"); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Enabled(zapcore.WarnLevel) || !miner.Enabled(zapcore.DebugLevel) {
+		t.Errorf("Global level should not change overridden level")
+	}
+
+	if err := SetLevel("miner", "loud"); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("Expected %s, have %v", ErrInvalidLevel, err)
+	}
+	if err := SetLevel("nobody", "debug"); !errors.Is(err, ErrUnknownLogger) {
+		t.Errorf("Expected %s, have %v", ErrUnknownLogger, err)
+	}
+	var levels = GetLevels()
+	if levels["miner"] != "debug" || levels["pool"] != "error" || levels[GlobalName] != "error" {
+		t.Errorf("Different levels, have %v", levels)
+	}
+	if _, ok := levels["nobody"]; ok {
+		t.Errorf("Different levels, have %v", levels)
+	}
+}
@@ -2,30 +2,103 @@ package network
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/network"
+
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/safego"
 )
 
 func (h Host) ServerProtocol(stream network.Stream) {
 	rw := bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream))
+	var peer = stream.Conn().RemotePeer().String()
+	if h.bans.banned(peer, time.Now()) {
+		stream.Reset()
+		return
+	}
+	// peer starts with delimited messages, framing is negotiated by FR flag
+	var framed, acked bool
 	for {
-		data, _ := rw.ReadBytes('\r')
+		data, err := readMessage(rw.Reader, framed)
 
 		if len(data) > 0 {
 			fmt.Printf("RECEIVED (h): %d\r\n", data)
-			var p = FromBytes(data)
+			p, perr := parsePacket(data)
+			if perr != nil {
+				if h.scorePeer(peer, false) {
+					stream.Conn().Close()
+					return
+				}
+				continue
+			}
 			fmt.Println(p)
-			// if p.T == 0xa {
-			// 	var snap = storage.Sync()
-			// 	var packet = new(Packet)
-			// 	packet.T = 0x4
-			// 	packet.Data = snap
-			// 	packet.T = 0x4
-			// 	fmt.Printf("SENDED (h): STORAGE SNAP %x\r\n", snap)
-			// 	rw.Write(packet.Bytes())
-			// 	rw.Flush()
-			// }
+			if p.TS != 0 && h.Clock != nil {
+				h.Clock.Observe(peer, time.UnixMilli(p.TS))
+			}
+			var bc = chain.GetBlockChain()
+			if p.H > 0 {
+				bc.UpdatePeerHeight(p.H)
+			}
+			bc.ObserveNode(peer, stream.Conn().RemoteMultiaddr().String(), p.H)
+			if p.T == BlockPacketType {
+				_, berr := h.processReceivedBlock(p.Data)
+				if h.scorePeer(peer, berr == nil) {
+					stream.Conn().Close()
+					return
+				}
+			}
+			if p.T == TxPacketType {
+				_, terr := h.processReceivedTx(p.Data)
+				if h.scorePeer(peer, terr == nil) {
+					stream.Conn().Close()
+					return
+				}
+			}
+			if p.T == JoinPacketType {
+				reply, done, jerr := h.processJoin(peer, p.Data)
+				if writeMessage(rw, reply, framed) == nil {
+					rw.Flush()
+				}
+				done()
+				if errors.Is(jerr, ErrInvalidJoinAcc) && h.scorePeer(peer, false) {
+					stream.Conn().Close()
+					return
+				}
+			}
+			if p.T == GetBlockPacketType {
+				answer, gerr := h.processGetBlock(p.Data)
+				if gerr != nil {
+					if h.scorePeer(peer, false) {
+						stream.Conn().Close()
+						return
+					}
+				} else if writeMessage(rw, answer, framed) == nil {
+					rw.Flush()
+				}
+			}
+			if p.T == WhoIsPacketType {
+				if answer := h.processWhoIsRequest(p.Data); answer != nil {
+					if writeMessage(rw, answer, framed) == nil {
+						rw.Flush()
+					}
+				}
+			}
+			if p.FR && acked {
+				// confirmation of client, next messages are framed
+				framed = true
+			} else if p.FR && !framed {
+				var ack = &Packet{T: 0x3, FR: true, TS: time.Now().UnixMilli(), H: chainHeight()}
+				if writeMessage(rw, ack, false) == nil && rw.Flush() == nil {
+					acked = true
+				}
+			}
+		}
+
+		if err != nil {
+			return
 		}
 
 		// time.Sleep(time.Second * 2)
@@ -51,11 +124,48 @@ func (h Host) ClientProtocol(rw *bufio.ReadWriter) {
 	p.T = 0x3
 	p.Data = []byte("OP_I")
 	p.EF = 0x3
-	rw.Write(p.Bytes())
+	p.TS = time.Now().UnixMilli()
+	p.H = chainHeight()
+	p.FR = true
+	writeMessage(rw, p, false)
+	rw.Flush()
+	// blocks missed while node was offline are pulled once per connection
+	var framed, checked bool
 	for {
-		data, _ := rw.ReadBytes('\r')
+		data, err := readMessage(rw.Reader, framed)
 		if len(data) > 0 {
 			fmt.Printf("RECEIVED (c): %x\r\n", data)
+			var in = FromBytes(data)
+			if local := chainHeight(); !checked && in.H > 0 {
+				checked = true
+				if in.H > local {
+					safego.Go("block_catch_up", func() { h.catchUp(local, in.H) })
+				}
+			}
+			if in.T == TxPacketType {
+				h.processReceivedTx(in.Data)
+			}
+			if in.T == BlockResponsePacketType {
+				if err := h.processBlockResponse(in.Data); err != nil {
+					fmt.Printf("Invalid block response: %s\r\n", err)
+				}
+			}
+			if in.T == WhoIsResponsePacketType {
+				if err := h.processWhoIsResponse(in.Data); err != nil {
+					fmt.Printf("Invalid WHO_IS response: %s\r\n", err)
+				}
+			}
+			// server reads framed messages, confirm and switch to framing
+			if in.FR && !framed {
+				var confirm = &Packet{T: 0x3, FR: true, TS: time.Now().UnixMilli(), H: chainHeight()}
+				if h.framing.upgrade(rw.Writer, confirm) == nil {
+					framed = true
+				}
+			}
+		}
+		if err != nil {
+			h.bootstrap.set(BootstrapDisconnected)
+			return
 		}
 
 		// str, _ := rw.ReadString('\n')
@@ -78,3 +188,9 @@ func (h Host) ClientProtocol(rw *bufio.ReadWriter) {
 		// }
 	}
 }
+
+// height of local chain to announce to peers
+func chainHeight() int {
+	var bc = chain.GetBlockChain()
+	return bc.SyncState().Height
+}
@@ -0,0 +1,215 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/chain"
+)
+
+// packets asking block of chain at height and answering it
+const (
+	GetBlockPacketType      = 0x5
+	BlockResponsePacketType = 0x6
+)
+
+// prefix of payload of block request, height follows it
+const getBlockPrefix = "GET_BLOCK|"
+
+var (
+	ErrBlockRequest  = errors.New("invalid block request")
+	ErrBlockNotFound = errors.New("peer has no block at height")
+	ErrBlockTimeout  = errors.New("no answer to block request")
+	ErrBlockMismatch = errors.New("received block height does not match request")
+	ErrBlockRejected = errors.New("received block is rejected by chain")
+)
+
+// blockAnswer is payload of block response, block is empty when peer has no
+// block at height
+type blockAnswer struct {
+	Height int    `json:"height"`
+	Block  []byte `json:"block"`
+}
+
+// blockRequests keeps in-flight block requests waiting for answers, shared by copies of host
+type blockRequests struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	pending  map[int]chan blockAnswer
+	catching bool // missing blocks are pulled now
+}
+
+func newBlockRequests(timeout time.Duration) *blockRequests {
+	return &blockRequests{
+		timeout: timeout,
+		pending: make(map[int]chan blockAnswer),
+	}
+}
+
+// track adds request for height, reports false if request for it is in flight
+func (r *blockRequests) track(height int) (chan blockAnswer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[height]; ok {
+		return nil, false
+	}
+	var ch = make(chan blockAnswer, 1)
+	r.pending[height] = ch
+	return ch, true
+}
+
+func (r *blockRequests) forget(height int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, height)
+}
+
+// deliver passes answer to request of its height, answers nobody waits for are dropped
+func (r *blockRequests) deliver(answer blockAnswer) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch, ok := r.pending[answer.Height]
+	if !ok {
+		return false
+	}
+	delete(r.pending, answer.Height)
+	ch <- answer
+	return true
+}
+
+// startCatchUp reports false if missing blocks are pulled already
+func (r *blockRequests) startCatchUp() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.catching {
+		return false
+	}
+	r.catching = true
+	return true
+}
+
+func (r *blockRequests) endCatchUp() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.catching = false
+}
+
+func parseGetBlock(data []byte) (int, error) {
+	var s = string(data)
+	if !strings.HasPrefix(s, getBlockPrefix) {
+		return 0, ErrBlockRequest
+	}
+	height, err := strconv.Atoi(strings.TrimPrefix(s, getBlockPrefix))
+	if err != nil || height < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrBlockRequest, s)
+	}
+	return height, nil
+}
+
+// processGetBlock answers request with block of chain at height
+func (h *Host) processGetBlock(data []byte) (*Packet, error) {
+	height, err := parseGetBlock(data)
+	if err != nil {
+		return nil, err
+	}
+	var answer = blockAnswer{Height: height}
+	if blk, err := chain.GetBlockChain().GetBlockByHeight(height); err == nil {
+		answer.Block = blk.ToBytes()
+	}
+	payload, err := json.Marshal(answer)
+	if err != nil {
+		return nil, err
+	}
+	return &Packet{
+		T:    BlockResponsePacketType,
+		Data: payload,
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}, nil
+}
+
+// processBlockResponse passes answer to waiting block request
+func (h *Host) processBlockResponse(data []byte) error {
+	var answer blockAnswer
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return err
+	}
+	if !h.blocks.deliver(answer) {
+		netLog.Warnf("Unexpected block response for height %d", answer.Height)
+	}
+	return nil
+}
+
+// RequestBlock asks swarm for block at height and waits for answer. Block of
+// other height than requested one is not accepted.
+func (h *Host) RequestBlock(height int) (*block.Block, error) {
+	if h.Stream == nil {
+		return nil, ErrNoStream
+	}
+	ch, ok := h.blocks.track(height)
+	if !ok {
+		return nil, fmt.Errorf("%w: request for %d is in flight", ErrBlockRequest, height)
+	}
+	var p = &Packet{
+		T:    GetBlockPacketType,
+		Data: []byte(getBlockPrefix + strconv.Itoa(height)),
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}
+	if err := h.framing.send(h.Stream, p); err != nil {
+		h.blocks.forget(height)
+		return nil, err
+	}
+	var timer = time.NewTimer(h.blocks.timeout)
+	defer timer.Stop()
+	select {
+	case answer := <-ch:
+		if len(answer.Block) == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrBlockNotFound, height)
+		}
+		blk, err := block.FromBytes(answer.Block)
+		if err != nil || blk == nil || blk.Head == nil {
+			return nil, ErrInvalidBlock
+		}
+		if blk.Head.Height != height {
+			return nil, fmt.Errorf("%w: %d, requested %d", ErrBlockMismatch, blk.Head.Height, height)
+		}
+		return blk, nil
+	case <-timer.C:
+		h.blocks.forget(height)
+		return nil, fmt.Errorf("%w: %d", ErrBlockTimeout, height)
+	}
+}
+
+// catchUp pulls blocks missing between local chain and height of peer one by
+// one and passes them to block handler, stops on first failed request or
+// block rejected by handler. Returns count of received blocks.
+func (h *Host) catchUp(local, peer int) (int, error) {
+	if !h.blocks.startCatchUp() {
+		return 0, nil
+	}
+	defer h.blocks.endCatchUp()
+	var received int
+	for height := local + 1; height <= peer; height++ {
+		blk, err := h.RequestBlock(height)
+		if err != nil {
+			netLog.Warnf("Catch up stopped at %d: %s", height, err)
+			return received, err
+		}
+		if !h.seen.check(blk.Hash()) {
+			// block of chain is not an error, it may come from other peer meanwhile
+			if _, err := h.handleBlock(blk); err != nil && !errors.Is(err, chain.ErrDuplicateBlock) {
+				netLog.Warnf("Catch up stopped at %d: %s", height, err)
+				return received, fmt.Errorf("%w: %d: %s", ErrBlockRejected, height, err)
+			}
+		}
+		received++
+	}
+	return received, nil
+}
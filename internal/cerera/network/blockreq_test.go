@@ -0,0 +1,124 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+// peerStream answers framed block requests written to it, as peer would do
+type peerStream struct {
+	network.Stream
+	h      *Host
+	answer func(data []byte) (*Packet, error)
+}
+
+func (s *peerStream) Write(p []byte) (int, error) {
+	req, err := DecodePacket(p[4:])
+	if err != nil {
+		return 0, err
+	}
+	reply, err := s.answer(req.Data)
+	if err != nil {
+		return 0, err
+	}
+	go s.h.processBlockResponse(reply.Data)
+	return len(p), nil
+}
+
+func TestRequestBlock(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	bc := chain.InitBlockChain(cfg)
+	var genesis = bc.GetLatestBlock()
+
+	var h = &Host{
+		framing: &frameState{framed: true},
+		seen:    newSeenBlocks(4),
+		blocks:  newBlockRequests(50 * time.Millisecond),
+	}
+	var stream = &peerStream{h: h}
+	stream.answer = h.processGetBlock
+	h.Stream = stream
+
+	blk, err := h.RequestBlock(genesis.Head.Height)
+	if err != nil || blk.Hash() != genesis.Hash() {
+		t.Fatalf("Expected block %s, have %v", genesis.Hash(), err)
+	}
+	if _, err := h.RequestBlock(genesis.Head.Height + 1); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("Expected %s, have %v", ErrBlockNotFound, err)
+	}
+	if _, err := h.processGetBlock([]byte("GET_BLOCK|x")); !errors.Is(err, ErrBlockRequest) {
+		t.Errorf("Expected %s, have %v", ErrBlockRequest, err)
+	}
+
+	// peer answers request of height 2 with block of height 3
+	stream.answer = func(data []byte) (*Packet, error) {
+		var other = block.NewBlock(&block.Header{Height: 3, Number: big.NewInt(3)})
+		payload, _ := json.Marshal(blockAnswer{Height: 2, Block: other.ToBytes()})
+		return &Packet{T: BlockResponsePacketType, Data: payload}, nil
+	}
+	if _, err := h.RequestBlock(2); !errors.Is(err, ErrBlockMismatch) {
+		t.Errorf("Expected %s, have %v", ErrBlockMismatch, err)
+	}
+
+	// missing range is pulled in order until peer has no block
+	var handled = make([]int, 0)
+	h.onBlock = func(b *block.Block) (bool, error) {
+		handled = append(handled, b.Head.Height)
+		return false, nil
+	}
+	stream.answer = func(data []byte) (*Packet, error) {
+		height, _ := parseGetBlock(data)
+		var answer = blockAnswer{Height: height}
+		if height <= 3 {
+			answer.Block = block.NewBlock(&block.Header{Height: height, Number: big.NewInt(int64(height))}).ToBytes()
+		}
+		payload, _ := json.Marshal(answer)
+		return &Packet{T: BlockResponsePacketType, Data: payload}, nil
+	}
+	if n, err := h.catchUp(0, 3); err != nil || n != 3 || len(handled) != 3 || handled[2] != 3 {
+		t.Errorf("Expected blocks 1..3, have %v, %v", handled, err)
+	}
+	if n, err := h.catchUp(3, 5); !errors.Is(err, ErrBlockNotFound) || n != 0 {
+		t.Errorf("Expected %s, have %d blocks, %v", ErrBlockNotFound, n, err)
+	}
+
+	// chain rejects block 2, next blocks are not requested
+	h.seen = newSeenBlocks(4)
+	handled = handled[:0]
+	h.onBlock = func(b *block.Block) (bool, error) {
+		handled = append(handled, b.Head.Height)
+		if b.Head.Height == 2 {
+			return false, chain.ErrInvalidPoW
+		}
+		return false, nil
+	}
+	if n, err := h.catchUp(0, 3); !errors.Is(err, ErrBlockRejected) || n != 1 || len(handled) != 2 {
+		t.Errorf("Expected %s after 1 block, have %d blocks, %v, handled %v", ErrBlockRejected, n, err, handled)
+	}
+	// block which is in chain already does not stop catch up
+	h.seen = newSeenBlocks(4)
+	h.onBlock = func(b *block.Block) (bool, error) { return false, chain.ErrDuplicateBlock }
+	if n, err := h.catchUp(0, 3); err != nil || n != 3 {
+		t.Errorf("Expected 3 blocks, have %d, %v", n, err)
+	}
+
+	// silent peer
+	h.Stream = &bufStream{}
+	if _, err := h.RequestBlock(1); !errors.Is(err, ErrBlockTimeout) {
+		t.Errorf("Expected %s, have %v", ErrBlockTimeout, err)
+	}
+	if h.blocks.deliver(blockAnswer{Height: 1}) {
+		t.Errorf("Timed out request should be forgotten")
+	}
+}
@@ -0,0 +1,139 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/config"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+var (
+	ErrNoBootstrap   = errors.New("no bootstrap address")
+	ErrOwnBootstrap  = errors.New("bootstrap address is own address of node")
+	ErrBootstrapDown = errors.New("bootstrap is not connected")
+)
+
+// states of connection to swarm bootstrap
+const (
+	BootstrapConnected    = "connected"
+	BootstrapRetrying     = "retrying"
+	BootstrapDisconnected = "disconnected"
+)
+
+// bootstrapState is state of connection to swarm bootstrap shared by copies of host
+type bootstrapState struct {
+	mu     sync.RWMutex
+	state  string
+	active string // address of bootstrap which is dialed or connected
+}
+
+func (b *bootstrapState) set(state string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = state
+}
+
+func (b *bootstrapState) setActive(addr string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = addr
+}
+
+func (b *bootstrapState) getActive() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.active
+}
+
+func (b *bootstrapState) get() string {
+	if b == nil {
+		return BootstrapDisconnected
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.state == "" {
+		return BootstrapDisconnected
+	}
+	return b.state
+}
+
+// bootstrapDelay returns delay before retry of dial, it doubles from base delay
+// and stays at max delay after max retries.
+func bootstrapDelay(cfg config.BootstrapConfig, retry int) time.Duration {
+	var max = time.Duration(cfg.MaxDelay) * time.Millisecond
+	if retry >= cfg.MaxRetries {
+		return max
+	}
+	var delay = time.Duration(cfg.BaseDelay) * time.Millisecond
+	for i := 0; i < retry && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// dialBootstrap dials bootstrap addresses in order until success, failed
+// address rotates to the next one and delay is made only after all of them
+// failed. With GiveUpAfterMaxRetries dialing stops after max retries,
+// otherwise it goes on at max delay.
+func (h *Host) dialBootstrap(addrs []string, dial func(addr string) (network.Stream, error), sleep func(time.Duration)) (network.Stream, error) {
+	if len(addrs) == 0 {
+		return nil, ErrNoBootstrap
+	}
+	for retry := 0; ; retry++ {
+		var err error
+		for _, addr := range addrs {
+			h.bootstrap.setActive(addr)
+			var s network.Stream
+			if s, err = dial(addr); err == nil {
+				h.bootstrap.set(BootstrapConnected)
+				return s, nil
+			}
+			netLog.Warnf("Dial of bootstrap %s failed: %s", addr, err)
+		}
+		if h.boot.GiveUpAfterMaxRetries && retry >= h.boot.MaxRetries {
+			h.bootstrap.set(BootstrapDisconnected)
+			return nil, err
+		}
+		h.bootstrap.set(BootstrapRetrying)
+		var delay = bootstrapDelay(h.boot, retry)
+		netLog.Warnf("Dial of swarm failed, retry in %s", delay)
+		sleep(delay)
+	}
+}
+
+// HealthCheck returns error when client node is not connected to swarm bootstrap.
+func (h *Host) HealthCheck() error {
+	if h.NetType != 0x2 {
+		return nil
+	}
+	if state := h.BootstrapState(); state != BootstrapConnected {
+		return fmt.Errorf("%w: %s", ErrBootstrapDown, state)
+	}
+	return nil
+}
+
+// ActiveBootstrap returns address of bootstrap which is connected or dialed now.
+func (h *Host) ActiveBootstrap() string {
+	return h.bootstrap.getActive()
+}
+
+// BootstrapState returns state of connection to swarm bootstrap:
+// connected, retrying or disconnected.
+func (h *Host) BootstrapState() string {
+	return h.bootstrap.get()
+}
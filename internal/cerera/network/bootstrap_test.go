@@ -0,0 +1,112 @@
+package network
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cerera/internal/cerera/config"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+// fakeDialer fails given count of dials and records dialed addresses and delays between them
+type fakeDialer struct {
+	fails  int
+	dials  int
+	addrs  []string
+	delays []time.Duration
+}
+
+func (d *fakeDialer) dial(addr string) (network.Stream, error) {
+	d.dials++
+	d.addrs = append(d.addrs, addr)
+	if d.dials <= d.fails {
+		return nil, errors.New("connection refused")
+	}
+	return nil, nil
+}
+
+func (d *fakeDialer) sleep(delay time.Duration) {
+	d.delays = append(d.delays, delay)
+}
+
+func TestBootstrapBackoff(t *testing.T) {
+	var boot = config.BootstrapConfig{BaseDelay: 1000, MaxDelay: 10000, MaxRetries: 4}
+	var h = Host{boot: boot, bootstrap: &bootstrapState{}}
+	if h.BootstrapState() != BootstrapDisconnected {
+		t.Errorf("Expected %s state before dial, have %s", BootstrapDisconnected, h.BootstrapState())
+	}
+
+	// delays grow up to max one and stay there after max retries
+	var d = &fakeDialer{fails: 7}
+	if _, err := h.dialBootstrap([]string{"boot-a"}, d.dial, d.sleep); err != nil {
+		t.Errorf("Dial should succeed after retries, have %s", err)
+	}
+	var want = []time.Duration{1, 2, 4, 8, 10, 10, 10}
+	if len(d.delays) != len(want) {
+		t.Fatalf("Expected %d delays, have %v", len(want), d.delays)
+	}
+	for i := range want {
+		if d.delays[i] != want[i]*time.Second {
+			t.Errorf("Delay %d: expected %s, have %s", i, want[i]*time.Second, d.delays[i])
+		}
+	}
+	if h.BootstrapState() != BootstrapConnected {
+		t.Errorf("Expected %s state, have %s", BootstrapConnected, h.BootstrapState())
+	}
+
+	// dialing stops after first dial and max retries
+	h.boot.GiveUpAfterMaxRetries = true
+	d = &fakeDialer{fails: 100}
+	if _, err := h.dialBootstrap([]string{"boot-a"}, d.dial, d.sleep); err == nil {
+		t.Errorf("Dial should fail after max retries")
+	}
+	if d.dials != boot.MaxRetries+1 || len(d.delays) != boot.MaxRetries {
+		t.Errorf("Expected %d dials, have %d with delays %v", boot.MaxRetries+1, d.dials, d.delays)
+	}
+	if h.BootstrapState() != BootstrapDisconnected {
+		t.Errorf("Expected %s state, have %s", BootstrapDisconnected, h.BootstrapState())
+	}
+}
+
+func TestBootstrapFailover(t *testing.T) {
+	var boot = config.BootstrapConfig{BaseDelay: 1000, MaxDelay: 10000, MaxRetries: 4}
+	var h = Host{boot: boot, bootstrap: &bootstrapState{}}
+	var addrs = []string{"boot-a", "boot-b", "boot-c"}
+
+	// failed bootstrap rotates to next one, delay only after whole round
+	var d = &fakeDialer{fails: 4}
+	if _, err := h.dialBootstrap(addrs, d.dial, d.sleep); err != nil {
+		t.Errorf("Dial should succeed on second round, have %s", err)
+	}
+	var want = []string{"boot-a", "boot-b", "boot-c", "boot-a", "boot-b"}
+	if strings.Join(d.addrs, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected dials %v, have %v", want, d.addrs)
+	}
+	if len(d.delays) != 1 || d.delays[0] != time.Second {
+		t.Errorf("Expected single delay after first round, have %v", d.delays)
+	}
+	if h.ActiveBootstrap() != "boot-b" || h.BootstrapState() != BootstrapConnected {
+		t.Errorf("Expected connected to boot-b, have %s %s", h.BootstrapState(), h.ActiveBootstrap())
+	}
+
+	if _, err := h.dialBootstrap(nil, d.dial, d.sleep); err != ErrNoBootstrap {
+		t.Errorf("Expected %s, have %v", ErrNoBootstrap, err)
+	}
+}
+
+func TestBootstrapHealth(t *testing.T) {
+	var h = Host{bootstrap: &bootstrapState{}, NetType: 0x1}
+	if err := h.HealthCheck(); err != nil {
+		t.Errorf("Server node does not need bootstrap, have %s", err)
+	}
+	h.NetType = 0x2
+	if err := h.HealthCheck(); !errors.Is(err, ErrBootstrapDown) {
+		t.Errorf("Expected %s, have %v", ErrBootstrapDown, err)
+	}
+	h.bootstrap.set(BootstrapConnected)
+	if err := h.HealthCheck(); err != nil {
+		t.Errorf("Connected client should be healthy, have %s", err)
+	}
+}
@@ -0,0 +1,136 @@
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+// type of packet which carries new block
+const BlockPacketType = 0xb
+
+// attempts to send block before it goes to dead letter queue
+const BroadcastRetries = 3
+
+// delay before second attempt, doubled on each next one
+const BroadcastBackoff = 200 * time.Millisecond
+
+// max count of blocks waiting for re-broadcast, oldest are dropped
+const DeadLetterSize = 64
+
+// period of re-broadcast attempts of failed blocks
+const DeadLetterInterval = 5 * time.Second
+
+var ErrNoStream = errors.New("no stream to broadcast")
+
+// broadcaster sends blocks to swarm with bounded retries,
+// blocks which could not be sent wait in dead letter queue until connectivity returns
+type broadcaster struct {
+	mu      sync.Mutex
+	send    func(*block.Block) error
+	backoff time.Duration
+	dead    []*block.Block
+}
+
+func newBroadcaster(send func(*block.Block) error) *broadcaster {
+	return &broadcaster{send: send, backoff: BroadcastBackoff}
+}
+
+func (b *broadcaster) trySend(blk *block.Block) error {
+	var err error
+	var delay = b.backoff
+	for i := 0; i < BroadcastRetries; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = b.send(blk); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// broadcast sends block, on final failure block is queued for re-broadcast.
+func (b *broadcaster) broadcast(blk *block.Block) error {
+	if err := b.trySend(blk); err != nil {
+		netLog.Warnf("Block %s is not broadcasted: %s, queued", blk.Hash(), err)
+		b.queue(blk)
+		return err
+	}
+	return nil
+}
+
+func (b *broadcaster) queue(blk *block.Block) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.dead) == DeadLetterSize {
+		b.dead = b.dead[1:]
+	}
+	b.dead = append(b.dead, blk)
+}
+
+// drain re-broadcasts queued blocks in order, stops on first failure.
+// Returns count of sent blocks.
+func (b *broadcaster) drain() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var sent int
+	for len(b.dead) > 0 {
+		if err := b.send(b.dead[0]); err != nil {
+			break
+		}
+		b.dead = b.dead[1:]
+		sent++
+	}
+	return sent
+}
+
+func (b *broadcaster) pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.dead)
+}
+
+// sendBlock writes block packet to swarm stream
+func (h *Host) sendBlock(blk *block.Block) error {
+	if h.Stream == nil {
+		return ErrNoStream
+	}
+	var p = &Packet{
+		T:    BlockPacketType,
+		Data: blk.ToBytes(),
+		TS:   time.Now().UnixMilli(),
+		H:    blk.Head.Height,
+	}
+	return h.framing.send(h.Stream, p)
+}
+
+// BroadcastBlocks sends generated blocks to swarm until channel is closed.
+// Blocks which failed to send are re-broadcasted when stream is available again.
+func (h *Host) BroadcastBlocks(blocks <-chan *block.Block) {
+	var b = newBroadcaster(h.sendBlock)
+	var ticker = time.NewTicker(DeadLetterInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case blk, ok := <-blocks:
+			if !ok {
+				return
+			}
+			// keep order of blocks, queued ones go first
+			b.drain()
+			if b.pending() > 0 {
+				b.queue(blk)
+				continue
+			}
+			b.broadcast(blk)
+		case <-ticker.C:
+			if n := b.drain(); n > 0 {
+				netLog.Infof("Re-broadcasted %d blocks", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package network
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/coinbase"
+)
+
+// iceLink simulates connection to swarm which goes down and comes back
+type iceLink struct {
+	mu   sync.Mutex
+	up   bool
+	sent []*block.Block
+	runs int
+}
+
+func (l *iceLink) send(b *block.Block) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runs++
+	if !l.up {
+		return errors.New("link is down")
+	}
+	l.sent = append(l.sent, b)
+	return nil
+}
+
+func TestBroadcastDeadLetter(t *testing.T) {
+	var link = &iceLink{}
+	var b = newBroadcaster(link.send)
+	b.backoff = 0
+
+	var blk = block.NewBlock(&block.Header{Height: 1, Number: big.NewInt(1)})
+	if err := b.broadcast(blk); err == nil {
+		t.Fatalf("Broadcast should fail while link is down")
+	}
+	if link.runs != BroadcastRetries {
+		t.Errorf("Expected %d attempts, have %d", BroadcastRetries, link.runs)
+	}
+	if b.pending() != 1 {
+		t.Fatalf("Failed block should be queued, have %d", b.pending())
+	}
+	if b.drain() != 0 || b.pending() != 1 {
+		t.Errorf("Block should stay queued while link is down")
+	}
+
+	link.up = true
+	if n := b.drain(); n != 1 || b.pending() != 0 {
+		t.Errorf("Queued block should be re-broadcasted, sent %d, pending %d", n, b.pending())
+	}
+	if len(link.sent) != 1 || link.sent[0] != blk {
+		t.Errorf("Expected block %s to be sent", blk.Hash())
+	}
+}
+
+func TestBroadcastDeadLetterBound(t *testing.T) {
+	var link = &iceLink{}
+	var b = newBroadcaster(link.send)
+	b.backoff = 0
+	var last *block.Block
+	for i := 0; i < DeadLetterSize+5; i++ {
+		last = block.NewBlock(&block.Header{Height: i, Number: big.NewInt(int64(i))})
+		b.broadcast(last)
+	}
+	if b.pending() != DeadLetterSize {
+		t.Errorf("Expected %d queued blocks, have %d", DeadLetterSize, b.pending())
+	}
+	link.up = true
+	b.drain()
+	if link.sent[len(link.sent)-1] != last || link.sent[0].Head.Height != 5 {
+		t.Errorf("Oldest blocks should be dropped from queue")
+	}
+}
+
+func TestReceivedBlockDedup(t *testing.T) {
+	var handled int
+	var h = Host{
+		seen: newSeenBlocks(2),
+		onBlock: func(*block.Block) (bool, error) {
+			handled++
+			return false, nil
+		},
+	}
+	var blocks = make([][]byte, 0)
+	for i := 1; i <= 3; i++ {
+		blocks = append(blocks, block.NewBlock(&block.Header{Height: i, Number: big.NewInt(int64(i))}).ToBytes())
+	}
+
+	// same block from bootstrap and from peer
+	if ok, err := h.processReceivedBlock(blocks[0]); !ok || err != nil {
+		t.Errorf("New block should be processed, have %v", err)
+	}
+	if ok, err := h.processReceivedBlock(blocks[0]); ok || err != nil {
+		t.Errorf("Seen block should be dropped without error, have %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("Block should be handled once, have %d", handled)
+	}
+	if ok, err := h.processReceivedBlock([]byte("not a block")); ok || err != ErrInvalidBlock {
+		t.Errorf("Broken block should be dropped with %v, have %v", ErrInvalidBlock, err)
+	}
+
+	// oldest hash leaves bounded set
+	h.processReceivedBlock(blocks[1])
+	h.processReceivedBlock(blocks[2])
+	if ok, _ := h.processReceivedBlock(blocks[0]); !ok || handled != 4 {
+		t.Errorf("Forgotten block should be processed again, handled %d", handled)
+	}
+}
+
+// sealedBlockOn returns block of node on top of parent, which chain accepts
+func sealedBlockOn(parent *block.Block, node types.Address) *block.Block {
+	var head = *parent.Head
+	head.Height = parent.Head.Height + 1
+	head.Index = parent.Head.Index + 1
+	head.Number = big.NewInt(int64(head.Height))
+	head.Timestamp = parent.Head.Timestamp + 1
+	head.PrevHash = parent.Hash()
+	head.Node = node
+	var b = block.NewBlockWithHeader(&head)
+	b.Transactions = append(b.Transactions, *coinbase.CreateCoinBaseTransation(head.Height, head.Timestamp, node))
+	b.Head.Root = block.ComputeTxRoot(b.Transactions)
+	b.Head.Size = int(unsafe.Sizeof(b))
+	chain.Seal(b, nil, 1)
+	return b
+}
+
+// prepareChain starts in memory chain of node with vault for rewards of blocks
+func prepareChain() chain.Chain {
+	nodeKey, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(nodeKey.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(nodeKey)
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	cfg.Chain.GenesisDifficulty = big.NewInt(16)
+	cfg.Vault.MEM = true
+	storage.NewD5Vault(cfg)
+	return chain.InitBlockChain(cfg)
+}
+
+func TestReceivedBlockToChain(t *testing.T) {
+	var bc = prepareChain()
+	var genesis = bc.GetLatestBlock()
+	// host of node without handlers set by test
+	var h = &Host{seen: newSeenBlocks(4)}
+	// tip of other node makes generator rebuild its block
+	heads, cancel := bc.SubscribeHead()
+	defer cancel()
+
+	var next = sealedBlockOn(genesis, types.Address{0xb})
+	if ok, err := h.processReceivedBlock(next.ToBytes()); !ok || err != nil {
+		t.Fatalf("Block from swarm should be processed, have %v", err)
+	}
+	if tip := bc.GetLatestBlock(); tip.Hash() != next.Hash() {
+		t.Errorf("Block from swarm should extend chain to %s, have %s", next.Hash(), tip.Hash())
+	}
+	select {
+	case head := <-heads:
+		if head.Hash() != next.Hash() || head.Head.Node != next.Head.Node {
+			t.Errorf("Expected head %s of other node, have %s", next.Hash(), head.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Block from swarm should be sent to head subscribers")
+	}
+
+	// hash of block with other nonce is above target
+	var unsealed = sealedBlockOn(next, types.Address{0xb})
+	for unsealed.Nonce++; block.VerifyBlockHash(unsealed); unsealed.Nonce++ {
+	}
+	if _, err := h.processReceivedBlock(unsealed.ToBytes()); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Unsealed block should be rejected with %s, have %v", ErrInvalidBlock, err)
+	}
+	// node may be behind, block of unknown parent is not penalized
+	var orphan = sealedBlockOn(sealedBlockOn(next, types.Address{0xb}), types.Address{0xb})
+	if ok, err := h.processReceivedBlock(orphan.ToBytes()); !ok || err != nil {
+		t.Errorf("Block of unknown parent should be passed without error, have %v", err)
+	}
+	if tip := bc.GetLatestBlock(); tip.Hash() != next.Hash() {
+		t.Errorf("Chain should stay at %s, have %s", next.Hash(), tip.Hash())
+	}
+}
@@ -3,65 +3,81 @@ package network
 import (
 	"bufio"
 	"context"
-	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/peerstore"
 	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/cerera/internal/cerera/safego"
 )
 
 type Client struct {
 }
 
 func InitClient(h *Host, localAddr string) network.Stream {
-	var swarmCfg = "swarm.ddd"
-	f, err := os.Open(swarmCfg)
+	var addrs = h.boot.BootstrapAddrs
+	if len(addrs) == 0 {
+		addrs = readSwarmFile("swarm.ddd", localAddr)
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	netLog.Infof("Swarm is:%s", strings.Join(addrs, ", "))
+	netLog.Infof("Joining")
+
+	s, err := h.dialBootstrap(addrs, h.dialPeer, time.Sleep)
+	if err != nil {
+		netLog.Warnf("Swarm is unreachable: %s", err)
+		return nil
+	}
+	h.Status = 0x2
+	rw := bufio.NewReadWriter(bufio.NewReader(s), bufio.NewWriter(s))
+	safego.Go("client_protocol", func() { h.ClientProtocol(rw) })
+	h.Stream = s
+	return h.Stream
+}
+
+// readSwarmFile returns network addresses of swarm nodes from lines
+// "address:multiaddr" of swarm file, entry of current node is skipped.
+func readSwarmFile(path string, localAddr string) []string {
+	f, err := os.Open(path)
 	if err != nil {
 		panic(err)
 	}
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
-	var swarmArr []string
+	var addrs []string
 	for scanner.Scan() {
-		swarmArr = append(swarmArr, scanner.Text())
+		var parts = strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || parts[0] == localAddr {
+			continue
+		}
+		addrs = append(addrs, parts[1])
 	}
 	if err := scanner.Err(); err != nil {
 		panic(err)
 	}
-	fmt.Printf("Swarm is:%s\r\n", swarmArr[0])
-	fmt.Printf("Joining\r\n")
+	return addrs
+}
 
-	// check swarm address with local
-	var addrs = strings.Split(swarmArr[0], ":")
-	vAddress := addrs[0]
-	nAddress := addrs[1]
-	if vAddress != localAddr {
-		maddr, err := ma.NewMultiaddr(nAddress)
-		if err != nil {
-			panic(err)
-		}
-		remoteHost, err := peer.AddrInfoFromP2pAddr(maddr)
-		if err != nil {
-			panic(err)
-		}
-		if h.NetHost.Addrs()[0] == maddr {
-			return nil
-		}
-		h.NetHost.Peerstore().AddAddrs(remoteHost.ID, remoteHost.Addrs, peerstore.PermanentAddrTTL)
-		s, err := h.NetHost.NewStream(context.Background(), remoteHost.ID, DiscoveryServiceTag)
-		if err != nil {
-			panic(err)
-		}
-		h.Status = 0x2
-		rw := bufio.NewReadWriter(bufio.NewReader(s), bufio.NewWriter(s))
-		go h.ClientProtocol(rw)
-		h.Stream = s
-		return h.Stream
-	} else {
-		return nil
+// dialPeer opens swarm stream to node with multiaddr
+func (h *Host) dialPeer(addr string) (network.Stream, error) {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	remoteHost, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return nil, err
+	}
+	if remoteHost.ID == h.NetHost.ID() {
+		return nil, ErrOwnBootstrap
 	}
+	h.NetHost.Peerstore().AddAddrs(remoteHost.ID, remoteHost.Addrs, peerstore.PermanentAddrTTL)
+	return h.NetHost.NewStream(context.Background(), remoteHost.ID, DiscoveryServiceTag)
 }
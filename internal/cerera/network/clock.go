@@ -0,0 +1,80 @@
+package network
+
+import (
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// default max offset of local clock with median clock of peers
+const DefaultMaxClockSkew = 30 * time.Second
+
+var peerTimeOffset = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "peer_time_offset_seconds",
+		Help: "Median offset of peers clocks with local clock",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(peerTimeOffset)
+}
+
+// ClockSkew tracks offsets of peers clocks to detect drift of local clock.
+type ClockSkew struct {
+	mu        sync.Mutex
+	offsets   map[string]time.Duration
+	threshold time.Duration
+	logger    *log.Logger
+}
+
+func NewClockSkew(threshold time.Duration) *ClockSkew {
+	if threshold <= 0 {
+		threshold = DefaultMaxClockSkew
+	}
+	return &ClockSkew{
+		offsets:   make(map[string]time.Duration),
+		threshold: threshold,
+		logger:    log.New(os.Stdout, "", log.LstdFlags),
+	}
+}
+
+// Observe stores offset of peer clock with local one and returns median
+// offset of all peers. Warns when median offset exceeds threshold.
+func (c *ClockSkew) Observe(peer string, peerTime time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offsets[peer] = time.Until(peerTime)
+
+	var median = c.median()
+	peerTimeOffset.Set(median.Seconds())
+	if median > c.threshold || median < -c.threshold {
+		c.logger.Printf("WARNING! Local clock differs from peers by %s, check time sync of node\r\n", median)
+	}
+	return median
+}
+
+// Median returns median offset of peers clocks with local clock.
+func (c *ClockSkew) Median() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.median()
+}
+
+func (c *ClockSkew) median() time.Duration {
+	if len(c.offsets) == 0 {
+		return 0
+	}
+	var offsets = make([]time.Duration, 0, len(c.offsets))
+	for _, o := range c.offsets {
+		offsets = append(offsets, o)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+	return offsets[len(offsets)/2]
+}
@@ -0,0 +1,37 @@
+package network
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClockSkew(t *testing.T) {
+	var buf bytes.Buffer
+	clock := NewClockSkew(10 * time.Second)
+	clock.logger = log.New(&buf, "", 0)
+
+	var now = time.Now()
+	clock.Observe("peer1", now.Add(time.Second))
+	clock.Observe("peer2", now.Add(2*time.Second))
+	if buf.Len() != 0 {
+		t.Errorf("Small offset should not be warned:\r\n%s", buf.String())
+	}
+
+	clock.Observe("peer3", now.Add(time.Minute))
+	clock.Observe("peer4", now.Add(time.Minute))
+	median := clock.Observe("peer5", now.Add(time.Minute))
+	if median < 59*time.Second || median > time.Minute {
+		t.Errorf("Different median offset! Have %s, want about %s", median, time.Minute)
+	}
+	if gauge := testutil.ToFloat64(peerTimeOffset); gauge < 59 || gauge > 60 {
+		t.Errorf("Different offset gauge! Have %f, want about %d", gauge, 60)
+	}
+	if !strings.Contains(buf.String(), "WARNING") {
+		t.Errorf("Offset over threshold should be warned")
+	}
+}
@@ -0,0 +1,80 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/cerera/internal/cerera/common"
+	"golang.org/x/crypto/blake2b"
+)
+
+// version of binary encoding of packets, first byte of encoding.
+// Legacy json packets start with '{', so both can be told apart.
+const PacketCodecVersion byte = 0x1
+
+// flags of binary packet
+const packetFlagFR byte = 0x1
+
+var (
+	ErrPacketVersion  = errors.New("unknown packet encoding version")
+	ErrPacketEncoding = errors.New("malformed packet encoding")
+)
+
+// EncodePacket encodes packet in stable binary form: version, type, EF,
+// flags, varint time and height and length prefixed data. Equal packets
+// always have equal encodings.
+func EncodePacket(p *Packet) []byte {
+	var buf = make([]byte, 0, 4+2*binary.MaxVarintLen64+binary.MaxVarintLen32+len(p.Data))
+	var flags byte
+	if p.FR {
+		flags |= packetFlagFR
+	}
+	buf = append(buf, PacketCodecVersion, p.T, p.EF, flags)
+	buf = binary.AppendVarint(buf, p.TS)
+	buf = binary.AppendVarint(buf, int64(p.H))
+	buf = binary.AppendUvarint(buf, uint64(len(p.Data)))
+	return append(buf, p.Data...)
+}
+
+// DecodePacket decodes binary packet, legacy json packets of peers which
+// do not support binary encoding yet are decoded too.
+func DecodePacket(data []byte) (Packet, error) {
+	var p Packet
+	if len(data) > 0 && data[0] == '{' {
+		err := json.Unmarshal(data, &p)
+		return p, err
+	}
+	if len(data) < 4 {
+		return p, ErrPacketEncoding
+	}
+	if data[0] != PacketCodecVersion {
+		return p, ErrPacketVersion
+	}
+	p.T, p.EF, p.FR = data[1], data[2], data[3]&packetFlagFR != 0
+	var rest = data[4:]
+	ts, n := binary.Varint(rest)
+	if n <= 0 {
+		return p, ErrPacketEncoding
+	}
+	p.TS, rest = ts, rest[n:]
+	h, n := binary.Varint(rest)
+	if n <= 0 {
+		return p, ErrPacketEncoding
+	}
+	p.H, rest = int(h), rest[n:]
+	size, n := binary.Uvarint(rest)
+	if n <= 0 || size != uint64(len(rest)-n) {
+		return p, ErrPacketEncoding
+	}
+	if size > 0 {
+		p.Data = append([]byte(nil), rest[n:]...)
+	}
+	return p, nil
+}
+
+// PacketDigest returns hash of binary encoding of packet, it does not
+// depend on field order or other details of json encoding.
+func PacketDigest(p *Packet) common.Hash {
+	return common.Hash(blake2b.Sum256(EncodePacket(p)))
+}
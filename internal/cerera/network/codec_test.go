@@ -0,0 +1,50 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPacketCodec(t *testing.T) {
+	var p = Packet{T: 0x3, Data: []byte("block\rdata"), EF: 0x1, TS: 1700000000000, H: -1, FR: true}
+	var enc = EncodePacket(&p)
+	if enc[0] != PacketCodecVersion {
+		t.Errorf("expected version %d, got %d", PacketCodecVersion, enc[0])
+	}
+	dec, err := DecodePacket(enc)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if dec.T != p.T || dec.EF != p.EF || dec.TS != p.TS || dec.H != p.H || dec.FR != p.FR || !bytes.Equal(dec.Data, p.Data) {
+		t.Errorf("expected %+v, got %+v", p, dec)
+	}
+	var same = p
+	same.Data = append([]byte(nil), p.Data...)
+	if PacketDigest(&same) != PacketDigest(&p) {
+		t.Errorf("digest of equal packets differs")
+	}
+	same.H = 2
+	if PacketDigest(&same) == PacketDigest(&p) {
+		t.Errorf("digest of different packets is equal")
+	}
+
+	// legacy json packets are still accepted
+	legacy, _ := json.Marshal(p)
+	dec, err = DecodePacket(legacy)
+	if err != nil || dec.H != p.H || !bytes.Equal(dec.Data, p.Data) {
+		t.Errorf("legacy decode: %+v, %v", dec, err)
+	}
+
+	var bad = append([]byte(nil), enc...)
+	bad[0] = 0x7f
+	if _, err := DecodePacket(bad); err != ErrPacketVersion {
+		t.Errorf("expected %s, got %v", ErrPacketVersion, err)
+	}
+	if _, err := DecodePacket(enc[:len(enc)-1]); err != ErrPacketEncoding {
+		t.Errorf("expected %s, got %v", ErrPacketEncoding, err)
+	}
+	if _, err := DecodePacket(nil); err != ErrPacketEncoding {
+		t.Errorf("expected %s, got %v", ErrPacketEncoding, err)
+	}
+}
@@ -0,0 +1,114 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// max size of one framed message, blocks with many txs fit in it
+const MaxFrameSize = 8 << 20
+
+// delimiter of messages of peers without framing
+const legacyDelimiter = '\r'
+
+var ErrFrameTooLarge = errors.New("framed message is too large")
+
+// frameState keeps whether peer of swarm stream reads length prefixed
+// messages, shared by copies of host. Peers switch to framing after handshake:
+// client announces framing in hello, server answers with the same flag and
+// client confirms with last delimited packet, all next packets are framed.
+type frameState struct {
+	mu     sync.Mutex
+	framed bool
+}
+
+// send writes packet in current mode of stream
+func (f *frameState) send(w io.Writer, p *Packet) error {
+	if f == nil {
+		return writeMessage(w, p, false)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return writeMessage(w, p, f.framed)
+}
+
+// upgrade writes confirmation of framing, packets sent after it are framed
+func (f *frameState) upgrade(w *bufio.Writer, confirm *Packet) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.framed {
+		return nil
+	}
+	if err := writeMessage(w, confirm, false); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	f.framed = true
+	return nil
+}
+
+// writeFrame writes message prefixed with 4 byte big-endian length
+func writeFrame(w io.Writer, msg []byte) error {
+	if len(msg) > MaxFrameSize {
+		return fmt.Errorf("%w: %d", ErrFrameTooLarge, len(msg))
+	}
+	var buf = make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(buf, uint32(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// readFramedMessage reads length of message and then exactly that count of bytes,
+// message split between several reads of stream is joined.
+func readFramedMessage(r io.Reader) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	var size = binary.BigEndian.Uint32(prefix[:])
+	if size > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d", ErrFrameTooLarge, size)
+	}
+	var msg = make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readMessage reads next message of peer, framed or delimited by legacy delimiter
+func readMessage(r *bufio.Reader, framed bool) ([]byte, error) {
+	if framed {
+		return readFramedMessage(r)
+	}
+	data, err := r.ReadBytes(legacyDelimiter)
+	if len(data) > 0 && data[len(data)-1] == legacyDelimiter {
+		data = data[:len(data)-1]
+	}
+	return data, err
+}
+
+// writeMessage writes packet to peer, framed and binary encoded if peer
+// supports it. Binary encoding may contain delimiter, so delimited
+// messages stay json.
+func writeMessage(w io.Writer, p *Packet, framed bool) error {
+	if framed {
+		return writeFrame(w, EncodePacket(p))
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, legacyDelimiter))
+	return err
+}
@@ -0,0 +1,80 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFramedMessage(t *testing.T) {
+	var buf bytes.Buffer
+	var msgs = [][]byte{[]byte("first\rmessage\n"), {}, bytes.Repeat([]byte{0x1}, 4096)}
+	for _, msg := range msgs {
+		if err := writeFrame(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every read returns one byte, message is joined from many reads
+	var r = iotest.OneByteReader(&buf)
+	for _, want := range msgs {
+		msg, err := readFramedMessage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg, want) {
+			t.Errorf("Different message, have %d bytes, want %d", len(msg), len(want))
+		}
+	}
+	if _, err := readFramedMessage(r); err == nil {
+		t.Errorf("Read of empty stream should fail")
+	}
+
+	var prefix = make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, MaxFrameSize+1)
+	if _, err := readFramedMessage(bytes.NewReader(prefix)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expected %s, have %v", ErrFrameTooLarge, err)
+	}
+	// message is cut
+	binary.BigEndian.PutUint32(prefix, 10)
+	if _, err := readFramedMessage(bytes.NewReader(append(prefix, 0x1, 0x2))); err == nil {
+		t.Errorf("Read of cut message should fail")
+	}
+}
+
+func TestFramingUpgrade(t *testing.T) {
+	var buf bytes.Buffer
+	var f = &frameState{}
+	var w = bufio.NewWriter(&buf)
+	if err := f.send(&buf, &Packet{T: 0x1, H: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.upgrade(w, &Packet{T: 0x3, FR: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.send(&buf, &Packet{T: BlockPacketType, H: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reader switches to framing after confirmation
+	var r = bufio.NewReader(&buf)
+	var framed bool
+	var heights []int
+	for i := 0; i < 3; i++ {
+		data, err := readMessage(r, framed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p = FromBytes(data)
+		if p.FR {
+			framed = true
+			continue
+		}
+		heights = append(heights, p.H)
+	}
+	if len(heights) != 2 || heights[0] != 1 || heights[1] != 2 {
+		t.Errorf("Expected packets of heights 1 and 2, have %v", heights)
+	}
+}
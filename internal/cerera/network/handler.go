@@ -2,13 +2,17 @@ package network
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/btcsuite/websocket"
+	"github.com/cerera/internal/cerera/logger"
 	"github.com/cerera/internal/pallada/pallada"
 )
 
@@ -50,6 +54,7 @@ func HandleRequest(ctx context.Context) http.HandlerFunc { //, poa *dddddpoa.DDD
 
 		var response = Response{
 			Result: pallada.GetData(),
+			Stale:  pallada.IsStale(request.Method),
 		}
 
 		response.JSONRPC = "2.0"
@@ -82,6 +87,199 @@ func HandleRequest(ctx context.Context) http.HandlerFunc { //, poa *dddddpoa.DDD
 	}
 }
 
+// HandleFaucetStatus serves GET /faucet/status/{addr}
+func HandleFaucetStatus(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var addr = strings.TrimPrefix(r.URL.Path, "/faucet/status/")
+		if addr == "" || strings.Contains(addr, "/") {
+			http.Error(w, "Address required", http.StatusBadRequest)
+			return
+		}
+
+		responseData, err := json.Marshal(pallada.Execute("faucet_status", []interface{}{addr}))
+		if err != nil {
+			http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err = w.Write(responseData); err != nil {
+			log.Println("Failed to write response:", err)
+		}
+	}
+}
+
+// requireAdmin passes request to h only when it carries "Authorization:
+// Bearer <token>". Empty token means admin endpoints are not configured and
+// every request is refused.
+func requireAdmin(token string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "Admin endpoints are disabled", http.StatusForbidden)
+			return
+		}
+		var given, ok = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// HandleLogLevels serves levels of named loggers: GET returns them, POST
+// with json {"name", "level"} changes level of one logger while node runs.
+func HandleLogLevels(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req struct {
+				Name  string `json:"name"`
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+				http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+				return
+			}
+			if err := logger.SetLevel(req.Name, req.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		responseData, err := json.Marshal(logger.GetLevels())
+		if err != nil {
+			http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(responseData); err != nil {
+			log.Println("Failed to write response:", err)
+		}
+	}
+}
+
+// count of txs in page of history when limit is not given
+const DefaultHistoryLimit = 20
+
+// HandleHistory serves GET /history/<address> with incoming txs of account
+// newest first. Query parameter limit bounds page, cursor is next of
+// previous page.
+func HandleHistory(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var addr = strings.TrimPrefix(r.URL.Path, "/history/")
+		if addr == "" || strings.Contains(addr, "/") {
+			http.Error(w, "Address required", http.StatusBadRequest)
+			return
+		}
+		var limit = DefaultHistoryLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			l, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = l
+		}
+		var params = []interface{}{addr, r.URL.Query().Get("cursor"), float64(limit)}
+		var res = pallada.Execute("history", params)
+		if code, ok := res.(int); ok && code == 0xf {
+			http.Error(w, fmt.Sprint(pallada.GetData()), http.StatusBadRequest)
+			return
+		}
+
+		responseData, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+			return
+		}
+
+		if pallada.IsStale("history") {
+			w.Header().Set("X-Stale", "true")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err = w.Write(responseData); err != nil {
+			log.Println("Failed to write response:", err)
+		}
+	}
+}
+
+// HandleBalances serves POST /balances with json array of addresses
+// and returns map of address to exact balance.
+func HandleBalances(ctx context.Context, maxAddrs int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var addrs []string
+		if err := json.NewDecoder(r.Body).Decode(&addrs); err != nil {
+			http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+			return
+		}
+		if len(addrs) > maxAddrs {
+			http.Error(w, fmt.Sprintf("Too many addresses, max %d", maxAddrs), http.StatusBadRequest)
+			return
+		}
+		var params = make([]interface{}, 0, len(addrs))
+		for _, addr := range addrs {
+			params = append(params, addr)
+		}
+
+		responseData, err := json.Marshal(pallada.Execute("balances", params))
+		if err != nil {
+			http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+			return
+		}
+
+		if pallada.IsStale("balances") {
+			w.Header().Set("X-Stale", "true")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err = w.Write(responseData); err != nil {
+			log.Println("Failed to write response:", err)
+		}
+	}
+}
+
+// HandleStats serves GET /stats with chain info and sync state
+func HandleStats(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		responseData, err := json.Marshal(pallada.Execute("stats", nil))
+		if err != nil {
+			http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err = w.Write(responseData); err != nil {
+			log.Println("Failed to write response:", err)
+		}
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
@@ -140,6 +338,7 @@ type Response struct {
 	Result  interface{} `json:"result"`
 	ID      int         `json:"id"`
 	Error   *Error      `json:"error,omitempty"`
+	Stale   bool        `json:"stale,omitempty"` // node is catching up, result may be outdated
 }
 type Error struct {
 	Code    int    `json:"code"`
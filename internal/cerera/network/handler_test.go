@@ -0,0 +1,185 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/logger"
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+)
+
+func prepareVault() types.Address {
+	pk, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(pk.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(pk)
+	cfg.Vault.MEM = true
+	storage.NewD5Vault(cfg)
+	return cfg.NetCfg.ADDR
+}
+
+func TestHandleBalances(t *testing.T) {
+	var known = prepareVault()
+	var unknown = types.HexToAddress("0x9642903868c35526a8b34686a5d4184125562e7300a4556790379884e81ee30507352e49266351711164874923761a17")
+	var handler = HandleBalances(context.Background(), 2)
+
+	body, _ := json.Marshal([]string{known.Hex(), unknown.Hex()})
+	var rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/balances", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Different status, have %d, want %d", rec.Code, http.StatusOK)
+	}
+	var res map[types.Address]*big.Int
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Error while parse response: %s", err)
+	}
+	if b, ok := res[known]; !ok || b == nil || b.Cmp(types.FloatToBigInt(100.0)) != 0 {
+		t.Errorf("Different balance of known address, have %v", b)
+	}
+	if b, ok := res[unknown]; !ok || b != nil {
+		t.Errorf("Unknown address should have null balance, have %v (%t)", b, ok)
+	}
+
+	body, _ = json.Marshal([]string{known.Hex(), unknown.Hex(), known.Hex()})
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/balances", strings.NewReader(string(body))))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Request over cap should fail, have status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/balances", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET should not be allowed, have status %d", rec.Code)
+	}
+}
+
+// chainOf locates txs in blocks by height of tx
+type chainOf map[common.Hash]*types.GTransaction
+
+func (c chainOf) LocateTransaction(hash common.Hash) (*types.GTransaction, types.Address, *block.Block, bool) {
+	tx, ok := c[hash]
+	if !ok {
+		return nil, types.Address{}, nil, false
+	}
+	return tx, types.Address{0x1}, block.NewBlockWithHeader(&block.Header{Height: int(tx.Nonce())}), true
+}
+
+func TestHandleHistory(t *testing.T) {
+	var known = prepareVault()
+	var to = types.Address{0x2}
+	var txs = chainOf{}
+	for i := 1; i <= 3; i++ {
+		var tx = types.NewTransaction(uint64(i), to, big.NewInt(int64(i)), 10, big.NewInt(100), nil)
+		if err := storage.GetVault().Transfer(known, to, big.NewInt(int64(i)), tx.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		txs[tx.Hash()] = tx
+	}
+	storage.GetVault().SetTxLocator(txs)
+	var handler = HandleHistory(context.Background())
+
+	type page struct {
+		Records []struct {
+			Hash   common.Hash `json:"hash"`
+			Amount string      `json:"amount"`
+			Height int         `json:"height"`
+		} `json:"records"`
+		Next *common.Hash `json:"next"`
+	}
+	var get = func(url string) page {
+		var rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Different status, have %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var res page
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Error while parse response: %s", err)
+		}
+		return res
+	}
+	var first = get("/history/" + to.Hex() + "?limit=2")
+	if len(first.Records) != 2 || first.Records[0].Height != 3 || first.Records[0].Amount != "3" || first.Next == nil {
+		t.Fatalf("Expected 2 newest records and cursor, have %+v", first)
+	}
+	var last = get("/history/" + to.Hex() + "?limit=2&cursor=" + first.Next.Hex())
+	if len(last.Records) != 1 || last.Records[0].Height != 1 || last.Next != nil {
+		t.Errorf("Expected oldest record without cursor, have %+v", last)
+	}
+
+	for _, url := range []string{"/history/", "/history/" + to.Hex() + "?limit=x", "/history/" + to.Hex() + "?limit=0"} {
+		var rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, have %d", url, http.StatusBadRequest, rec.Code)
+		}
+	}
+	var rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/history/"+to.Hex(), nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST should not be allowed, have status %d", rec.Code)
+	}
+}
+
+func TestHandleLogLevels(t *testing.T) {
+	defer logger.Init("info")
+	logger.Named("gossip")
+	var handler = HandleLogLevels(context.Background())
+
+	var rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/admin/log", strings.NewReader(`{"name":"gossip","level":"debug"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Different status, have %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var levels map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &levels); err != nil || levels["gossip"] != "debug" {
+		t.Errorf("Expected debug level of gossip, have %v, %v", levels, err)
+	}
+
+	for _, body := range []string{`{"name":"gossip","level":"loud"}`, `{"name":"nobody","level":"debug"}`, `{"level":"debug"}`, `not json`} {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/admin/log", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, have %d", body, http.StatusBadRequest, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodDelete, "/admin/log", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE should not be allowed, have status %d", rec.Code)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	var ok = func(w http.ResponseWriter, r *http.Request) {}
+	for _, tc := range []struct {
+		token, header string
+		code          int
+	}{
+		{"", "Bearer ", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	} {
+		var req = httptest.NewRequest(http.MethodGet, "/admin/log", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		var rec = httptest.NewRecorder()
+		requireAdmin(tc.token, ok)(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("token %q, header %q: expected status %d, have %d", tc.token, tc.header, tc.code, rec.Code)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,9 +10,13 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/Arceliar/phony"
+	"github.com/cerera/internal/cerera/block"
 	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/logger"
+	"github.com/cerera/internal/cerera/safego"
 	"github.com/cerera/internal/cerera/types"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -23,6 +28,8 @@ import (
 
 const DiscoveryServiceTag = "/vavilov/1.0.0"
 
+var netLog = logger.Named("network")
+
 type Host struct {
 	phony.Inbox
 	Addr    types.Address
@@ -30,13 +37,29 @@ type Host struct {
 	K       []byte
 
 	DataChannel chan []byte
+	Clock       *ClockSkew // offsets of peers clocks
 
 	c context.Context
 
 	// Overlay Swarm
-	Status  byte
-	Stream  network.Stream
-	NetType byte
+	Status     byte
+	Stream     network.Stream
+	NetType    byte
+	framing    *frameState                      // mode of messages written to Stream
+	seen       *seenBlocks                      // hashes of blocks received from swarm
+	onBlock    func(*block.Block) (bool, error) // handler of new blocks received from swarm, chain of node when nil
+	seenTxs    *seenBlocks                      // hashes of txs received from swarm
+	relayedTxs *seenBlocks                      // hashes of txs sent to swarm
+	onTx       func(*types.GTransaction)        // handler of new txs received from swarm
+
+	bans     *banList // peers disconnected for misbehaviour
+	banScore int      // score of peer below which it is banned
+
+	boot      config.BootstrapConfig // retries of connection to swarm
+	bootstrap *bootstrapState        // state of connection to swarm
+	whois     *whoIsTracker          // requests of network addresses of nodes
+	syncs     *syncLimiter           // state syncs served to joining nodes
+	blocks    *blockRequests         // requests of blocks missed by node
 }
 
 // Node interface defines the structure of a Node in the network
@@ -101,17 +124,31 @@ func InitP2PHost(ctx context.Context, cfg config.Config) *Host {
 	p := types.DecodePrivKey(cfg.NetCfg.PRIV)
 	b := types.EncodePrivateKeyToByte(p)
 	dHost := &Host{
-		Addr:    cfg.NetCfg.ADDR,
-		NetHost: h,
-		K:       b,
-		c:       ctx,
+		Addr:       cfg.NetCfg.ADDR,
+		NetHost:    h,
+		K:          b,
+		c:          ctx,
+		Clock:      NewClockSkew(time.Duration(cfg.NetCfg.SKEW) * time.Second),
+		framing:    &frameState{},
+		seen:       newSeenBlocks(cfg.GetSeenBlocks()),
+		seenTxs:    newSeenBlocks(cfg.GetSeenBlocks()),
+		relayedTxs: newSeenBlocks(cfg.GetSeenBlocks()),
+		onTx:       funnelTx,
+		bans:       newBanList(),
+		banScore:   cfg.GetBanScore(),
+		boot:       cfg.GetBootstrap(),
+		bootstrap:  &bootstrapState{},
+		whois:      newWhoIsTracker(cfg.GetWhoIsTimeout(), cfg.GetWhoIsAttempts()),
+		syncs:      newSyncLimiter(cfg.GetMaxSyncs(), cfg.GetSyncInterval()),
+		blocks:     newBlockRequests(cfg.GetBlockRequestTimeout()),
 	}
 	log.Println("Create host with cerera addr:", dHost.Addr)
 	log.Println("Create host with net addrs:", dHost.NetHost.Addrs())
 
 	// Connect to Swarm
 	// ConnectToSwarm(dHost)
-	go dHost.serviceLoop()
+	safego.Loop("host_service", dHost.serviceLoop)
+	safego.Loop("host_whois", dHost.whoIsLoop)
 
 	return dHost
 }
@@ -163,9 +200,11 @@ func (h *Host) serviceLoop() {
 // HandShake performs a handshake over the network stream
 func (h *Host) HandShake() {
 	p := &Packet{
-		T:    0xa,
-		Data: []byte("OP_I"),
+		T:    JoinPacketType,
+		Data: []byte(joinPayload),
 		EF:   0xa,
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
 	}
 	data, _ := json.Marshal(p)
 	n, _ := h.Stream.Write(data)
@@ -177,6 +216,19 @@ func (h *Host) HandShake() {
 	}
 }
 
+// Voters returns count of connected peers including current node.
+func (h *Host) Voters() int {
+	if h.NetHost == nil {
+		return 1
+	}
+	return len(h.NetHost.Network().Peers()) + 1
+}
+
+// SetBlockHandler sets handler of new blocks received from swarm
+func (h *Host) SetBlockHandler(fn func(*block.Block) (bool, error)) {
+	h.onBlock = fn
+}
+
 func (h *Host) SetUpProtocol() {
 
 }
@@ -191,23 +243,39 @@ func (h *Host) SetUpHttp(ctx context.Context, cfg config.Config) {
 	)
 	prometheus.MustRegister(rpcRequestMetric)
 
-	go func() {
+	safego.Go("http_server", func() {
 		http.Handle("/metrics", promhttp.Handler())
 		if cfg.SEC.HTTP.TLS {
-			err := http.ListenAndServeTLS(fmt.Sprintf(":%d", cfg.NetCfg.RPC), "./server.crt", "./server.key", nil)
+			// certificate is rotated without restart by SIGHUP or ReloadTLSCert
+			reloader, err := newCertReloader(TLSCertFile, TLSKeyFile)
 			if err != nil {
 				fmt.Println("ListenAndServe: ", err)
+				return
+			}
+			setCertReloader(reloader)
+			safego.Go("tls_reload", func() { reloadTLSOnHangup(ctx) })
+			var server = &http.Server{
+				Addr:      fmt.Sprintf(":%d", cfg.NetCfg.RPC),
+				TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+			}
+			if err := server.ListenAndServeTLS("", ""); err != nil {
+				fmt.Println("ListenAndServe: ", err)
 			}
 		} else {
 			if err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.NetCfg.RPC), nil); err != nil {
 				fmt.Println("Error starting server:", err)
 			}
 		}
-	}()
+	})
 
 	fmt.Printf("Starting http server at port %d\r\n", cfg.NetCfg.RPC)
 	go http.HandleFunc("/", HandleRequest(ctx))
 	go http.HandleFunc("/ws", HandleWebSockerRequest(ctx))
+	go http.HandleFunc("/faucet/status/", HandleFaucetStatus(ctx))
+	go http.HandleFunc("/balances", HandleBalances(ctx, cfg.GetMaxBulkBalances()))
+	go http.HandleFunc("/stats", HandleStats(ctx))
+	go http.HandleFunc("/history/", HandleHistory(ctx))
+	go http.HandleFunc("/admin/log", requireAdmin(cfg.SEC.HTTP.AdminToken, HandleLogLevels(ctx)))
 }
 
 // Stop stops the host
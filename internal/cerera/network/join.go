@@ -0,0 +1,131 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/storage"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// packets of joining node asking state of vault, answers with vault snapshot and rejection of join
+const (
+	JoinPacketType     = 0xa
+	SyncPacketType     = 0x4
+	JoinNackPacketType = 0xe
+)
+
+// payload of join without account of joining node
+const joinPayload = "OP_I"
+
+var (
+	ErrSyncBusy       = errors.New("too many state syncs in progress")
+	ErrSyncRate       = errors.New("state sync requested too often")
+	ErrInvalidJoinAcc = errors.New("invalid account of joining node")
+)
+
+// syncLimiter bounds count of state syncs served at once and rate of syncs of one peer
+type syncLimiter struct {
+	mu       sync.Mutex
+	max      int
+	interval time.Duration
+	active   int
+	last     map[string]time.Time // start of last sync of peer
+}
+
+func newSyncLimiter(max int, interval time.Duration) *syncLimiter {
+	return &syncLimiter{
+		max:      max,
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// admit starts sync for peer, returned func ends it
+func (l *syncLimiter) admit(peer string, now time.Time) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.last[peer]; ok && now.Sub(last) < l.interval {
+		return nil, ErrSyncRate
+	}
+	if l.active >= l.max {
+		return nil, ErrSyncBusy
+	}
+	l.active++
+	l.last[peer] = now
+	for p, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, p)
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.active--
+		})
+	}, nil
+}
+
+// checkJoinAccount decodes account of joining node, it should be new for
+// vault and have non negative balance
+func checkJoinAccount(data []byte) (*types.StateAccount, error) {
+	sa, err := types.BytesToStateAccountSafe(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJoinAcc, err)
+	}
+	if sa.Address == (types.Address{}) || sa.Balance == nil || sa.Balance.Sign() < 0 {
+		return nil, ErrInvalidJoinAcc
+	}
+	if storage.GetVault().GetCopy(sa.Address) != nil {
+		return nil, fmt.Errorf("%w: account %s is known", ErrInvalidJoinAcc, sa.Address)
+	}
+	return sa, nil
+}
+
+func joinNack(err error) *Packet {
+	return &Packet{
+		T:    JoinNackPacketType,
+		Data: []byte(err.Error()),
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}
+}
+
+// processJoin answers join of peer with snapshot of vault or rejection when
+// limit of syncs is exceeded or account of joining node is invalid. Returned
+// func ends sync after answer is written, error is reason of rejection.
+func (h *Host) processJoin(peer string, data []byte) (*Packet, func(), error) {
+	var done = func() {}
+	var sa *types.StateAccount
+	if len(data) > 0 && string(data) != joinPayload {
+		var err error
+		if sa, err = checkJoinAccount(data); err != nil {
+			return joinNack(err), done, err
+		}
+	}
+	end, err := h.syncs.admit(peer, time.Now())
+	if err != nil {
+		netLog.Warnf("Reject join of %s: %s", peer, err)
+		return joinNack(err), done, err
+	}
+	var vault = storage.GetVault()
+	if sa != nil {
+		vault.Put(sa.Address, *sa)
+	}
+	var snap bytes.Buffer
+	if err := vault.ExportSnapshot(&snap); err != nil {
+		end()
+		return joinNack(err), done, err
+	}
+	return &Packet{
+		T:    SyncPacketType,
+		Data: snap.Bytes(),
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}, end, nil
+}
@@ -0,0 +1,61 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+func TestJoinSyncLimit(t *testing.T) {
+	var known = prepareVault()
+	var h = Host{syncs: newSyncLimiter(2, time.Minute)}
+
+	// syncs in progress are not ended, joins above limit are rejected
+	var ends = make([]func(), 0)
+	for i := 0; i < 5; i++ {
+		reply, end, err := h.processJoin(fmt.Sprintf("peer-%d", i), []byte(joinPayload))
+		if i < 2 {
+			if err != nil || reply.T != SyncPacketType || len(reply.Data) == 0 {
+				t.Fatalf("Join %d should be answered with snapshot, have %d, %v", i, reply.T, err)
+			}
+			ends = append(ends, end)
+			continue
+		}
+		if !errors.Is(err, ErrSyncBusy) || reply.T != JoinNackPacketType {
+			t.Errorf("Join %d should be rejected, have %d, %v", i, reply.T, err)
+		}
+	}
+	for _, end := range ends {
+		end()
+		end()
+	}
+
+	// free slot is given to new peer, but not to peer synced recently
+	if _, _, err := h.processJoin("peer-0", []byte(joinPayload)); !errors.Is(err, ErrSyncRate) {
+		t.Errorf("Expected %s, have %v", ErrSyncRate, err)
+	}
+	if _, end, err := h.processJoin("peer-4", []byte(joinPayload)); err != nil {
+		t.Errorf("Join after end of syncs should be accepted, have %v", err)
+	} else {
+		end()
+	}
+
+	// account of joining node is validated before put to vault
+	pk, _ := types.GenerateAccount()
+	var sa = types.StateAccount{Address: types.PubkeyToAddress(pk.PublicKey), Balance: big.NewInt(-1)}
+	if _, _, err := h.processJoin("peer-5", sa.Bytes()); !errors.Is(err, ErrInvalidJoinAcc) {
+		t.Errorf("Account with negative balance should be rejected, have %v", err)
+	}
+	sa.Address = known
+	sa.Balance = big.NewInt(0)
+	if _, _, err := h.processJoin("peer-5", sa.Bytes()); !errors.Is(err, ErrInvalidJoinAcc) {
+		t.Errorf("Known account should be rejected, have %v", err)
+	}
+	if _, _, err := h.processJoin("peer-5", []byte("{garbage")); !errors.Is(err, ErrInvalidJoinAcc) {
+		t.Errorf("Malformed account should be rejected, have %v", err)
+	}
+}
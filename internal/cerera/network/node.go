@@ -5,6 +5,8 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/network"
 	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/cerera/internal/cerera/safego"
 )
 
 type P2PHost struct {
@@ -28,7 +30,9 @@ func InitServer(h *Host) network.Stream {
 	fmt.Printf("My address is: %s\r\n", fmt.Sprintf("%s/p2p/%s", addr, h.NetHost.ID()))
 	var endPointAddress = fmt.Sprintf("%s/p2p/%s", addr, h.NetHost.ID())
 	WriteSwarmData(h.Addr, endPointAddress)
-	h.NetHost.SetStreamHandler("/vavilov/1.0.0", h.ServerProtocol)
+	h.NetHost.SetStreamHandler("/vavilov/1.0.0", func(s network.Stream) {
+		safego.Go("server_protocol", func() { h.ServerProtocol(s) })
+	})
 	h.Status = 0x2
 	return h.Stream
 }
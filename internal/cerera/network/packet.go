@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/gob"
-	"encoding/json"
 	"fmt"
 
 	"github.com/raszia/gotiny"
@@ -14,6 +13,9 @@ type Packet struct {
 	T    byte   `json:"T,omitempty"`
 	Data []byte `json:"Data,omitempty"`
 	EF   byte   `json:"EF,omitempty"`
+	TS   int64  `json:"TS,omitempty"` // sender time (ms) to detect clock skew
+	H    int    `json:"H,omitempty"`  // height of sender chain
+	FR   bool   `json:"FR,omitempty"` // sender reads length prefixed messages
 }
 
 func (p *Packet) Bytes() []byte {
@@ -31,10 +33,14 @@ func (p *Packet) Bytes() []byte {
 
 }
 
+// parsePacket decodes packet and returns error of malformed message
+func parsePacket(data []byte) (Packet, error) {
+	return DecodePacket(data)
+}
+
 func FromBytes(data []byte) Packet {
 	// 	gob.Register(Packet{})
-	p := Packet{}
-	json.Unmarshal(data, p)
+	p, _ := DecodePacket(data)
 	// 	b := bytes.Buffer{}
 	// 	b.Write(data)
 	// 	d := gob.NewDecoder(&b)
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cerera/internal/cerera/chain"
+)
+
+// changes of peer score by its messages
+const (
+	ScoreInvalidMessage = -5
+	ScoreValidMessage   = 1
+)
+
+// time banned peer can not connect
+const BanDuration = 10 * time.Minute
+
+// banList keeps peers which are not accepted until ban expires
+type banList struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newBanList() *banList {
+	return &banList{until: make(map[string]time.Time)}
+}
+
+func (b *banList) ban(id string, d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.until[id] = time.Now().Add(d)
+}
+
+func (b *banList) banned(id string, now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.until[id]
+	if ok && !now.Before(until) {
+		delete(b.until, id)
+		return false
+	}
+	return ok
+}
+
+// scorePeer changes score of peer by its message, peer with score below
+// ban score is banned. Reports whether peer got banned.
+func (h *Host) scorePeer(id string, valid bool) bool {
+	var delta = ScoreValidMessage
+	if !valid {
+		delta = ScoreInvalidMessage
+	}
+	var bc = chain.GetBlockChain()
+	if score := bc.AdjustPeerScore(id, delta); score < h.banScore {
+		netLog.Warnf("Ban peer %s with score %d for %s", id, score, BanDuration)
+		h.bans.ban(id, BanDuration)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/config"
+)
+
+func TestBanList(t *testing.T) {
+	var b = newBanList()
+	var now = time.Now()
+	b.ban("peer-a", time.Minute)
+	if !b.banned("peer-a", now) {
+		t.Errorf("Peer should be banned")
+	}
+	if b.banned("peer-b", now) {
+		t.Errorf("Other peer should not be banned")
+	}
+	if b.banned("peer-a", now.Add(2*time.Minute)) {
+		t.Errorf("Ban should expire")
+	}
+	if len(b.until) != 0 {
+		t.Errorf("Expired ban should be removed, have %d", len(b.until))
+	}
+}
+
+func TestScorePeer(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Chain.ChainID = big.NewInt(11)
+	cfg.Chain.Path = "EMPTY"
+	cfg.Chain.MEM = true
+	bc := chain.InitBlockChain(cfg)
+
+	var h = Host{bans: newBanList(), banScore: -12}
+	if h.scorePeer("peer-v", true) || bc.PeerScore("peer-v") != ScoreValidMessage {
+		t.Errorf("Valid message should raise score, have %d", bc.PeerScore("peer-v"))
+	}
+	// 1 - 5 - 5 = -9 stays above threshold, next invalid message bans peer
+	for i := 0; i < 2; i++ {
+		if h.scorePeer("peer-v", false) {
+			t.Errorf("Peer should not be banned with score %d", bc.PeerScore("peer-v"))
+		}
+	}
+	if !h.scorePeer("peer-v", false) || !h.bans.banned("peer-v", time.Now()) {
+		t.Errorf("Peer should be banned with score %d", bc.PeerScore("peer-v"))
+	}
+}
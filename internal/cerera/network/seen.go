@@ -0,0 +1,83 @@
+package network
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/chain"
+	"github.com/cerera/internal/cerera/common"
+)
+
+var ErrInvalidBlock = errors.New("invalid block from swarm")
+
+// seenBlocks is lru set of hashes of blocks or txs received from swarm
+type seenBlocks struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently seen
+	items map[common.Hash]*list.Element
+}
+
+func newSeenBlocks(size int) *seenBlocks {
+	if size < 1 {
+		size = 1
+	}
+	return &seenBlocks{
+		size:  size,
+		order: list.New(),
+		items: make(map[common.Hash]*list.Element),
+	}
+}
+
+// check reports whether hash was seen before and remembers it
+func (s *seenBlocks) check(hash common.Hash) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[hash]; ok {
+		s.order.MoveToFront(el)
+		return true
+	}
+	s.items[hash] = s.order.PushFront(hash)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(common.Hash))
+	}
+	return false
+}
+
+// processReceivedBlock decodes block of packet and passes it to block handler
+// of host once, same block from other peers is dropped. Reports whether block
+// was passed, broken block or block rejected by chain returns ErrInvalidBlock.
+// Block of unknown parent is not an error, node may be behind peer.
+func (h *Host) processReceivedBlock(data []byte) (bool, error) {
+	blk, err := block.FromBytes(data)
+	if err != nil || blk == nil || blk.Head == nil {
+		netLog.Warnf("Invalid block from swarm: %v", err)
+		return false, ErrInvalidBlock
+	}
+	if h.seen.check(blk.Hash()) {
+		return false, nil
+	}
+	if _, err := h.handleBlock(blk); err != nil && !errors.Is(err, chain.ErrDuplicateBlock) && !errors.Is(err, chain.ErrUnknownParent) {
+		netLog.Warnf("Block %d from swarm is rejected: %s", blk.Head.Height, err)
+		return true, fmt.Errorf("%w: %s", ErrInvalidBlock, err)
+	}
+	return true, nil
+}
+
+// handleBlock passes block received from swarm to block handler of host or
+// to chain of node when handler is not set
+func (h *Host) handleBlock(b *block.Block) (bool, error) {
+	if h.onBlock != nil {
+		return h.onBlock(b)
+	}
+	var bc = chain.GetBlockChain()
+	return bc.HandleCompetingBlock(b)
+}
@@ -0,0 +1,101 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// files of tls certificate of http server
+const (
+	TLSCertFile = "./server.crt"
+	TLSKeyFile  = "./server.key"
+)
+
+var ErrNoTLS = errors.New("tls of http server is not enabled")
+
+// certReloader serves tls certificate which may be replaced while server
+// runs, connections made before reload keep old certificate
+type certReloader struct {
+	mu       sync.RWMutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	var r = &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload reads certificate files again, certificate which does not parse
+// is not used and served one stays
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls certificate %s is not loaded: %w", r.certFile, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+var (
+	certsMu sync.Mutex
+	certs   *certReloader // nil while http server runs without tls
+)
+
+func setCertReloader(r *certReloader) {
+	certsMu.Lock()
+	defer certsMu.Unlock()
+	certs = r
+}
+
+// ReloadTLSCert reads certificate of http server from its files again, new
+// connections get new certificate. If files do not parse, old certificate
+// is kept and error is returned.
+func ReloadTLSCert() error {
+	certsMu.Lock()
+	var r = certs
+	certsMu.Unlock()
+	if r == nil {
+		return ErrNoTLS
+	}
+	if err := r.reload(); err != nil {
+		netLog.Warnf("%s, old one is kept", err)
+		return err
+	}
+	netLog.Infof("TLS certificate %s is reloaded", r.certFile)
+	return nil
+}
+
+// reloadTLSOnHangup reloads certificate of http server on every SIGHUP
+// until ctx is done
+func reloadTLSOnHangup(ctx context.Context) {
+	var hup = make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-hup:
+			ReloadTLSCert()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
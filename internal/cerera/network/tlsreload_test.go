@@ -0,0 +1,87 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes self-signed certificate with serial and its key to files
+func writeCert(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmpl = &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
+
+func servedSerial(t *testing.T, r *certReloader) int64 {
+	cert, err := r.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("Certificate should be served, have %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestReloadTLSCert(t *testing.T) {
+	defer setCertReloader(nil)
+	if err := ReloadTLSCert(); err != ErrNoTLS {
+		t.Errorf("Expected %s, have %v", ErrNoTLS, err)
+	}
+
+	var dir = t.TempDir()
+	var certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if _, err := newCertReloader(certFile, keyFile); err == nil {
+		t.Errorf("Missing certificate should not be loaded")
+	}
+	writeCert(t, certFile, keyFile, 1)
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCertReloader(r)
+
+	writeCert(t, certFile, keyFile, 2)
+	if err := ReloadTLSCert(); err != nil {
+		t.Fatalf("Rotated certificate should be loaded: %s", err)
+	}
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Errorf("Expected certificate 2, have %d", serial)
+	}
+
+	// broken files do not replace served certificate
+	os.WriteFile(certFile, []byte("not a certificate"), 0600)
+	if err := ReloadTLSCert(); err == nil {
+		t.Errorf("Broken certificate should be reported")
+	}
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Errorf("Expected certificate 2 to be kept, have %d", serial)
+	}
+}
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cerera/internal/cerera/pool"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/cerera/validator"
+)
+
+// type of packet which carries new transaction
+const TxPacketType = 0x7
+
+// prefix of payload of tx packet, hex of json of tx follows it
+const txPrefix = "TX|"
+
+var ErrInvalidTx = errors.New("invalid transaction from swarm")
+
+func encodeTxPayload(tx *types.GTransaction) ([]byte, error) {
+	data, err := tx.MarshalFormatJSON()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(txPrefix + hex.EncodeToString(data)), nil
+}
+
+func decodeTxPayload(payload []byte) (*types.GTransaction, error) {
+	var s = string(payload)
+	if !strings.HasPrefix(s, txPrefix) {
+		return nil, ErrInvalidTx
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(s, txPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTx, err)
+	}
+	var tx = new(types.GTransaction)
+	if err := tx.UnmarshalFormatJSON(data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTx, err)
+	}
+	return tx, nil
+}
+
+// funnelTx passes tx received from swarm to pool of node
+func funnelTx(tx *types.GTransaction) {
+	var p = pool.Get()
+	go func() { p.Funnel <- []*types.GTransaction{tx} }()
+}
+
+// BroadcastTransaction sends tx to swarm once, tx already sent is not sent
+// again. Tx received from swarm is relayed once too, so it reaches peers
+// behind this node, while txs do not loop between peers.
+func (h *Host) BroadcastTransaction(tx *types.GTransaction) error {
+	if h.Stream == nil {
+		return ErrNoStream
+	}
+	if h.relayedTxs.check(tx.Hash()) {
+		return nil
+	}
+	payload, err := encodeTxPayload(tx)
+	if err != nil {
+		return err
+	}
+	var p = &Packet{
+		T:    TxPacketType,
+		Data: payload,
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}
+	return h.framing.send(h.Stream, p)
+}
+
+// BroadcastTransactions sends txs accepted by pool to swarm until channel is
+// closed. Txs received from swarm come back from pool too and are relayed.
+func (h *Host) BroadcastTransactions(txs <-chan *types.GTransaction) {
+	for tx := range txs {
+		if err := h.BroadcastTransaction(tx); err != nil && !errors.Is(err, ErrNoStream) {
+			netLog.Warnf("Tx %s is not broadcasted: %s", tx.Hash(), err)
+		}
+	}
+}
+
+// processReceivedTx decodes tx of packet and passes it to tx handler of host
+// once, same tx from other peers is dropped. Reports whether tx was passed,
+// broken or badly signed tx returns ErrInvalidTx.
+func (h *Host) processReceivedTx(data []byte) (bool, error) {
+	tx, err := decodeTxPayload(data)
+	if err != nil {
+		netLog.Warnf("Invalid tx from swarm: %s", err)
+		return false, err
+	}
+	if h.seenTxs.check(tx.Hash()) {
+		return false, nil
+	}
+	if v := validator.Get(); v != nil && !v.ValidateRawTransaction(tx) {
+		return false, fmt.Errorf("%w: %s", ErrInvalidTx, tx.Hash())
+	}
+	if h.onTx != nil {
+		h.onTx(tx)
+	}
+	return true, nil
+}
@@ -0,0 +1,82 @@
+package network
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+func TestTransactionGossip(t *testing.T) {
+	acc, _ := types.GenerateAccount()
+	var to = types.HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	itx := types.NewTransaction(1, to, big.NewInt(5), 100000, big.NewInt(100), []byte("gossip"))
+	signer := types.NewSimpleSignerWithPen(big.NewInt(11), acc)
+	tx, err := types.SignTx(itx, signer, acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stream = &bufStream{}
+	var sender = Host{
+		Stream:     stream,
+		framing:    &frameState{framed: true},
+		seenTxs:    newSeenBlocks(4),
+		relayedTxs: newSeenBlocks(4),
+	}
+	if err := sender.BroadcastTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	// tx is sent once even if it is submitted again
+	if err := sender.BroadcastTransaction(tx); err != nil || stream.writes() != 1 {
+		t.Errorf("Expected 1 send, have %d, %v", stream.writes(), err)
+	}
+
+	p, err := DecodePacket(stream.buf.Bytes()[4:])
+	if err != nil || p.T != TxPacketType {
+		t.Fatalf("Expected tx packet, have %v", err)
+	}
+	var funneled = make([]*types.GTransaction, 0)
+	var receiver = Host{
+		framing:    &frameState{framed: true},
+		seenTxs:    newSeenBlocks(4),
+		relayedTxs: newSeenBlocks(4),
+		onTx:       func(tx *types.GTransaction) { funneled = append(funneled, tx) },
+	}
+	if ok, err := receiver.processReceivedTx(p.Data); !ok || err != nil {
+		t.Errorf("New tx should be processed, have %v", err)
+	}
+	if ok, err := receiver.processReceivedTx(p.Data); ok || err != nil {
+		t.Errorf("Seen tx should be dropped without error, have %v", err)
+	}
+	if len(funneled) != 1 || funneled[0].Hash() != tx.Hash() {
+		t.Errorf("Expected tx %s funneled once, have %d txs", tx.Hash(), len(funneled))
+	}
+	// tx received from swarm is relayed to next hop once
+	var relay = &bufStream{}
+	receiver.Stream = relay
+	for i := 0; i < 2; i++ {
+		if err := receiver.BroadcastTransaction(funneled[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if relay.writes() != 1 {
+		t.Errorf("Received tx should be relayed once, have %d sends", relay.writes())
+	}
+	// next hop accepts relayed tx, which was not received by it before
+	p, err = DecodePacket(relay.buf.Bytes()[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var next = Host{seenTxs: newSeenBlocks(4), onTx: func(*types.GTransaction) {}}
+	if ok, err := next.processReceivedTx(p.Data); !ok || err != nil {
+		t.Errorf("Relayed tx should be processed by next hop, have %v", err)
+	}
+
+	for _, data := range [][]byte{[]byte("TX|zz"), []byte("BLOCK|00"), []byte("TX|7b7d")} {
+		if _, err := receiver.processReceivedTx(data); !errors.Is(err, ErrInvalidTx) {
+			t.Errorf("Expected %s for %s, have %v", ErrInvalidTx, data, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// packets asking network address of node by its cerera address and answering it
+const (
+	WhoIsPacketType         = 0xc
+	WhoIsResponsePacketType = 0xd
+)
+
+// whoIsAnswer is payload of WHO_IS response
+type whoIsAnswer struct {
+	Addr string `json:"addr"` // cerera address of node
+	Net  string `json:"net"`  // network address of node
+}
+
+type whoIsRequest struct {
+	sent     time.Time
+	attempts int
+}
+
+// whoIsTracker keeps in-flight WHO_IS requests and answers for them, shared by copies of host
+type whoIsTracker struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	attempts int
+	pending  map[string]*whoIsRequest
+	known    map[string]string // network addresses of nodes
+}
+
+func newWhoIsTracker(timeout time.Duration, attempts int) *whoIsTracker {
+	return &whoIsTracker{
+		timeout:  timeout,
+		attempts: attempts,
+		pending:  make(map[string]*whoIsRequest),
+		known:    make(map[string]string),
+	}
+}
+
+// track adds request for address, reports false if request for it is in flight
+func (w *whoIsTracker) track(addr string, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.pending[addr]; ok {
+		return false
+	}
+	w.pending[addr] = &whoIsRequest{sent: now, attempts: 1}
+	return true
+}
+
+// due returns addresses which requests timed out and should be sent again,
+// requests out of attempts are dropped
+func (w *whoIsTracker) due(now time.Time) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var addrs = make([]string, 0)
+	for addr, req := range w.pending {
+		if now.Sub(req.sent) < w.timeout {
+			continue
+		}
+		if req.attempts >= w.attempts {
+			netLog.Warnf("No answer to WHO_IS for %s after %d attempts", addr, req.attempts)
+			delete(w.pending, addr)
+			continue
+		}
+		req.sent = now
+		req.attempts++
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// resolve remembers network address of node and clears its request
+func (w *whoIsTracker) resolve(addr, net string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pending, addr)
+	w.known[addr] = net
+}
+
+func (w *whoIsTracker) lookup(addr string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	net, ok := w.known[addr]
+	return net, ok
+}
+
+func (w *whoIsTracker) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+func (h *Host) writeWhoIs(addr string) error {
+	if h.Stream == nil {
+		return ErrNoStream
+	}
+	var p = &Packet{
+		T:    WhoIsPacketType,
+		Data: []byte(addr),
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}
+	return h.framing.send(h.Stream, p)
+}
+
+// sendWhoIsRequest asks swarm for network address of node, request for address
+// which is already in flight is not duplicated
+func (h *Host) sendWhoIsRequest(addr string) error {
+	if !h.whois.track(addr, time.Now()) {
+		return nil
+	}
+	return h.writeWhoIs(addr)
+}
+
+// resendWhoIs sends again requests which were not answered in time
+func (h *Host) resendWhoIs(now time.Time) {
+	for _, addr := range h.whois.due(now) {
+		if err := h.writeWhoIs(addr); err != nil {
+			netLog.Warnf("Failed to re-send WHO_IS for %s: %s", addr, err)
+		}
+	}
+}
+
+// verifyNodesIPAddresses requests network addresses of nodes which are not known yet
+func (h *Host) verifyNodesIPAddresses(addrs []string) {
+	for _, addr := range addrs {
+		if _, ok := h.whois.lookup(addr); ok {
+			continue
+		}
+		if err := h.sendWhoIsRequest(addr); err != nil {
+			netLog.Warnf("Failed to send WHO_IS for %s: %s", addr, err)
+		}
+	}
+}
+
+// processWhoIsRequest answers with network address when request is about current node
+func (h *Host) processWhoIsRequest(data []byte) *Packet {
+	if string(data) != h.Addr.String() || h.NetHost == nil || len(h.NetHost.Addrs()) == 0 {
+		return nil
+	}
+	var net = fmt.Sprintf("%s/p2p/%s", h.NetHost.Addrs()[0], h.NetHost.ID())
+	payload, err := json.Marshal(whoIsAnswer{Addr: h.Addr.String(), Net: net})
+	if err != nil {
+		return nil
+	}
+	return &Packet{
+		T:    WhoIsResponsePacketType,
+		Data: payload,
+		TS:   time.Now().UnixMilli(),
+		H:    chainHeight(),
+	}
+}
+
+// processWhoIsResponse remembers network address of node and clears its request
+func (h *Host) processWhoIsResponse(data []byte) error {
+	var answer whoIsAnswer
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return err
+	}
+	h.whois.resolve(answer.Addr, answer.Net)
+	return nil
+}
+
+// whoIsLoop re-sends unanswered WHO_IS requests
+func (h *Host) whoIsLoop() {
+	var ticker = time.NewTicker(h.whois.timeout / 2)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		h.resendWhoIs(now)
+	}
+}
+
+// PendingWhoIsCount returns count of WHO_IS requests waiting for answer.
+func (h *Host) PendingWhoIsCount() int {
+	return h.whois.count()
+}
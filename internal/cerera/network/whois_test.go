@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+func TestWhoIsPending(t *testing.T) {
+	var stream = &bufStream{}
+	var h = Host{
+		Stream:  stream,
+		framing: &frameState{framed: true},
+		whois:   newWhoIsTracker(time.Second, 2),
+	}
+
+	// concurrent verifications send one request per address
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.verifyNodesIPAddresses([]string{"0xa", "0xb"})
+		}()
+	}
+	wg.Wait()
+	if h.PendingWhoIsCount() != 2 || stream.writes() != 2 {
+		t.Errorf("Expected 2 pending requests and 2 sends, have %d and %d", h.PendingWhoIsCount(), stream.writes())
+	}
+
+	// unanswered request is re-sent after timeout until attempts are out
+	if err := h.processWhoIsResponse([]byte(`{"addr":"0xa","net":"/ip4/10.0.0.1/tcp/6116"}`)); err != nil {
+		t.Fatal(err)
+	}
+	var now = time.Now()
+	h.resendWhoIs(now)
+	if stream.writes() != 2 {
+		t.Errorf("Request should not be re-sent before timeout")
+	}
+	h.resendWhoIs(now.Add(2 * time.Second))
+	if stream.writes() != 3 || h.PendingWhoIsCount() != 1 {
+		t.Errorf("Expected re-send of one request, have %d sends and %d pending", stream.writes(), h.PendingWhoIsCount())
+	}
+	h.resendWhoIs(now.Add(4 * time.Second))
+	if stream.writes() != 3 || h.PendingWhoIsCount() != 0 {
+		t.Errorf("Request out of attempts should be dropped, have %d sends and %d pending", stream.writes(), h.PendingWhoIsCount())
+	}
+
+	// known address is not requested again
+	h.verifyNodesIPAddresses([]string{"0xa"})
+	if net, _ := h.whois.lookup("0xa"); net != "/ip4/10.0.0.1/tcp/6116" || h.PendingWhoIsCount() != 0 {
+		t.Errorf("Known address should not be requested, have %s", net)
+	}
+}
+
+// bufStream counts framed messages written to swarm stream
+type bufStream struct {
+	network.Stream
+	mu  sync.Mutex
+	buf bytes.Buffer
+	n   int
+}
+
+func (s *bufStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	return s.buf.Write(p)
+}
+
+func (s *bufStream) writes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+)
+
+// how often pool looks for expired txs
+const ExpiryInterval = time.Minute
+
+// reason of eviction of tx which stayed in pool longer than max age
+const EvictExpired = "expired"
+
+// SetMaxAge sets time tx is kept in pool before eviction.
+func (p *Pool) SetMaxAge(maxAge time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxAge = maxAge
+}
+
+// RemoveExpired evicts txs which came to pool more than maxAge ago
+// and were not prepared for block. Returns count of evicted txs.
+func (p *Pool) RemoveExpired(maxAge time.Duration) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var now = time.Now()
+	var count = 0
+	for hash := range p.memPool {
+		if a, ok := p.arrivals[hash]; ok && now.Sub(a.at) < maxAge {
+			continue
+		}
+		fmt.Printf("Evict tx %s from pool: %s\r\n", hash, EvictExpired)
+		poolEvicted.WithLabelValues(EvictExpired).Inc()
+		delete(p.memPool, hash)
+		delete(p.arrivals, hash)
+		count++
+	}
+	return count
+}
+
+// ExpiryLoop evicts expired txs every ExpiryInterval.
+func (p *Pool) ExpiryLoop() {
+	var ticker = time.NewTicker(ExpiryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		var maxAge = p.maxAge
+		p.mu.Unlock()
+		if maxAge > 0 {
+			p.RemoveExpired(maxAge)
+		}
+	}
+}
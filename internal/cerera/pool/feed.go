@@ -0,0 +1,158 @@
+package pool
+
+import (
+	"sync"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// count of txs waiting for slow subscriber, next txs are dropped for it
+const TxFeedBuffer = 64
+
+// txFeed delivers every tx accepted by pool to subscribers.
+// Slow subscribers do not block pool, they miss txs above buffer.
+type txFeed struct {
+	mu   sync.Mutex
+	subs map[chan *types.GTransaction]struct{}
+}
+
+func newTxFeed() *txFeed {
+	return &txFeed{
+		subs: make(map[chan *types.GTransaction]struct{}),
+	}
+}
+
+func (f *txFeed) subscribe() (<-chan *types.GTransaction, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ch = make(chan *types.GTransaction, TxFeedBuffer)
+	f.subs[ch] = struct{}{}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			delete(f.subs, ch)
+			close(ch)
+		})
+	}
+}
+
+func (f *txFeed) send(tx *types.GTransaction) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- tx:
+		default:
+		}
+	}
+}
+
+// Subscribe returns channel which receives every tx accepted by pool and
+// func which ends subscription and closes channel.
+func (p *Pool) Subscribe() (<-chan *types.GTransaction, func()) {
+	return p.feed.subscribe()
+}
+
+// SubscribeAll returns channel which receives every tx accepted by pool in
+// order, none of them is dropped, and func which ends subscription.
+func (p *Pool) SubscribeAll() (<-chan *types.GTransaction, func()) {
+	return p.queue.subscribe()
+}
+
+// txQueueFeed delivers every tx accepted by pool to subscribers in order.
+// Unlike txFeed it drops nothing: txs wait in queue of subscriber until they
+// are read, so txs accepted in burst are all gossiped.
+type txQueueFeed struct {
+	mu   sync.Mutex
+	subs []*txQueueSub
+}
+
+type txQueueSub struct {
+	mu     sync.Mutex
+	queue  []*types.GTransaction
+	signal chan struct{} // queue is not empty
+	out    chan *types.GTransaction
+	done   chan struct{}
+}
+
+func newTxQueueFeed() *txQueueFeed {
+	return &txQueueFeed{
+		subs: make([]*txQueueSub, 0),
+	}
+}
+
+func (f *txQueueFeed) subscribe() (<-chan *types.GTransaction, func()) {
+	var sub = &txQueueSub{
+		signal: make(chan struct{}, 1),
+		out:    make(chan *types.GTransaction),
+		done:   make(chan struct{}),
+	}
+	f.mu.Lock()
+	f.subs = append(f.subs, sub)
+	f.mu.Unlock()
+	go sub.forward()
+
+	var once sync.Once
+	return sub.out, func() {
+		once.Do(func() { f.unsubscribe(sub) })
+	}
+}
+
+func (f *txQueueFeed) unsubscribe(sub *txQueueSub) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.subs {
+		if f.subs[i] == sub {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			break
+		}
+	}
+	close(sub.done)
+}
+
+func (f *txQueueFeed) send(tx *types.GTransaction) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, sub := range f.subs {
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, tx)
+		sub.mu.Unlock()
+		select {
+		case sub.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// forward moves queued txs to channel of subscriber until it unsubscribes
+func (s *txQueueSub) forward() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.signal:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		var tx = s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.out <- tx:
+		case <-s.done:
+			return
+		}
+	}
+}
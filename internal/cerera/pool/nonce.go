@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// reason of eviction of tx which waited too long for missing nonces
+const EvictQueueExpired = "queue_expired"
+
+// SetQueueTTL sets time tx with future nonce waits for missing nonces.
+func (p *Pool) SetQueueTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queueTTL = ttl
+}
+
+// ReadyTransactions returns prepared txs which nonces continue nonce of sender
+// account without gaps. Txs are in order of priority, txs of one sender are in
+// order of nonce. Txs with future nonces stay queued until gap is filled or
+// queue ttl expires, txs with stale nonces are dropped.
+func (p *Pool) ReadyTransactions(accounts AccountReader) []types.GTransaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// contiguous txs of every sender starting from nonce of account
+	var bySender = make(map[types.Address]map[uint64]*types.GTransaction)
+	for _, tx := range p.Prepared {
+		var from = tx.From()
+		if bySender[from] == nil {
+			bySender[from] = make(map[uint64]*types.GTransaction)
+		}
+		if _, ok := bySender[from][tx.Nonce()]; !ok {
+			bySender[from][tx.Nonce()] = tx
+		}
+	}
+	var nonces = make(map[types.Address]uint64, len(bySender))
+	var ready = make(map[types.Address][]*types.GTransaction, len(bySender))
+	for from, txs := range bySender {
+		var nonce = accounts.Get(from).Nonce
+		nonces[from] = nonce
+		for tx, ok := txs[nonce]; ok; tx, ok = txs[nonce] {
+			ready[from] = append(ready[from], tx)
+			nonce++
+		}
+	}
+	var isReady = make(map[common.Hash]bool)
+	for _, txs := range ready {
+		for _, tx := range txs {
+			isReady[tx.Hash()] = true
+		}
+	}
+
+	var now = time.Now()
+	var res = make([]types.GTransaction, 0, len(isReady))
+	var kept = p.Prepared[:0]
+	for _, tx := range p.Prepared {
+		var from, hash = tx.From(), tx.Hash()
+		switch {
+		case isReady[hash]:
+			// place of tx by priority is taken by next tx of sender
+			res = append(res, *ready[from][0])
+			ready[from] = ready[from][1:]
+			delete(p.queued, hash)
+		case tx.Nonce() < nonces[from]:
+			fmt.Printf("Evict tx %s from pool: %s\r\n", hash, EvictStaleNonce)
+			poolEvicted.WithLabelValues(EvictStaleNonce).Inc()
+			delete(p.queued, hash)
+			continue
+		default:
+			if p.queued == nil {
+				p.queued = make(map[common.Hash]time.Time)
+			}
+			since, ok := p.queued[hash]
+			if !ok {
+				p.queued[hash] = now
+			} else if p.queueTTL > 0 && now.Sub(since) >= p.queueTTL {
+				fmt.Printf("Evict tx %s from pool: %s\r\n", hash, EvictQueueExpired)
+				poolEvicted.WithLabelValues(EvictQueueExpired).Inc()
+				delete(p.queued, hash)
+				continue
+			}
+		}
+		kept = append(kept, tx)
+	}
+	p.Prepared = kept
+	return res
+}
+
+// RemovePrepared removes txs from prepared for block, queued txs stay.
+func (p *Pool) RemovePrepared(hashes []common.Hash) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var remove = make(map[common.Hash]bool, len(hashes))
+	for _, hash := range hashes {
+		remove[hash] = true
+	}
+	var kept = p.Prepared[:0]
+	for _, tx := range p.Prepared {
+		if !remove[tx.Hash()] {
+			kept = append(kept, tx)
+		}
+	}
+	p.Prepared = kept
+}
@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+var (
+	ErrPoolFile   = errors.New("invalid pool file")
+	ErrPoolSender = errors.New("sender of tx does not match signature")
+)
+
+// SetFile sets file where pending txs are saved on stop, empty path keeps
+// txs in memory only.
+func (p *Pool) SetFile(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = path
+}
+
+// pending returns txs waiting in pool and prepared for block, once each
+func (p *Pool) pending() []*types.GTransaction {
+	var seen = make(map[common.Hash]bool)
+	var txs = make([]*types.GTransaction, 0, len(p.memPool)+len(p.Prepared))
+	for _, tx := range p.Prepared {
+		if !seen[tx.Hash()] {
+			seen[tx.Hash()] = true
+			txs = append(txs, tx)
+		}
+	}
+	for hash := range p.memPool {
+		var tx = p.memPool[hash]
+		if !seen[hash] {
+			seen[hash] = true
+			txs = append(txs, &tx)
+		}
+	}
+	return txs
+}
+
+// savedSender returns sender written with tx by Persist
+func savedSender(entry json.RawMessage) types.Address {
+	var saved struct {
+		From types.Address `json:"from"`
+	}
+	json.Unmarshal(entry, &saved)
+	return saved.From
+}
+
+// Persist writes pending txs to file, file is replaced at once so it is not
+// left half written.
+func (p *Pool) Persist(path string) error {
+	p.mu.Lock()
+	var entries = make([]json.RawMessage, 0)
+	for _, tx := range p.pending() {
+		data, err := tx.MarshalFormatJSON()
+		if err != nil {
+			fmt.Printf("Tx %s is not persisted: %s\r\n", tx.Hash(), err)
+			continue
+		}
+		entries = append(entries, data)
+	}
+	p.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	var tmp = path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load returns txs saved by Persist to pool. Each tx is checked again: broken
+// txs, unsigned txs, txs which signature does not match saved sender and txs
+// with stale nonce are skipped with warning. Missing file is not an error;
+// file which is not a list of txs is reported and pool stays as is.
+func (p *Pool) Load(path string, signer types.Signer, accounts AccountReader) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("%w: %s", ErrPoolFile, err)
+	}
+	var loaded int
+	for i, entry := range entries {
+		var tx = new(types.GTransaction)
+		if err := tx.UnmarshalFormatJSON(entry); err != nil {
+			fmt.Printf("WARNING! Tx %d of pool file is skipped: %s\r\n", i, err)
+			continue
+		}
+		// signature of changed tx still recovers some address, it should be
+		// the sender saved with tx
+		from, err := types.Sender(signer, tx)
+		if err == nil && from != savedSender(entry) {
+			err = ErrPoolSender
+		}
+		if err != nil {
+			fmt.Printf("WARNING! Tx %s of pool file is skipped: %s\r\n", tx.Hash(), err)
+			continue
+		}
+		if tx.Nonce() < accounts.Get(from).Nonce {
+			fmt.Printf("WARNING! Tx %s of pool file is skipped: %s\r\n", tx.Hash(), EvictStaleNonce)
+			continue
+		}
+		p.AddTransaction(from, tx)
+		loaded++
+	}
+	fmt.Printf("Loaded %d of %d txs from pool file\r\n", loaded, len(entries))
+	return nil
+}
+
+// Stop saves pending txs, so restarted node has them again.
+func (p *Pool) Stop() error {
+	p.mu.Lock()
+	var path = p.file
+	p.mu.Unlock()
+	if path == "" {
+		return nil
+	}
+	return p.Persist(path)
+}
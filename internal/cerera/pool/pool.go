@@ -8,6 +8,9 @@ import (
 	"unsafe"
 
 	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/safego"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/cerera/internal/cerera/types"
 )
@@ -31,6 +34,15 @@ type Pool struct {
 	maxSize        int
 	minGas         uint64
 	memPool        map[common.Hash]types.GTransaction
+	arrivals       map[common.Hash]arrival // order and time of arrival of txs
+	seq            uint64
+	queued         map[common.Hash]time.Time // since when txs wait for missing nonces
+	queueTTL       time.Duration
+	maxAge         time.Duration
+	priceBump      int    // percent gas price of replacing tx exceeds replaced one
+	file           string // file of pending txs, empty for in memory pool
+	feed           *txFeed
+	queue          *txQueueFeed // accepted txs for gossip, none dropped
 	maintainTicker *time.Ticker
 
 	Status   byte
@@ -41,10 +53,10 @@ type Pool struct {
 }
 
 func SendTransaction(tx types.GTransaction) (common.Hash, error) {
-	if len(p.memPool) < p.maxSize && p.minGas <= tx.Gas() {
-		p.memPool[tx.Hash()] = tx
-		// p.memPool = append(p.memPool, tx)
-		// network.BroadcastTx(tx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.add(tx.From(), &tx); err != nil {
+		return tx.Hash(), err
 	}
 	fmt.Println(p.memPool)
 	return tx.Hash(), nil
@@ -55,9 +67,16 @@ func InitPool(minGas uint64, maxSize int) *Pool {
 	mPool := make(map[common.Hash]types.GTransaction)
 	p = Pool{
 		memPool:        mPool,
+		arrivals:       make(map[common.Hash]arrival),
+		queued:         make(map[common.Hash]time.Time),
+		queueTTL:       config.DefaultQueueTTL,
+		maxAge:         config.DefaultTxMaxAge,
+		priceBump:      config.DefaultPriceBump,
 		maintainTicker: time.NewTicker(time.Second * 5),
 		maxSize:        maxSize,
 		minGas:         minGas,
+		feed:           newTxFeed(),
+		queue:          newTxQueueFeed(),
 
 		Prepared: nil,
 		Executed: make([]types.GTransaction, 0),
@@ -67,26 +86,63 @@ func InitPool(minGas uint64, maxSize int) *Pool {
 	}
 	fmt.Printf("Init pool with parameters: \r\n\t MIN_GAS:%d\r\n\tMAX_SIZE:%d\r\n", p.minGas, p.maxSize)
 
-	go p.PoolServiceLoop()
+	safego.Loop("pool_service", p.PoolServiceLoop)
+	safego.Loop("pool_expiry", p.ExpiryLoop)
 	return &p
 }
 
-func (p *Pool) AddRawTransaction(tx *types.GTransaction) {
+// SetUp applies settings of pool from config. Pool in memory mode has no
+// file, so its pending txs are not saved on stop.
+func (p *Pool) SetUp(cfg *config.Config) {
+	p.SetQueueTTL(cfg.GetQueueTTL())
+	p.SetMaxAge(cfg.GetTxMaxAge())
+	p.SetPriceBump(cfg.GetPriceBump())
+	p.SetFile(cfg.GetPoolFile())
+}
+
+func (p *Pool) AddRawTransaction(tx *types.GTransaction) error {
 	fmt.Printf("Catch tx with value: %s\r\n", tx.Value())
-	if len(p.memPool) < p.maxSize && p.minGas <= tx.Gas() {
-		p.memPool[tx.Hash()] = *tx
-		// p.memPool = append(p.memPool, *tx)
-		// network.BroadcastTx(tx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.add(tx.From(), tx); err != nil {
+		fmt.Printf("Tx %s is rejected: %s\r\n", tx.Hash(), err)
+		return err
 	}
 	fmt.Println(len(p.memPool))
+	return nil
 }
 
-func (p *Pool) AddTransaction(from types.Address, tx *types.GTransaction) {
-	if len(p.memPool) < p.maxSize && p.minGas <= tx.Gas() {
-		p.memPool[tx.Hash()] = *tx
-		// p.memPool = append(p.memPool, *tx)
-		// network.BroadcastTx(tx)
+func (p *Pool) AddTransaction(from types.Address, tx *types.GTransaction) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.add(from, tx)
+}
+
+// add puts tx into pool, tx with nonce of pending tx of same sender replaces
+// it if its gas price is high enough
+func (p *Pool) add(from types.Address, tx *types.GTransaction) error {
+	if p.minGas > tx.Gas() {
+		return nil
+	}
+	old, err := p.replaceable(from, tx)
+	if err != nil {
+		return err
 	}
+	if old == nil && len(p.memPool) >= p.maxSize {
+		return nil
+	}
+	if old != nil {
+		fmt.Printf("Evict tx %s from pool: %s by %s\r\n", old.Hash(), EvictReplaced, tx.Hash())
+		poolEvicted.WithLabelValues(EvictReplaced).Inc()
+		p.remove(old.Hash())
+	}
+	p.memPool[tx.Hash()] = *tx
+	p.arrive(tx.Hash())
+	p.feed.send(tx)
+	p.queue.send(tx)
+	// p.memPool = append(p.memPool, *tx)
+	// network.BroadcastTx(tx)
+	return nil
 }
 
 func (p *Pool) GetInfo() MemPoolInfo {
@@ -158,12 +214,13 @@ func (p *Pool) PoolServiceLoop() {
 				fmt.Printf("%s to %s - signed %t \r\n", tx.Hash(), tx.To(), tx.IsSigned())
 				// if tx signed - add it to block
 				if big.NewInt(0).Cmp(r) != 0 && big.NewInt(0).Cmp(s) != 0 && big.NewInt(0).Cmp(v) != 0 {
-					p.Prepared = append(p.Prepared, &tx)
+					p.prepare(&tx)
 				}
 				for _, preparedTx := range p.Prepared {
 					delete(p.memPool, preparedTx.Hash())
 				}
 			}
+			p.forgetArrivals()
 			p.mu.Unlock()
 			// fmt.Printf("Prepared for block txs count: %d\r\n", len(p.Prepared))
 			// fmt.Printf("Executed txs count: %d\r\n", len(p.Executed))
@@ -212,3 +269,53 @@ func (p *Pool) GetMinimalGasValue() uint64 {
 func Get() *Pool {
 	return &p
 }
+
+// AccountReader gives current state of accounts to check pending txs
+type AccountReader interface {
+	Get(types.Address) types.StateAccount
+}
+
+// reasons of tx eviction from pool
+const (
+	EvictInsufficientFunds = "insufficient_funds"
+	EvictStaleNonce        = "stale_nonce"
+)
+
+var poolEvicted = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pool_evicted_total",
+		Help: "Count txs evicted from pool after block",
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	prometheus.MustRegister(poolEvicted)
+}
+
+// Reconcile re-checks pending signed txs against accounts state after block
+// is applied and evicts txs which became invalid. Returns evicted hashes.
+func (p *Pool) Reconcile(vault AccountReader) []common.Hash {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var evicted = make([]common.Hash, 0)
+	for hash, tx := range p.memPool {
+		if !tx.IsSigned() {
+			continue
+		}
+		var sender = vault.Get(tx.From())
+		var reason string
+		if sender.Balance == nil || sender.Balance.Cmp(tx.Value()) < 0 {
+			reason = EvictInsufficientFunds
+		} else if tx.Nonce() < sender.Nonce {
+			reason = EvictStaleNonce
+		} else {
+			continue
+		}
+		fmt.Printf("Evict tx %s from pool: %s\r\n", hash, reason)
+		poolEvicted.WithLabelValues(reason).Inc()
+		delete(p.memPool, hash)
+		evicted = append(evicted, hash)
+	}
+	return evicted
+}
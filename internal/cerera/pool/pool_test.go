@@ -1,9 +1,16 @@
 package pool
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cerera/internal/cerera/common"
 	"github.com/cerera/internal/cerera/types"
 )
 
@@ -65,3 +72,417 @@ func TestUtilityMethods(t *testing.T) {
 		t.Errorf("Diffenrent minimum gas value! Have %d, want %d", tPool.GetMinimalGasValue(), minGas)
 	}
 }
+
+type testAccounts map[types.Address]types.StateAccount
+
+func (ta testAccounts) Get(addr types.Address) types.StateAccount {
+	return ta[addr]
+}
+
+func createSignedTx(value int64, nonce uint64) *types.GTransaction {
+	var acc, _ = types.GenerateAccount()
+	var to = types.HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	itx := types.NewTx(&types.PGTransaction{
+		To:       &to,
+		Value:    big.NewInt(value),
+		GasPrice: big.NewInt(15),
+		Gas:      1000000,
+		Nonce:    nonce,
+		Time:     time.Now(),
+	})
+	signer := types.NewSimpleSignerWithPen(big.NewInt(25331), acc)
+	tx, _ := types.SignTx(itx, signer, acc)
+	return tx
+}
+
+func TestReconcile(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var valid = createSignedTx(10, 2)
+	var poor = createSignedTx(100, 2)
+	var stale = createSignedTx(10, 1)
+	var accounts = testAccounts{
+		valid.From(): {Balance: big.NewInt(50), Nonce: 2},
+		poor.From():  {Balance: big.NewInt(50), Nonce: 2},
+		stale.From(): {Balance: big.NewInt(50), Nonce: 2},
+	}
+	tPool.AddRawTransaction(valid)
+	tPool.AddRawTransaction(poor)
+	tPool.AddRawTransaction(stale)
+	// unsigned txs are not checked
+	tPool.AddRawTransaction(testTx1)
+
+	var evicted = tPool.Reconcile(accounts)
+	if len(evicted) != 2 {
+		t.Errorf("Different evicted count, have %d, want %d", len(evicted), 2)
+	}
+	if tPool.GetTransaction(valid.Hash()) == nil {
+		t.Errorf("Valid tx evicted from pool")
+	}
+	if tPool.GetTransaction(poor.Hash()) != nil {
+		t.Errorf("Tx with insufficient funds still in pool")
+	}
+	if tPool.GetTransaction(stale.Hash()) != nil {
+		t.Errorf("Tx with stale nonce still in pool")
+	}
+	if tPool.GetTransaction(testTx1.Hash()) == nil {
+		t.Errorf("Unsigned tx evicted from pool")
+	}
+}
+
+func TestPendingByGasPrice(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var to = types.HexToAddress("0x24F369F35D4323dF9980eDF0E1bEdb882C4705e984Bb01aceE5B80F4b6Ad1A81a976278d1245dC6863CfF8ec7F99b5B6")
+	var txs = []*types.GTransaction{
+		types.NewTransaction(1, to, big.NewInt(1), 2000, big.NewInt(100), []byte{0x1}),
+		types.NewTransaction(2, to, big.NewInt(1), 2000, big.NewInt(300), []byte{0x2}),
+		types.NewTransaction(2, to, big.NewInt(1), 2000, big.NewInt(200), []byte{0x3}),
+		types.NewTransaction(1, to, big.NewInt(1), 2000, big.NewInt(200), []byte{0x4}),
+		types.NewTransaction(1, to, big.NewInt(1), 2000, big.NewInt(200), []byte{0x5}),
+	}
+	for _, tx := range txs {
+		tPool.AddRawTransaction(tx)
+	}
+	tPool.mu.Lock()
+	for _, tx := range txs {
+		tPool.prepare(tx)
+	}
+	tPool.mu.Unlock()
+
+	// gas price desc, then nonce asc, then arrival
+	var want = []*types.GTransaction{txs[1], txs[3], txs[4], txs[2], txs[0]}
+	var pending = tPool.GetPendingTransactionsByGasPrice(0)
+	if len(pending) != len(want) {
+		t.Fatalf("Different count of pending txs, have %d, want %d", len(pending), len(want))
+	}
+	for i := range want {
+		if pending[i].Hash() != want[i].Hash() {
+			t.Errorf("Different tx at %d, have %s, want %s", i, pending[i].Hash(), want[i].Hash())
+		}
+	}
+	if pending = tPool.GetPendingTransactionsByGasPrice(2); len(pending) != 2 || pending[1].Hash() != txs[3].Hash() {
+		t.Errorf("Limit should return 2 first txs")
+	}
+}
+
+func TestReadyTransactions(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var acc, _ = types.GenerateAccount()
+	var other, _ = types.GenerateAccount()
+	var to = types.HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	var sign = func(key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *types.GTransaction {
+		itx := types.NewTx(&types.PGTransaction{
+			To:       &to,
+			Value:    big.NewInt(1),
+			GasPrice: big.NewInt(gasPrice),
+			Gas:      1000000,
+			Nonce:    nonce,
+			Time:     time.Now(),
+		})
+		signer := types.NewSimpleSignerWithPen(big.NewInt(25331), key)
+		tx, _ := types.SignTx(itx, signer, key)
+		return tx
+	}
+	var stale = sign(acc, 1, 50)
+	var first = sign(acc, 2, 10)
+	var second = sign(acc, 3, 40)
+	var future = sign(acc, 5, 30)
+	var gapped = sign(other, 8, 20)
+	var accounts = testAccounts{
+		types.PubkeyToAddress(acc.PublicKey):   {Nonce: 2},
+		types.PubkeyToAddress(other.PublicKey): {Nonce: 7},
+	}
+	tPool.mu.Lock()
+	for _, tx := range []*types.GTransaction{stale, first, second, future, gapped} {
+		tPool.arrive(tx.Hash())
+		tPool.prepare(tx)
+	}
+	tPool.mu.Unlock()
+
+	// txs of sender go by nonce even if later one pays more
+	var ready = tPool.ReadyTransactions(accounts)
+	if len(ready) != 2 || ready[0].Hash() != first.Hash() || ready[1].Hash() != second.Hash() {
+		t.Fatalf("Expected 2 contiguous txs of sender, have %d", len(ready))
+	}
+	tPool.RemovePrepared([]common.Hash{first.Hash(), second.Hash()})
+	if len(tPool.Prepared) != 2 {
+		t.Errorf("Future txs should stay queued, have %d prepared", len(tPool.Prepared))
+	}
+
+	// gap is filled
+	var missing = sign(other, 7, 5)
+	tPool.mu.Lock()
+	tPool.arrive(missing.Hash())
+	tPool.prepare(missing)
+	tPool.mu.Unlock()
+	ready = tPool.ReadyTransactions(accounts)
+	if len(ready) != 2 || ready[0].Hash() != missing.Hash() || ready[1].Hash() != gapped.Hash() {
+		t.Errorf("Expected txs after filled gap, have %d", len(ready))
+	}
+
+	// queued tx expires
+	tPool.RemovePrepared([]common.Hash{missing.Hash(), gapped.Hash()})
+	tPool.SetQueueTTL(time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	tPool.ReadyTransactions(accounts)
+	if len(tPool.Prepared) != 0 {
+		t.Errorf("Expired tx should be evicted, have %d prepared", len(tPool.Prepared))
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var old = createSignedTx(10, 1)
+	var fresh = createSignedTx(10, 1)
+	tPool.AddRawTransaction(old)
+	tPool.AddRawTransaction(fresh)
+	tPool.mu.Lock()
+	var a = tPool.arrivals[old.Hash()]
+	a.at = a.at.Add(-2 * time.Hour)
+	tPool.arrivals[old.Hash()] = a
+	tPool.mu.Unlock()
+
+	if n := tPool.RemoveExpired(time.Hour); n != 1 {
+		t.Errorf("Different evicted count, have %d, want %d", n, 1)
+	}
+	if tPool.GetTransaction(old.Hash()) != nil {
+		t.Errorf("Expired tx still in pool")
+	}
+	if tPool.GetTransaction(fresh.Hash()) == nil {
+		t.Errorf("Fresh tx evicted from pool")
+	}
+
+	// eviction runs along with adding txs
+	var done = make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			tPool.AddRawTransaction(createSignedTx(int64(i), 1))
+		}
+		close(done)
+	}()
+	for i := 0; i < 5; i++ {
+		tPool.RemoveExpired(time.Hour)
+	}
+	<-done
+}
+
+func TestPersistLoad(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var acc, _ = types.GenerateAccount()
+	var to = types.HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	var signer = types.NewSimpleSignerWithPen(big.NewInt(25331), acc)
+	var sign = func(nonce uint64) *types.GTransaction {
+		itx := types.NewTx(&types.PGTransaction{
+			To:       &to,
+			Value:    big.NewInt(1),
+			GasPrice: big.NewInt(15),
+			Gas:      1000000,
+			Nonce:    nonce,
+			Time:     time.Now().UTC(),
+		})
+		tx, _ := types.SignTx(itx, signer, acc)
+		return tx
+	}
+	var stale, ready, queued = sign(1), sign(2), sign(3)
+	tPool.AddRawTransaction(stale)
+	tPool.AddRawTransaction(ready)
+	tPool.mu.Lock()
+	tPool.arrive(queued.Hash())
+	tPool.prepare(queued)
+	tPool.mu.Unlock()
+
+	var path = filepath.Join(t.TempDir(), "pool.dat")
+	tPool.SetFile(path)
+	if err := tPool.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// restarted node
+	tPool.Clear()
+	tPool.Prepared = nil
+	var accounts = testAccounts{types.PubkeyToAddress(acc.PublicKey): {Nonce: 2}}
+	if err := tPool.Load(path, signer, accounts); err != nil {
+		t.Fatal(err)
+	}
+	if tPool.GetTransaction(ready.Hash()) == nil || tPool.GetTransaction(queued.Hash()) == nil {
+		t.Errorf("Pending txs should be loaded")
+	}
+	if tPool.GetTransaction(stale.Hash()) != nil {
+		t.Errorf("Tx with stale nonce should be skipped")
+	}
+	if from := tPool.GetTransaction(ready.Hash()).From(); from != types.PubkeyToAddress(acc.PublicKey) {
+		t.Errorf("Expected sender %s, have %s", types.PubkeyToAddress(acc.PublicKey), from)
+	}
+
+	// broken entries are skipped, broken file is reported
+	tPool.Clear()
+	good, _ := ready.MarshalFormatJSON()
+	var sender = strings.ToLower(types.PubkeyToAddress(acc.PublicKey).Hex())
+	var forged = strings.Replace(string(good), sender, strings.ToLower(to.Hex()), 1)
+	os.WriteFile(path, []byte(`[{"type":9},`+forged+`,`+string(good)+`]`), 0644)
+	if err := tPool.Load(path, signer, accounts); err != nil {
+		t.Fatal(err)
+	}
+	if info := tPool.GetInfo(); len(info.Txs) != 1 || tPool.GetTransaction(ready.Hash()) == nil {
+		t.Errorf("Only valid tx should be loaded, have %d", len(info.Txs))
+	}
+	os.WriteFile(path, []byte(`{"txs"`), 0644)
+	if err := tPool.Load(path, signer, accounts); !errors.Is(err, ErrPoolFile) {
+		t.Errorf("Expected %s, have %v", ErrPoolFile, err)
+	}
+	if err := tPool.Load(filepath.Join(t.TempDir(), "missing.dat"), signer, accounts); err != nil {
+		t.Errorf("Missing file should not be an error, have %s", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	first, unsubscribe := tPool.Subscribe()
+	second, _ := tPool.Subscribe()
+
+	var tx = createSignedTx(10, 1)
+	tPool.AddRawTransaction(tx)
+	// tx below min gas is not accepted
+	tPool.AddRawTransaction(testTx2)
+	for _, ch := range []<-chan *types.GTransaction{first, second} {
+		select {
+		case got := <-ch:
+			if got.Hash() != tx.Hash() {
+				t.Errorf("Expected tx %s, have %s", tx.Hash(), got.Hash())
+			}
+		default:
+			t.Fatalf("Accepted tx was not delivered")
+		}
+		if len(ch) != 0 {
+			t.Errorf("Rejected tx should not be delivered")
+		}
+	}
+
+	// ended subscription is closed, slow subscriber does not block pool
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-first; ok {
+		t.Errorf("Channel should be closed after unsubscribe")
+	}
+	for i := 0; i < TxFeedBuffer+1; i++ {
+		tPool.feed.send(createSignedTx(int64(i), 1))
+	}
+	if len(second) != TxFeedBuffer {
+		t.Errorf("Expected %d buffered txs, have %d", TxFeedBuffer, len(second))
+	}
+}
+
+func TestSubscribeAll(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	txs, cancel := tPool.SubscribeAll()
+
+	// burst above buffer of lossy feed is delivered to slow subscriber
+	var sent = make([]*types.GTransaction, 0)
+	for i := 0; i < TxFeedBuffer+8; i++ {
+		var tx = createSignedTx(int64(i), 1)
+		tPool.queue.send(tx)
+		sent = append(sent, tx)
+	}
+	for i, tx := range sent {
+		select {
+		case got := <-txs:
+			if got.Hash() != tx.Hash() {
+				t.Errorf("Tx %d: expected %s, have %s", i, tx.Hash(), got.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Tx %d was not delivered", i)
+		}
+	}
+
+	cancel()
+	cancel()
+	select {
+	case _, ok := <-txs:
+		if ok {
+			t.Errorf("Channel should be closed after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Channel should be closed after cancel")
+	}
+}
+
+func TestReplaceTransaction(t *testing.T) {
+	tPool := InitPool(uint64(minGas), maxCap)
+	var acc, _ = types.GenerateAccount()
+	var to = types.HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	var sign = func(nonce uint64, gasPrice int64) *types.GTransaction {
+		itx := types.NewTx(&types.PGTransaction{
+			To:       &to,
+			Value:    big.NewInt(1),
+			GasPrice: big.NewInt(gasPrice),
+			Gas:      1000000,
+			Nonce:    nonce,
+			Time:     time.Now(),
+		})
+		signer := types.NewSimpleSignerWithPen(big.NewInt(25331), acc)
+		tx, _ := types.SignTx(itx, signer, acc)
+		return tx
+	}
+	var from = types.PubkeyToAddress(acc.PublicKey)
+	var old = sign(4, 100)
+	if err := tPool.AddTransaction(from, old); err != nil {
+		t.Fatalf("Tx should be accepted: %s", err)
+	}
+
+	// default bump is 10%, cheaper replacements are rejected and old tx stays
+	for _, gasPrice := range []int64{90, 100, 105, 109} {
+		var tx = sign(4, gasPrice)
+		if err := tPool.AddTransaction(from, tx); !errors.Is(err, ErrReplacementUnderpriced) {
+			t.Errorf("Expected %s for gas price %d, have %v", ErrReplacementUnderpriced, gasPrice, err)
+		}
+		if tPool.GetTransaction(tx.Hash()) != nil {
+			t.Errorf("Underpriced tx %d should not be in pool", gasPrice)
+		}
+	}
+	if tPool.GetTransaction(old.Hash()) == nil {
+		t.Errorf("Replaced tx should stay after rejected replacement")
+	}
+	// other nonce of sender is not a replacement
+	if err := tPool.AddTransaction(from, sign(5, 1)); err != nil {
+		t.Errorf("Tx with next nonce should be accepted: %s", err)
+	}
+
+	var bumped = sign(4, 110)
+	if err := tPool.AddTransaction(from, bumped); err != nil {
+		t.Fatalf("Replacement should be accepted: %s", err)
+	}
+	if tPool.GetTransaction(old.Hash()) != nil {
+		t.Errorf("Replaced tx should be removed from pool")
+	}
+	if _, ok := tPool.arrivals[old.Hash()]; ok {
+		t.Errorf("Arrival of replaced tx should be removed")
+	}
+	if tPool.GetTransaction(bumped.Hash()) == nil {
+		t.Errorf("Replacement should be in pool")
+	}
+
+	// tx prepared for block and queued is replaced too
+	tPool.mu.Lock()
+	delete(tPool.memPool, bumped.Hash())
+	tPool.prepare(bumped)
+	tPool.queued[bumped.Hash()] = time.Now()
+	tPool.mu.Unlock()
+	tPool.SetPriceBump(50)
+	if err := tPool.AddRawTransaction(sign(4, 160)); !errors.Is(err, ErrReplacementUnderpriced) {
+		t.Errorf("Expected %s, have %v", ErrReplacementUnderpriced, err)
+	}
+	var last = sign(4, 165)
+	if err := tPool.AddRawTransaction(last); err != nil {
+		t.Fatalf("Replacement should be accepted: %s", err)
+	}
+	for _, tx := range tPool.Prepared {
+		if tx.Hash() == bumped.Hash() {
+			t.Errorf("Replaced tx should be removed from prepared")
+		}
+	}
+	if _, ok := tPool.queued[bumped.Hash()]; ok {
+		t.Errorf("Replaced tx should be removed from queue")
+	}
+	if tPool.GetTransaction(last.Hash()) == nil {
+		t.Errorf("Replacement should be in pool")
+	}
+}
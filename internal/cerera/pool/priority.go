@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+type arrival struct {
+	seq uint64
+	at  time.Time
+}
+
+// arrive remembers order in which txs come to pool, re-signed tx keeps its place
+func (p *Pool) arrive(hash common.Hash) {
+	if p.arrivals == nil {
+		p.arrivals = make(map[common.Hash]arrival)
+	}
+	if _, ok := p.arrivals[hash]; !ok {
+		p.seq++
+		p.arrivals[hash] = arrival{seq: p.seq, at: time.Now()}
+	}
+}
+
+// higher reports whether tx a goes to block before tx b:
+// higher gas price first, then lower nonce, then earlier arrival
+func (p *Pool) higher(a, b *types.GTransaction) bool {
+	if c := a.GasPrice().Cmp(b.GasPrice()); c != 0 {
+		return c > 0
+	}
+	if a.Nonce() != b.Nonce() {
+		return a.Nonce() < b.Nonce()
+	}
+	return p.arrivals[a.Hash()].seq < p.arrivals[b.Hash()].seq
+}
+
+// prepare inserts signed tx into Prepared keeping order of priority
+func (p *Pool) prepare(tx *types.GTransaction) {
+	var i = sort.Search(len(p.Prepared), func(i int) bool {
+		return p.higher(tx, p.Prepared[i])
+	})
+	p.Prepared = append(p.Prepared, nil)
+	copy(p.Prepared[i+1:], p.Prepared[i:])
+	p.Prepared[i] = tx
+}
+
+// forgetArrivals drops order of txs which left pool
+func (p *Pool) forgetArrivals() {
+	var prepared = make(map[common.Hash]bool, len(p.Prepared))
+	for _, tx := range p.Prepared {
+		prepared[tx.Hash()] = true
+	}
+	for hash := range p.arrivals {
+		if _, ok := p.memPool[hash]; !ok && !prepared[hash] {
+			delete(p.arrivals, hash)
+		}
+	}
+}
+
+// GetPendingTransactionsByGasPrice returns signed txs prepared for block
+// ordered by gas price, higher first, ties are ordered by nonce and arrival.
+// At most limit txs are returned, limit <= 0 means all.
+func (p *Pool) GetPendingTransactionsByGasPrice(limit int) []types.GTransaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var count = len(p.Prepared)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	var res = make([]types.GTransaction, 0, count)
+	for _, tx := range p.Prepared[:count] {
+		res = append(res, *tx)
+	}
+	return res
+}
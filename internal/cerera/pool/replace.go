@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// reason of eviction of tx replaced by tx with same sender and nonce
+const EvictReplaced = "replaced"
+
+var ErrReplacementUnderpriced = errors.New("replacement tx gas price is too low")
+
+// SetPriceBump sets percent by which gas price of tx should exceed gas price
+// of pending tx with same sender and nonce to replace it.
+func (p *Pool) SetPriceBump(percent int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.priceBump = percent
+}
+
+// sameNonce returns tx of sender with nonce waiting in pool or prepared
+// for block
+func (p *Pool) sameNonce(from types.Address, nonce uint64) *types.GTransaction {
+	for _, tx := range p.Prepared {
+		if tx.Nonce() == nonce && tx.From() == from {
+			return tx
+		}
+	}
+	for hash := range p.memPool {
+		var tx = p.memPool[hash]
+		if tx.Nonce() == nonce && tx.From() == from {
+			return &tx
+		}
+	}
+	return nil
+}
+
+// replaceable returns pending tx which tx replaces, nil if there is no tx of
+// sender with same nonce. Replacing tx should pay more than replaced one by
+// price bump, otherwise ErrReplacementUnderpriced is returned. Sender of
+// unsigned tx is unknown, it does not replace anything.
+func (p *Pool) replaceable(from types.Address, tx *types.GTransaction) (*types.GTransaction, error) {
+	if from == (types.Address{}) {
+		return nil, nil
+	}
+	var old = p.sameNonce(from, tx.Nonce())
+	if old == nil || old.Hash() == tx.Hash() {
+		return nil, nil
+	}
+	// price*100 >= oldPrice*(100+bump), so bump is not lost in rounding
+	var price = new(big.Int).Mul(tx.GasPrice(), big.NewInt(100))
+	var need = new(big.Int).Mul(old.GasPrice(), big.NewInt(int64(100+p.priceBump)))
+	if tx.GasPrice().Cmp(old.GasPrice()) <= 0 || price.Cmp(need) < 0 {
+		return nil, fmt.Errorf("%w: %s for nonce %d, pending tx %s pays %s, bump %d%%",
+			ErrReplacementUnderpriced, tx.GasPrice(), tx.Nonce(), old.Hash(), old.GasPrice(), p.priceBump)
+	}
+	return old, nil
+}
+
+// remove drops tx from pool, prepared txs and queue
+func (p *Pool) remove(hash common.Hash) {
+	delete(p.memPool, hash)
+	delete(p.arrivals, hash)
+	delete(p.queued, hash)
+	var kept = p.Prepared[:0]
+	for _, tx := range p.Prepared {
+		if tx.Hash() != hash {
+			kept = append(kept, tx)
+		}
+	}
+	p.Prepared = kept
+}
@@ -0,0 +1,62 @@
+package safego
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// delay before first restart of panicked loop, doubled after each next panic
+var (
+	RestartBackoff    = time.Second
+	MaxRestartBackoff = time.Minute
+)
+
+var goroutinePanics = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goroutine_panics_total",
+		Help: "Count recovered panics of goroutines",
+	},
+	[]string{"name"},
+)
+
+func init() {
+	prometheus.MustRegister(goroutinePanics)
+}
+
+// run calls fn and recovers its panic, reports whether fn panicked
+func run(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			goroutinePanics.WithLabelValues(name).Inc()
+			log.Printf("PANIC in goroutine %s: %v\r\n%s", name, r, debug.Stack())
+		}
+	}()
+	fn()
+	return false
+}
+
+// Go runs fn in new goroutine, panic of fn is recovered and reported
+// instead of crashing the node.
+func Go(name string, fn func()) {
+	go run(name, fn)
+}
+
+// Loop runs long-lived fn in new goroutine and restarts it with backoff
+// after panic. Loop stops when fn returns normally.
+func Loop(name string, fn func()) {
+	go func() {
+		var backoff = RestartBackoff
+		for run(name, fn) {
+			log.Printf("Restart goroutine %s in %s\r\n", name, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > MaxRestartBackoff {
+				backoff = MaxRestartBackoff
+			}
+		}
+	}()
+}
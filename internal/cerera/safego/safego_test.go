@@ -0,0 +1,57 @@
+package safego
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGoRecoversPanic(t *testing.T) {
+	var before = testutil.ToFloat64(goroutinePanics.WithLabelValues("test_go"))
+	var done = make(chan struct{})
+	Go("test_go", func() {
+		defer close(done)
+		var m map[string]int
+		m["a"] = 1
+	})
+	<-done
+	// panic is counted after deferred funcs of fn
+	var deadline = time.Now().Add(time.Second)
+	for testutil.ToFloat64(goroutinePanics.WithLabelValues("test_go")) != before+1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Panic of goroutine was not counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoopRestarts(t *testing.T) {
+	var backoff = RestartBackoff
+	RestartBackoff = time.Millisecond
+	defer func() { RestartBackoff = backoff }()
+
+	var before = testutil.ToFloat64(goroutinePanics.WithLabelValues("test_loop"))
+	var runs = make(chan int, 3)
+	var count = 0
+	Loop("test_loop", func() {
+		count++
+		runs <- count
+		if count < 3 {
+			panic("loop failed")
+		}
+	})
+	for i := 1; i <= 3; i++ {
+		select {
+		case n := <-runs:
+			if n != i {
+				t.Errorf("Different run number, have %d, want %d", n, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Loop was not restarted after panic %d", i)
+		}
+	}
+	if v := testutil.ToFloat64(goroutinePanics.WithLabelValues("test_loop")) - before; v != 2 {
+		t.Errorf("Different count of panics, have %v, want %d", v, 2)
+	}
+}
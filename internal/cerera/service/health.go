@@ -0,0 +1,45 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// health statuses of node and services
+const (
+	HealthOk        = "ok"
+	HealthUnhealthy = "unhealthy"
+)
+
+// HealthReport is response of health endpoint
+type HealthReport struct {
+	Status   string            `json:"status"`
+	Services map[string]string `json:"services"` // status or error of every checked service
+}
+
+// Health returns report of all services, node is healthy when all of them are.
+func (r *Registry) Health() HealthReport {
+	var report = HealthReport{Status: HealthOk, Services: make(map[string]string)}
+	for name, err := range r.AggregateHealth() {
+		if err != nil {
+			report.Status = HealthUnhealthy
+			report.Services[name] = err.Error()
+		} else {
+			report.Services[name] = HealthOk
+		}
+	}
+	return report
+}
+
+// HealthHandler serves health report of registry for orchestration probes,
+// unhealthy node answers with 503.
+func HealthHandler(r *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var report = r.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != HealthOk {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// HealthChecker is service which reports whether it works, nil error means healthy.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// Stoppable is service which releases its resources on shutdown of node.
+type Stoppable interface {
+	Stop() error
+}
+
+type entry struct {
+	name string
+	svc  interface{}
+}
+
+// Registry keeps services of node in order of registration, so they are
+// stopped in reverse order: services registered later may use earlier ones.
+type Registry struct {
+	mu       sync.Mutex
+	services []entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds service to registry, service which is not Stoppable is kept
+// but skipped on shutdown.
+func (r *Registry) Register(name string, svc interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services = append(r.services, entry{name: name, svc: svc})
+}
+
+// AggregateHealth returns result of health check of every service which
+// implements HealthChecker by name of service, nil means healthy.
+func (r *Registry) AggregateHealth() map[string]error {
+	r.mu.Lock()
+	var services = make([]entry, len(r.services))
+	copy(services, r.services)
+	r.mu.Unlock()
+
+	var res = make(map[string]error)
+	for _, e := range services {
+		if hc, ok := e.svc.(HealthChecker); ok {
+			res[e.name] = hc.HealthCheck()
+		}
+	}
+	return res
+}
+
+// StopAllServices stops Stoppable services in reverse order of registration.
+// Services left when context is done are not stopped. Returned error joins
+// errors of all services which failed to stop.
+func (r *Registry) StopAllServices(ctx context.Context) error {
+	r.mu.Lock()
+	var services = make([]entry, len(r.services))
+	copy(services, r.services)
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(services) - 1; i >= 0; i-- {
+		st, ok := services[i].svc.(Stoppable)
+		if !ok {
+			continue
+		}
+		if err := stopService(ctx, st); err != nil {
+			errs = append(errs, fmt.Errorf("stop service %s: %w", services[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopService waits for service to stop until context is done
+func stopService(ctx context.Context, st Stoppable) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var done = make(chan error, 1)
+	go func() { done <- st.Stop() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stopLog keeps names of stopped services
+type stopLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (l *stopLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.names, ",")
+}
+
+type stopRecorder struct {
+	name    string
+	stopped *stopLog
+	err     error
+	delay   time.Duration
+}
+
+func (s *stopRecorder) Stop() error {
+	time.Sleep(s.delay)
+	s.stopped.mu.Lock()
+	defer s.stopped.mu.Unlock()
+	s.stopped.names = append(s.stopped.names, s.name)
+	return s.err
+}
+
+func TestStopAllServices(t *testing.T) {
+	var stopped = &stopLog{}
+	var failure = errors.New("stop failure")
+	var r = NewRegistry()
+	r.Register("vault", &stopRecorder{name: "vault", stopped: stopped})
+	r.Register("config", struct{}{})
+	r.Register("pool", &stopRecorder{name: "pool", stopped: stopped, err: failure})
+	r.Register("host", &stopRecorder{name: "host", stopped: stopped})
+
+	var err = r.StopAllServices(context.Background())
+	if stopped.String() != "host,pool,vault" {
+		t.Errorf("Services should stop in reverse order, have %v", stopped)
+	}
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "pool") {
+		t.Errorf("Error should name failed service, have %v", err)
+	}
+
+	// services left after deadline are not stopped
+	stopped = &stopLog{}
+	r = NewRegistry()
+	r.Register("vault", &stopRecorder{name: "vault", stopped: stopped})
+	r.Register("host", &stopRecorder{name: "host", stopped: stopped, delay: 100 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = r.StopAllServices(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "vault") {
+		t.Errorf("Expected deadline errors, have %v", err)
+	}
+	if stopped.String() != "" {
+		t.Errorf("Services should not be stopped after deadline, have %v", stopped)
+	}
+}
+
+type healthStub struct {
+	err error
+}
+
+func (s healthStub) HealthCheck() error { return s.err }
+
+func TestAggregateHealth(t *testing.T) {
+	var down = errors.New("bootstrap is not connected")
+	var r = NewRegistry()
+	r.Register("vault", healthStub{})
+	r.Register("config", struct{}{})
+	r.Register("host", healthStub{err: down})
+
+	var health = r.AggregateHealth()
+	if len(health) != 2 || health["vault"] != nil || health["host"] != down {
+		t.Errorf("Unexpected health of services: %v", health)
+	}
+
+	var rec = httptest.NewRecorder()
+	HealthHandler(r)(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var report HealthReport
+	json.Unmarshal(rec.Body.Bytes(), &report)
+	if rec.Code != http.StatusServiceUnavailable || report.Status != HealthUnhealthy || report.Services["host"] != down.Error() {
+		t.Errorf("Unhealthy node should answer 503, have %d %+v", rec.Code, report)
+	}
+
+	r = NewRegistry()
+	r.Register("vault", healthStub{})
+	rec = httptest.NewRecorder()
+	HealthHandler(r)(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ok"`) {
+		t.Errorf("Healthy node should answer 200, have %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,46 +1,220 @@
 package storage
 
 import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/cerera/internal/cerera/config"
 	"github.com/cerera/internal/cerera/types"
 	"github.com/tyler-smith/go-bip32"
 )
 
+// default count of account trie shards
+const DefaultTrieShards = config.DefaultVaultShards
+
+// part of accounts with own lock, so operations
+// with accounts of different shards do not contend
+type accountsShard struct {
+	mu       sync.RWMutex
+	balance  sync.Mutex // held while balance of account of shard is read and changed
+	accounts map[types.Address]types.StateAccount
+}
+
 // structure stores account and other accounting stuff
 // in smth like merkle-b-tree (cool data structure)
 type AccountsTrie struct {
-	accounts map[types.Address]types.StateAccount
+	shards []*accountsShard
 }
 
 func GetAccountsTrie() *AccountsTrie {
 	// this smth like init function
-	return &AccountsTrie{
-		accounts: make(map[types.Address]types.StateAccount),
+	return NewAccountsTrie(DefaultTrieShards)
+}
+
+// NewAccountsTrie creates trie split into n shards by first byte of address.
+func NewAccountsTrie(n int) *AccountsTrie {
+	if n < 1 {
+		n = 1
+	}
+	if n > 256 {
+		n = 256
+	}
+	var at = &AccountsTrie{
+		shards: make([]*accountsShard, n),
+	}
+	for i := range at.shards {
+		at.shards[i] = &accountsShard{
+			accounts: make(map[types.Address]types.StateAccount),
+		}
+	}
+	return at
+}
+
+func (at *AccountsTrie) shard(addr types.Address) *accountsShard {
+	return at.shards[int(addr[0])%len(at.shards)]
+}
+
+// lockBalances locks balance changes of shards of addresses. Shards are
+// locked in order of their index, so transfers in opposite directions do not
+// deadlock. Returned func unlocks them.
+func (at *AccountsTrie) lockBalances(a, b types.Address) func() {
+	var i, j = int(a[0]) % len(at.shards), int(b[0]) % len(at.shards)
+	if i > j {
+		i, j = j, i
+	}
+	at.shards[i].balance.Lock()
+	if i == j {
+		return at.shards[i].balance.Unlock
+	}
+	at.shards[j].balance.Lock()
+	return func() {
+		at.shards[j].balance.Unlock()
+		at.shards[i].balance.Unlock()
 	}
 }
 
 // add account with address to Account Tree
 func (at *AccountsTrie) Append(addr types.Address, sa types.StateAccount) {
-	at.accounts[addr] = sa
+	var s = at.shard(addr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[addr] = sa
 }
 
 func (at *AccountsTrie) Clear() error {
-	at.accounts = make(map[types.Address]types.StateAccount)
+	for _, s := range at.shards {
+		s.mu.Lock()
+		s.accounts = make(map[types.Address]types.StateAccount)
+		s.mu.Unlock()
+	}
 	return nil
 }
 
+// Replace swaps all accounts of trie at once, readers see either old or new accounts.
+func (at *AccountsTrie) Replace(accounts []types.StateAccount) {
+	var maps = make([]map[types.Address]types.StateAccount, len(at.shards))
+	for i := range maps {
+		maps[i] = make(map[types.Address]types.StateAccount)
+	}
+	for _, sa := range accounts {
+		maps[int(sa.Address[0])%len(at.shards)][sa.Address] = sa
+	}
+	for _, s := range at.shards {
+		s.mu.Lock()
+	}
+	for i, s := range at.shards {
+		s.accounts = maps[i]
+	}
+	for _, s := range at.shards {
+		s.mu.Unlock()
+	}
+}
+
 func (at *AccountsTrie) GetAccount(addr types.Address) types.StateAccount {
-	return at.accounts[addr]
+	var s = at.shard(addr)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accounts[addr]
 }
 
-func (at *AccountsTrie) GetKBytes(pubKey *bip32.Key) []byte {
-	for _, account := range at.accounts {
-		if pubKey.B58Serialize() == account.MPub {
-			return account.CodeHash
+// GetAccountOk returns account and whether it exists.
+func (at *AccountsTrie) GetAccountOk(addr types.Address) (types.StateAccount, bool) {
+	var s = at.shard(addr)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sa, ok := s.accounts[addr]
+	return sa, ok
+}
+
+// GetAccounts returns accounts of addresses read under locks of all shards
+// at once, so result is consistent. Unknown addresses are not in result.
+func (at *AccountsTrie) GetAccounts(addrs []types.Address) map[types.Address]types.StateAccount {
+	for _, s := range at.shards {
+		s.mu.RLock()
+	}
+	defer func() {
+		for _, s := range at.shards {
+			s.mu.RUnlock()
+		}
+	}()
+	var res = make(map[types.Address]types.StateAccount, len(addrs))
+	for _, addr := range addrs {
+		if sa, ok := at.shard(addr).accounts[addr]; ok {
+			res[addr] = sa
 		}
 	}
-	return nil
+	return res
+}
+
+// snapshot returns all accounts of all shards sorted by address bytes.
+func (at *AccountsTrie) snapshot() []types.StateAccount {
+	var res = make([]types.StateAccount, 0)
+	for _, s := range at.shards {
+		s.mu.RLock()
+		for _, sa := range s.accounts {
+			res = append(res, sa)
+		}
+		s.mu.RUnlock()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].Address[:], res[j].Address[:]) < 0
+	})
+	return res
+}
+
+// GetAll returns all accounts sorted by address bytes,
+// so order is the same between calls.
+func (at *AccountsTrie) GetAll() []types.StateAccount {
+	return at.snapshot()
+}
+
+// ForEach calls fn for every account in order of address bytes
+// until fn returns false.
+func (at *AccountsTrie) ForEach(fn func(sa types.StateAccount) bool) {
+	for _, sa := range at.snapshot() {
+		if !fn(sa) {
+			return
+		}
+	}
+}
+
+// Addresses returns addresses of all accounts sorted by address bytes.
+// Each shard is locked only while its keys are copied.
+func (at *AccountsTrie) Addresses() []types.Address {
+	var res = make([]types.Address, 0)
+	for _, s := range at.shards {
+		s.mu.RLock()
+		for addr := range s.accounts {
+			res = append(res, addr)
+		}
+		s.mu.RUnlock()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i][:], res[j][:]) < 0
+	})
+	return res
+}
+
+func (at *AccountsTrie) GetKBytes(pubKey *bip32.Key) []byte {
+	var res []byte
+	var mPub = pubKey.B58Serialize()
+	at.ForEach(func(account types.StateAccount) bool {
+		if mPub == account.MPub {
+			res = account.CodeHash
+			return false
+		}
+		return true
+	})
+	return res
 }
 
 func (at *AccountsTrie) Size() int {
-	return len(at.accounts)
+	var size = 0
+	for _, s := range at.shards {
+		s.mu.RLock()
+		size += len(s.accounts)
+		s.mu.RUnlock()
+	}
+	return size
 }
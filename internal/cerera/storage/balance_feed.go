@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// balanceFeed delivers new balances of accounts to subscribers
+type balanceFeed struct {
+	mu   sync.Mutex
+	subs map[types.Address][]chan *big.Int
+}
+
+func (f *balanceFeed) subscribe(addr types.Address) (<-chan *big.Int, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[types.Address][]chan *big.Int)
+	}
+	var ch = make(chan *big.Int, 1)
+	f.subs[addr] = append(f.subs[addr], ch)
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { f.unsubscribe(addr, ch) })
+	}
+}
+
+func (f *balanceFeed) unsubscribe(addr types.Address, ch chan *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var subs = f.subs[addr]
+	for i := range subs {
+		if subs[i] == ch {
+			f.subs[addr] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(f.subs[addr]) == 0 {
+		delete(f.subs, addr)
+	}
+	close(ch)
+}
+
+func (f *balanceFeed) send(addr types.Address, balance *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs[addr] {
+		// slow subscriber misses update
+		select {
+		case ch <- copyBalance(balance):
+		default:
+		}
+	}
+}
+
+// SubscribeBalance returns channel which receives new balance of address after
+// each change and function to unsubscribe. Updates are dropped while channel is full.
+func (v *D5Vault) SubscribeBalance(addr types.Address) (<-chan *big.Int, func()) {
+	return v.balances.subscribe(addr)
+}
+
+// notifyBalance should be called by every path which changes balance
+func (v *D5Vault) notifyBalance(addr types.Address, balance *big.Int) {
+	v.balances.send(addr, balance)
+}
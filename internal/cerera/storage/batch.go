@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// accountDelta is change of account made through batch
+type accountDelta struct {
+	balance *big.Int      // added to balance, negative for spent coins
+	nonce   uint64        // count of nonce increments
+	inputs  []common.Hash // inputs added to account
+}
+
+func (d *accountDelta) copy() *accountDelta {
+	return &accountDelta{
+		balance: copyBalance(d.balance),
+		nonce:   d.nonce,
+		inputs:  append([]common.Hash(nil), d.inputs...),
+	}
+}
+
+// VaultBatch defers writes of vault file while block is applied. Accounts
+// in memory change at once, changed accounts are written by one rewrite of
+// vault file on Commit instead of one rewrite per account.
+// Batch belongs to its caller: only changes made through methods of batch
+// are deferred and rolled back, changes made meanwhile by other callers of
+// vault are kept.
+type VaultBatch struct {
+	v      *D5Vault
+	deltas map[types.Address]*accountDelta // changes of accounts, for rollback
+	minted *big.Int                        // coins created in batch
+	done   bool                            // batch is committed or rolled back
+}
+
+// BeginBatch starts batch, Transfer and Credit of batch do not write vault
+// file until batch is committed or rolled back.
+func (v *D5Vault) BeginBatch() *VaultBatch {
+	return &VaultBatch{v: v, deltas: make(map[types.Address]*accountDelta)}
+}
+
+// record adds change of account to batch, called under balanceMu or under
+// locks of shards of account, batch is used by one goroutine
+func (b *VaultBatch) record(addr types.Address, balance *big.Int, nonce uint64, input common.Hash) {
+	var d, ok = b.deltas[addr]
+	if !ok {
+		d = &accountDelta{balance: big.NewInt(0)}
+		b.deltas[addr] = d
+	}
+	d.balance.Add(d.balance, balance)
+	d.nonce += nonce
+	if input != (common.Hash{}) {
+		d.inputs = append(d.inputs, input)
+	}
+}
+
+// Transfer moves cnt like D5Vault.Transfer, vault file is written on Commit.
+func (b *VaultBatch) Transfer(from, to types.Address, cnt *big.Int, txHash common.Hash) error {
+	return b.v.transfer(b, from, to, cnt, txHash)
+}
+
+// Credit adds newly created coins to account, e.g. reward of block.
+// Vault file and minted supply are written on Commit.
+func (b *VaultBatch) Credit(to types.Address, val *big.Int) error {
+	if val == nil || val.Sign() < 0 {
+		return ErrNegativeAmount
+	}
+	var v = b.v
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	var destSA = v.Get(to)
+	var newDest = destSA
+	newDest.Address = to
+	newDest.Balance = new(big.Int).Add(copyBalance(destSA.Balance), val)
+	b.record(to, val, 0, common.Hash{})
+	b.minted = new(big.Int).Add(copyBalance(b.minted), val)
+	v.supply.add(val)
+	v.history.touch(to, destSA.Balance)
+	v.accounts.Append(to, newDest)
+	v.notifyBalance(to, newDest.Balance)
+	return nil
+}
+
+// Commit writes accounts changed in batch to vault file. If write fails,
+// changes of batch are rolled back and error is returned.
+func (b *VaultBatch) Commit() error {
+	var v = b.v
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	if b.done {
+		return nil
+	}
+	b.done = true
+	if v.inMem || len(b.deltas) == 0 {
+		return nil
+	}
+	var err = replaceVault(v.accounts.GetAll())
+	v.breaker.record(err)
+	if err != nil {
+		v.unapply(b.deltas, b.minted)
+		return err
+	}
+	if b.minted != nil {
+		if err := writeSupply(v.supply.get()); err != nil {
+			fmt.Printf("Failed to save minted supply: %s\r\n", err)
+		}
+	}
+	return nil
+}
+
+// Rollback takes back changes made through batch. Accounts changed by other
+// callers meanwhile may be written with changes of batch, so vault file is
+// rewritten.
+func (b *VaultBatch) Rollback() {
+	var v = b.v
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	if b.done {
+		return
+	}
+	b.done = true
+	if len(b.deltas) == 0 {
+		return
+	}
+	v.unapply(b.deltas, b.minted)
+	if v.inMem {
+		return
+	}
+	var err = replaceVault(v.accounts.GetAll())
+	v.breaker.record(err)
+	if err != nil {
+		fmt.Printf("Failed to write vault after rollback: %s\r\n", err)
+	}
+}
+
+// unapply subtracts changes of accounts and minted coins, called under balanceMu
+func (v *D5Vault) unapply(deltas map[types.Address]*accountDelta, minted *big.Int) {
+	for addr, d := range deltas {
+		var sa = v.Get(addr)
+		v.history.touch(addr, sa.Balance)
+		var prev = sa
+		prev.Address = addr
+		prev.Balance = new(big.Int).Sub(copyBalance(sa.Balance), d.balance)
+		if prev.Nonce >= d.nonce {
+			prev.Nonce -= d.nonce
+		} else {
+			prev.Nonce = 0
+		}
+		prev.Inputs = removeInputs(sa.Inputs, d.inputs)
+		v.accounts.Append(addr, prev)
+		v.notifyBalance(addr, prev.Balance)
+	}
+	if minted != nil && minted.Sign() != 0 {
+		v.mint(new(big.Int).Neg(minted))
+	}
+}
+
+// removeInputs returns copy of inputs without last occurrence of each of removed
+func removeInputs(inputs []common.Hash, removed []common.Hash) []common.Hash {
+	if len(removed) == 0 {
+		return inputs
+	}
+	var res = append([]common.Hash(nil), inputs...)
+	for _, h := range removed {
+		for i := len(res) - 1; i >= 0; i-- {
+			if res[i] == h {
+				res = append(res[:i], res[i+1:]...)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// BlockUndo keeps changes of accounts made by applied block and coins
+// minted by block, so block may be reverted when chain reorgs.
+type BlockUndo struct {
+	accounts map[types.Address]*accountDelta
+	minted   *big.Int
+}
+
+// Undo returns changes of batch which are needed to revert it after commit.
+func (b *VaultBatch) Undo() *BlockUndo {
+	var v = b.v
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	var u = &BlockUndo{
+		accounts: make(map[types.Address]*accountDelta, len(b.deltas)),
+		minted:   copyBalance(b.minted),
+	}
+	for addr, d := range b.deltas {
+		u.accounts[addr] = d.copy()
+	}
+	return u
+}
+
+// Revert takes back changes of accounts made by block and coins minted by
+// it, changes made by others are kept. Vault file is rewritten once.
+func (v *D5Vault) Revert(u *BlockUndo) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	v.unapply(u.accounts, u.minted)
+	if v.inMem || len(u.accounts) == 0 {
+		return nil
+	}
+	var err = replaceVault(v.accounts.GetAll())
+	v.breaker.record(err)
+	return err
+}
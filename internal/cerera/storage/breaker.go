@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// count of consecutive write failures which switch vault to read only mode
+var BreakerThreshold = 5
+
+var ErrVaultReadOnly = errors.New("vault is in read only mode after write failures")
+
+var (
+	vaultWriteFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_write_failures_total",
+			Help: "Count failed writes to vault file",
+		},
+	)
+	vaultReadOnly = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_read_only",
+			Help: "Vault stopped accepting mutations after write failures",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(vaultWriteFailures, vaultReadOnly)
+}
+
+// vault file writes, replaced in tests to inject failures
+var (
+	saveAccount   = SaveToVault
+	updateAccount = UpdateVault
+	replaceVault  = ReplaceVault
+)
+
+// writeBreaker stops mutations of vault when writes to file fail
+// several times in a row, so memory does not diverge from disk.
+type writeBreaker struct {
+	mu       sync.Mutex
+	failures int
+	open     bool
+}
+
+func (b *writeBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		return ErrVaultReadOnly
+	}
+	return nil
+}
+
+func (b *writeBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+func (b *writeBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	vaultWriteFailures.Inc()
+	b.failures++
+	if b.failures >= BreakerThreshold && !b.open {
+		b.open = true
+		vaultReadOnly.Set(1)
+		fmt.Printf("ALERT! Vault switched to read only mode after %d write failures: %s\r\n", b.failures, err)
+	}
+}
+
+func (b *writeBreaker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.open = false
+	vaultReadOnly.Set(0)
+}
+
+// ReadOnly reports whether vault stopped accepting mutations.
+func (v *D5Vault) ReadOnly() bool {
+	return v.breaker.isOpen()
+}
+
+// HealthCheck returns ErrVaultReadOnly while vault rejects mutations,
+// otherwise error of opening vault file for writing.
+func (v *D5Vault) HealthCheck() error {
+	if v.breaker.isOpen() {
+		return ErrVaultReadOnly
+	}
+	return v.checkWritable()
+}
+
+// Recover checks that vault file is writable again and switches vault back
+// from read only mode, operator calls it after cause of failures is fixed.
+func (v *D5Vault) Recover() error {
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+	v.breaker.reset()
+	fmt.Printf("Vault accepts mutations again\r\n")
+	return nil
+}
+
+func (v *D5Vault) checkWritable() error {
+	if v.inMem {
+		return nil
+	}
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
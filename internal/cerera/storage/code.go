@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	ErrCodeNotFound     = errors.New("contract code not found")
+	ErrCodeHashMismatch = errors.New("contract code hash mismatch")
+)
+
+// file with contract code, one json record per line, latest record wins
+const CodeFilePath = "./code.dat"
+
+// contract code file access, replaced in tests to count reads
+var (
+	readCode  = ReadCodeFile
+	writeCode = WriteCodeFile
+)
+
+type codeRecord struct {
+	Address types.Address `json:"address"`
+	Code    []byte        `json:"code"`
+	Hash    common.Hash   `json:"hash"`
+}
+
+func codeHash(code []byte) common.Hash {
+	return common.Hash(blake2b.Sum256(code))
+}
+
+// ReadCodeFile looks for latest code record of address in code file.
+func ReadCodeFile(addr types.Address) ([]byte, common.Hash, error) {
+	file, err := os.OpenFile(CodeFilePath, os.O_RDONLY, 0644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, common.Hash{}, ErrCodeNotFound
+		}
+		return nil, common.Hash{}, fmt.Errorf("failed to open the code file: %w", err)
+	}
+	defer file.Close()
+
+	var found *codeRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec codeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if rec.Address == addr {
+			found = &rec
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, common.Hash{}, fmt.Errorf("failed to read the code file: %w", err)
+	}
+	if found == nil {
+		return nil, common.Hash{}, ErrCodeNotFound
+	}
+	return found.Code, found.Hash, nil
+}
+
+// WriteCodeFile appends code record of address to code file.
+func WriteCodeFile(addr types.Address, code []byte) error {
+	f, err := os.OpenFile(CodeFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(codeRecord{Address: addr, Code: code, Hash: codeHash(code)})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// memCodeStore keeps contract code of in-memory vault
+type memCodeStore struct {
+	mu      sync.RWMutex
+	records map[types.Address]codeRecord
+}
+
+func (m *memCodeStore) read(addr types.Address) ([]byte, common.Hash, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec, ok := m.records[addr]
+	if !ok {
+		return nil, common.Hash{}, ErrCodeNotFound
+	}
+	return rec.Code, rec.Hash, nil
+}
+
+func (m *memCodeStore) write(addr types.Address, code []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.records == nil {
+		m.records = make(map[types.Address]codeRecord)
+	}
+	var cpy = types.CopyBytes(code)
+	m.records[addr] = codeRecord{Address: addr, Code: cpy, Hash: codeHash(cpy)}
+	return nil
+}
+
+var (
+	codeCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_code_cache_hits_total",
+			Help: "Count contract code reads served from cache",
+		},
+	)
+	codeCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_code_cache_misses_total",
+			Help: "Count contract code reads which went to code store",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(codeCacheHits, codeCacheMisses)
+}
+
+// codeCache is lru cache of verified contract code
+type codeCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is most recently used
+	items map[types.Address]*list.Element
+}
+
+type codeEntry struct {
+	addr types.Address
+	code []byte
+}
+
+func newCodeCache(size int) *codeCache {
+	if size < 1 {
+		size = 1
+	}
+	return &codeCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[types.Address]*list.Element),
+	}
+}
+
+func (c *codeCache) get(addr types.Address) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[addr]; ok {
+		c.order.MoveToFront(el)
+		codeCacheHits.Inc()
+		return el.Value.(*codeEntry).code, true
+	}
+	codeCacheMisses.Inc()
+	return nil, false
+}
+
+func (c *codeCache) add(addr types.Address, code []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[addr]; ok {
+		el.Value.(*codeEntry).code = code
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[addr] = c.order.PushFront(&codeEntry{addr: addr, code: code})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*codeEntry).addr)
+	}
+}
+
+func (c *codeCache) remove(addr types.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[addr]; ok {
+		c.order.Remove(el)
+		delete(c.items, addr)
+	}
+}
+
+// GetContractCode returns code of contract. Code is read from code file
+// (or memory in in-memory mode) once and its hash is checked on cache fill,
+// next calls are served from cache.
+func (v *D5Vault) GetContractCode(addr types.Address) ([]byte, error) {
+	if code, ok := v.code.get(addr); ok {
+		return code, nil
+	}
+	var read = readCode
+	if v.inMem {
+		read = v.memCode.read
+	}
+	code, hash, err := read(addr)
+	if err != nil {
+		return nil, err
+	}
+	if codeHash(code) != hash {
+		return nil, ErrCodeHashMismatch
+	}
+	v.code.add(addr, code)
+	return code, nil
+}
+
+// StoreContractCode writes code of contract and drops cached one.
+func (v *D5Vault) StoreContractCode(addr types.Address, code []byte) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	v.code.remove(addr)
+	if v.inMem {
+		return v.memCode.write(addr, code)
+	}
+	err := writeCode(addr, code)
+	v.breaker.record(err)
+	return err
+}
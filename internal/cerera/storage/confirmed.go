@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// count of blocks for which balance changes of accounts are kept
+var BalanceHistoryDepth = 64
+
+type balancePoint struct {
+	height  int
+	balance *big.Int
+}
+
+// balanceHistory keeps balances of changed accounts by block height,
+// so balance may be read as it was some blocks ago
+type balanceHistory struct {
+	mu     sync.Mutex
+	height int // height of latest applied block
+	dirty  map[types.Address]struct{}
+	points map[types.Address][]balancePoint
+}
+
+// touch remembers account which balance is going to change in current block.
+// First change of account stores its balance before change as baseline.
+func (h *balanceHistory) touch(addr types.Address, before *big.Int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.dirty == nil {
+		h.dirty = make(map[types.Address]struct{})
+		h.points = make(map[types.Address][]balancePoint)
+	}
+	if _, ok := h.points[addr]; !ok {
+		h.points[addr] = []balancePoint{{height: h.height, balance: copyBalance(before)}}
+	}
+	h.dirty[addr] = struct{}{}
+}
+
+func copyBalance(b *big.Int) *big.Int {
+	if b == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(b)
+}
+
+// CommitHeight stores balances changed by applied block of height
+// and drops history deeper than BalanceHistoryDepth.
+func (v *D5Vault) CommitHeight(height int) {
+	var h = &v.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.height = height
+	for addr := range h.dirty {
+		h.points[addr] = append(h.points[addr], balancePoint{height: height, balance: copyBalance(v.Get(addr).Balance)})
+		delete(h.dirty, addr)
+	}
+	var cutoff = height - BalanceHistoryDepth
+	for addr, points := range h.points {
+		// keep last point before cutoff, it is balance at cutoff
+		var i = sort.Search(len(points), func(i int) bool { return points[i].height > cutoff })
+		if i > 1 {
+			h.points[addr] = points[i-1:]
+		}
+	}
+}
+
+// RewindHistory drops balances stored above height after blocks above it were
+// reverted, so balances of replaced blocks are not confirmed later. Balances
+// of reverted accounts are back to height, they are not stored again.
+func (v *D5Vault) RewindHistory(height int) {
+	var h = &v.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.height = height
+	for addr := range h.dirty {
+		delete(h.dirty, addr)
+	}
+	for addr, points := range h.points {
+		var i = sort.Search(len(points), func(i int) bool { return points[i].height > height })
+		if i == 0 {
+			// account was not changed up to height
+			delete(h.points, addr)
+		} else {
+			h.points[addr] = points[:i]
+		}
+	}
+}
+
+// ConfirmedBalance returns balance of account as of block which has
+// minConf confirmations, so funds which may be reorged away are not counted.
+// Zero is returned when history of account is not that deep.
+func (v *D5Vault) ConfirmedBalance(addr types.Address, minConf int) *big.Int {
+	var current = copyBalance(v.Get(addr).Balance)
+	if minConf <= 0 {
+		return current
+	}
+	var h = &v.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var points, ok = h.points[addr]
+	if !ok {
+		// balance did not change while history is kept
+		return current
+	}
+	var target = h.height - minConf
+	var i = sort.Search(len(points), func(i int) bool { return points[i].height > target })
+	if i == 0 {
+		return big.NewInt(0)
+	}
+	return copyBalance(points[i-1].balance)
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+var (
+	ErrStorageNotPersisted = errors.New("contract storage is available in in-memory mode only")
+	ErrInvalidStorageKey   = errors.New("storage key should fit into 32 bytes")
+	ErrInvalidRangeLimit   = errors.New("range limit should be positive")
+)
+
+// memStorage keeps contract storage of in-memory vault,
+// slots are keyed by 32-byte big-endian key
+type memStorage struct {
+	mu    sync.RWMutex
+	slots map[types.Address]map[string]*big.Int
+}
+
+func (m *memStorage) get(addr types.Address, key common.Hash) *big.Int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if val, ok := m.slots[addr][string(key[:])]; ok {
+		return new(big.Int).Set(val)
+	}
+	return big.NewInt(0)
+}
+
+func (m *memStorage) set(addr types.Address, key common.Hash, val *big.Int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// zero value clears slot
+	if val == nil || val.Sign() == 0 {
+		if slots, ok := m.slots[addr]; ok {
+			delete(slots, string(key[:]))
+			if len(slots) == 0 {
+				delete(m.slots, addr)
+			}
+		}
+		return
+	}
+	if m.slots == nil {
+		m.slots = make(map[types.Address]map[string]*big.Int)
+	}
+	if m.slots[addr] == nil {
+		m.slots[addr] = make(map[string]*big.Int)
+	}
+	m.slots[addr][string(key[:])] = new(big.Int).Set(val)
+}
+
+// keys returns keys of contract slots not less than start, sorted
+func (m *memStorage) keys(addr types.Address, start string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res = make([]string, 0)
+	for k := range m.slots[addr] {
+		// keys have same length, so string order is order of numbers
+		if k >= start {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// GetStorage returns value of storage slot of contract, zero if slot is not set.
+func (v *D5Vault) GetStorage(addr types.Address, key common.Hash) *big.Int {
+	if !v.inMem {
+		return big.NewInt(0)
+	}
+	return v.storage.get(addr, key)
+}
+
+// SetStorage sets value of storage slot of contract, zero value clears slot.
+// Storage lives for lifetime of process, file vault does not persist it yet.
+func (v *D5Vault) SetStorage(addr types.Address, key common.Hash, val *big.Int) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	if !v.inMem {
+		return ErrStorageNotPersisted
+	}
+	v.storage.set(addr, key, val)
+	return nil
+}
+
+// GetStorageRange returns up to limit slots of contract with keys not less than startKey,
+// sorted by key. Keys of result are hex of 32-byte keys. Next key is cursor for next
+// page, nil when there are no more slots. Nil startKey means first slot.
+func (v *D5Vault) GetStorageRange(address types.Address, startKey *big.Int, limit int) (map[string]*big.Int, *big.Int, error) {
+	if limit <= 0 {
+		return nil, nil, ErrInvalidRangeLimit
+	}
+	var start common.Hash
+	if startKey != nil {
+		if startKey.Sign() < 0 || startKey.BitLen() > 8*common.HashLength {
+			return nil, nil, ErrInvalidStorageKey
+		}
+		startKey.FillBytes(start[:])
+	}
+	if !v.inMem {
+		return nil, nil, ErrStorageNotPersisted
+	}
+
+	var keys = v.storage.keys(address, string(start[:]))
+	var res = make(map[string]*big.Int, limit)
+	var next *big.Int
+	for i, k := range keys {
+		if i == limit {
+			next = new(big.Int).SetBytes([]byte(k))
+			break
+		}
+		var key = common.BytesToHash([]byte(k))
+		// slot could be cleared after keys were read
+		if val := v.storage.get(address, key); val.Sign() != 0 {
+			res[key.Hex()] = val
+		}
+	}
+	return res, next, nil
+}
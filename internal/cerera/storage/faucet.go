@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// faucet limits, value is requested in coins
+var (
+	FaucetCooldown = 24 * time.Hour
+	FaucetMinValue = types.FloatToBigInt(1.0)
+	FaucetMaxValue = types.FloatToBigInt(1000.0)
+)
+
+var (
+	ErrFaucetCooldown = errors.New("faucet cooldown is not over for address")
+	ErrFaucetValue    = errors.New("faucet value is out of allowed range")
+)
+
+// FaucetStatus describes whether address can request faucet now.
+type FaucetStatus struct {
+	Address   types.Address `json:"address"`
+	Eligible  bool          `json:"eligible"`
+	Remaining float64       `json:"remaining"` // seconds of cooldown left
+	Min       *big.Int      `json:"min"`
+	Max       *big.Int      `json:"max"`
+}
+
+// remaining cooldown of address, should be called under faucetMu
+func (v *D5Vault) faucetCooldown(addr types.Address) time.Duration {
+	last, ok := v.faucetTimes[addr]
+	if !ok {
+		return 0
+	}
+	var cooldown = FaucetCooldown
+	if d, ok := v.faucetOverrides[addr]; ok {
+		cooldown = d
+	}
+	var remaining = cooldown - time.Since(last)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// FaucetStatus reports faucet eligibility of address without dispensing.
+func (v *D5Vault) FaucetStatus(addr types.Address) FaucetStatus {
+	v.faucetMu.Lock()
+	defer v.faucetMu.Unlock()
+	var remaining = v.faucetCooldown(addr)
+	return FaucetStatus{
+		Address:   addr,
+		Eligible:  remaining == 0,
+		Remaining: remaining.Seconds(),
+		Min:       new(big.Int).Set(FaucetMinValue),
+		Max:       new(big.Int).Set(FaucetMaxValue),
+	}
+}
+
+// checkFaucet checks limits of faucet for address and value.
+func (v *D5Vault) checkFaucet(addr types.Address, val *big.Int) error {
+	if val.Cmp(FaucetMinValue) < 0 || val.Cmp(FaucetMaxValue) > 0 {
+		return ErrFaucetValue
+	}
+	v.faucetMu.Lock()
+	defer v.faucetMu.Unlock()
+	if v.faucetCooldown(addr) > 0 {
+		return ErrFaucetCooldown
+	}
+	return nil
+}
+
+// markFaucet starts cooldown of address after faucet is dispensed.
+func (v *D5Vault) markFaucet(addr types.Address) {
+	v.faucetMu.Lock()
+	defer v.faucetMu.Unlock()
+	if v.faucetTimes == nil {
+		v.faucetTimes = make(map[types.Address]time.Time)
+	}
+	v.faucetTimes[addr] = time.Now()
+}
+
+// SetFaucetCooldownOverride sets own faucet cooldown of address instead of FaucetCooldown,
+// zero duration means no cooldown.
+func (v *D5Vault) SetFaucetCooldownOverride(addr types.Address, d time.Duration) {
+	v.faucetMu.Lock()
+	defer v.faucetMu.Unlock()
+	if v.faucetOverrides == nil {
+		v.faucetOverrides = make(map[types.Address]time.Duration)
+	}
+	v.faucetOverrides[addr] = d
+}
+
+// ClearFaucetCooldownOverride returns address to default faucet cooldown.
+func (v *D5Vault) ClearFaucetCooldownOverride(addr types.Address) {
+	v.faucetMu.Lock()
+	defer v.faucetMu.Unlock()
+	delete(v.faucetOverrides, addr)
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+// upper bound of records in one page of history
+const MaxHistoryLimit = 100
+
+var (
+	ErrNoTxLocator    = errors.New("transactions of chain can not be located")
+	ErrHistoryLimit   = errors.New("invalid history limit")
+	ErrHistoryCursor  = errors.New("history cursor is not input of account")
+	ErrUnknownAccount = errors.New("unknown account")
+)
+
+// TxLocator finds tx of chain by hash with its sender and block including it
+type TxLocator interface {
+	LocateTransaction(hash common.Hash) (*types.GTransaction, types.Address, *block.Block, bool)
+}
+
+// TxRecord is incoming tx of account as it is shown in history
+type TxRecord struct {
+	Hash         common.Hash
+	Amount       *big.Int
+	Counterparty types.Address // sender of tx
+	Height       int           // height of block including tx
+	Timestamp    uint64        // time of block including tx, ms
+}
+
+// MarshalJSON encodes record in unified format: hashes and addresses are
+// hex, amount is decimal string.
+func (r TxRecord) MarshalJSON() ([]byte, error) {
+	var amount = "0"
+	if r.Amount != nil {
+		amount = r.Amount.String()
+	}
+	return json.Marshal(struct {
+		Hash         common.Hash   `json:"hash"`
+		Amount       string        `json:"amount"`
+		Counterparty types.Address `json:"counterparty"`
+		Height       int           `json:"height"`
+		Timestamp    uint64        `json:"timestamp"`
+	}{r.Hash, amount, r.Counterparty, r.Height, r.Timestamp})
+}
+
+// SetTxLocator sets source of txs and blocks used by TransactionHistory.
+func (v *D5Vault) SetTxLocator(l TxLocator) {
+	v.locator = l
+}
+
+// TransactionHistory returns incoming txs of account newest first, at most
+// limit of them. Page starts after input cursor, empty cursor starts from
+// newest tx; hash of last record is cursor of next page. Inputs which are
+// not found in chain are skipped.
+func (v *D5Vault) TransactionHistory(addr types.Address, cursor common.Hash, limit int) ([]TxRecord, error) {
+	if v.locator == nil {
+		return nil, ErrNoTxLocator
+	}
+	if limit <= 0 || limit > MaxHistoryLimit {
+		return nil, fmt.Errorf("%w: %d, max %d", ErrHistoryLimit, limit, MaxHistoryLimit)
+	}
+	var sa = v.GetCopy(addr)
+	if sa == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, addr)
+	}
+
+	var records = make([]TxRecord, 0, len(sa.Inputs))
+	for _, h := range sa.Inputs {
+		tx, from, b, ok := v.locator.LocateTransaction(h)
+		if !ok {
+			continue
+		}
+		records = append(records, TxRecord{
+			Hash:         h,
+			Amount:       tx.Value(),
+			Counterparty: from,
+			Height:       b.Head.Height,
+			Timestamp:    b.Head.Timestamp,
+		})
+	}
+	// newest first, txs of one block in stable order
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Height != records[j].Height {
+			return records[i].Height > records[j].Height
+		}
+		return records[i].Hash.Compare(records[j].Hash) < 0
+	})
+
+	var start = 0
+	if cursor != (common.Hash{}) {
+		start = -1
+		for i := range records {
+			if records[i].Hash == cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrHistoryCursor, cursor)
+		}
+	}
+	var end = start + limit
+	if end > len(records) {
+		end = len(records)
+	}
+	return records[start:end], nil
+}
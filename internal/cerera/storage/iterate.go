@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+var ErrVaultNotInitialized = errors.New("vault is not initialized")
+
+// Iterate calls fn for every account in order of address bytes until fn returns false.
+// Only addresses are snapshotted, accounts are read one by one without holding
+// shard locks during the walk, so accounts changed meanwhile are seen in their latest
+// state and removed ones are skipped. Account passed to fn is a copy.
+func (v *D5Vault) Iterate(fn func(addr types.Address, acc *types.StateAccount) bool) error {
+	if v.accounts == nil {
+		return ErrVaultNotInitialized
+	}
+	for _, addr := range v.accounts.Addresses() {
+		sa, ok := v.accounts.GetAccountOk(addr)
+		if !ok {
+			continue
+		}
+		if !fn(addr, sa.Copy()) {
+			return nil
+		}
+	}
+	return nil
+}
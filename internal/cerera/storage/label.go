@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// max length of account label in bytes
+const MaxLabelLength = 64
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrLabelTooLong    = errors.New("account label is too long")
+)
+
+// SetLabel sets local label of account, empty label removes it.
+func (v *D5Vault) SetLabel(addr types.Address, label string) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	if len(label) > MaxLabelLength {
+		return ErrLabelTooLong
+	}
+	var sa = v.Get(addr)
+	if sa.Address != addr {
+		return ErrAccountNotFound
+	}
+	sa.Label = label
+	if !v.inMem {
+		var err = updateAccount(sa.Bytes())
+		v.breaker.record(err)
+		if err != nil {
+			return err
+		}
+	}
+	v.accounts.Append(addr, sa)
+	return nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/cerera/internal/cerera/types"
+	"golang.org/x/crypto/blake2b"
+)
+
+// snapshot format: magic, count of accounts (uint64), minted supply as
+// length (uint32) and big-endian bytes, accounts as length (uint32) and
+// Bytes() of account, blake2b-256 digest of all previous bytes
+var snapshotMagic = []byte("CRS2")
+
+// upper bound of encoded minted supply in snapshot
+const maxSnapshotSupplySize = 64
+
+// upper bound of one encoded account in snapshot
+const MaxSnapshotAccountSize = 1 << 20
+
+var (
+	ErrSnapshotFormat = errors.New("invalid vault snapshot format")
+	ErrSnapshotDigest = errors.New("vault snapshot digest mismatch")
+)
+
+// ExportSnapshot writes all accounts of vault and minted supply to w.
+func (v *D5Vault) ExportSnapshot(w io.Writer) error {
+	v.balanceMu.Lock()
+	var accounts, minted = v.accounts.GetAll(), v.supply.get()
+	v.balanceMu.Unlock()
+	hw, _ := blake2b.New256(nil)
+	bw := bufio.NewWriter(w)
+	out := io.MultiWriter(bw, hw)
+
+	var header = make([]byte, 8)
+	binary.BigEndian.PutUint64(header, uint64(len(accounts)))
+	if _, err := out.Write(append(append([]byte{}, snapshotMagic...), header...)); err != nil {
+		return err
+	}
+	var size = make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(minted.Bytes())))
+	if _, err := out.Write(append(size, minted.Bytes()...)); err != nil {
+		return err
+	}
+	for i := range accounts {
+		data := accounts[i].Bytes()
+		binary.BigEndian.PutUint32(size, uint32(len(data)))
+		if _, err := out.Write(size); err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.Write(hw.Sum(nil)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readSnapshot reads and verifies whole snapshot before returning accounts
+// and minted supply
+func readSnapshot(r io.Reader) ([]types.StateAccount, *big.Int, error) {
+	hw, _ := blake2b.New256(nil)
+	br := bufio.NewReader(r)
+	in := io.TeeReader(br, hw)
+
+	var header = make([]byte, len(snapshotMagic)+8)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, nil, fmt.Errorf("%w: header: %s", ErrSnapshotFormat, err)
+	}
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) {
+		return nil, nil, fmt.Errorf("%w: bad magic", ErrSnapshotFormat)
+	}
+	var count = binary.BigEndian.Uint64(header[len(snapshotMagic):])
+
+	var size = make([]byte, 4)
+	if _, err := io.ReadFull(in, size); err != nil {
+		return nil, nil, fmt.Errorf("%w: supply: %s", ErrSnapshotFormat, err)
+	}
+	if n := binary.BigEndian.Uint32(size); n > maxSnapshotSupplySize {
+		return nil, nil, fmt.Errorf("%w: supply is too large", ErrSnapshotFormat)
+	}
+	var supply = make([]byte, binary.BigEndian.Uint32(size))
+	if _, err := io.ReadFull(in, supply); err != nil {
+		return nil, nil, fmt.Errorf("%w: supply: %s", ErrSnapshotFormat, err)
+	}
+	var minted = new(big.Int).SetBytes(supply)
+
+	var accounts = make([]types.StateAccount, 0)
+	for i := uint64(0); i < count; i++ {
+		if _, err := io.ReadFull(in, size); err != nil {
+			return nil, nil, fmt.Errorf("%w: account %d: %s", ErrSnapshotFormat, i, err)
+		}
+		var n = binary.BigEndian.Uint32(size)
+		if n > MaxSnapshotAccountSize {
+			return nil, nil, fmt.Errorf("%w: account %d is too large", ErrSnapshotFormat, i)
+		}
+		var data = make([]byte, n)
+		if _, err := io.ReadFull(in, data); err != nil {
+			return nil, nil, fmt.Errorf("%w: account %d: %s", ErrSnapshotFormat, i, err)
+		}
+		sa, err := types.BytesToStateAccountSafe(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: account %d: %s", ErrSnapshotFormat, i, err)
+		}
+		accounts = append(accounts, *sa)
+	}
+
+	var digest = hw.Sum(nil)
+	var trailer = make([]byte, len(digest))
+	if _, err := io.ReadFull(br, trailer); err != nil {
+		return nil, nil, fmt.Errorf("%w: digest: %s", ErrSnapshotFormat, err)
+	}
+	if !bytes.Equal(trailer, digest) {
+		return nil, nil, ErrSnapshotDigest
+	}
+	return accounts, minted, nil
+}
+
+// ImportSnapshot replaces all accounts of vault and minted supply with the
+// ones of snapshot. Snapshot is verified completely before vault is changed,
+// balances of its accounts should sum to its minted supply. Vault file is
+// rewritten first, so memory is not changed if write fails.
+func (v *D5Vault) ImportSnapshot(r io.Reader) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	accounts, minted, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	var sum = big.NewInt(0)
+	for i := range accounts {
+		sum.Add(sum, copyBalance(accounts[i].Balance))
+	}
+	if err := checkSupply(sum, minted); err != nil {
+		return err
+	}
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
+	if !v.inMem {
+		var err = replaceVault(accounts)
+		v.breaker.record(err)
+		if err != nil {
+			return err
+		}
+	}
+	v.accounts.Replace(accounts)
+	for i := range accounts {
+		v.notifyBalance(accounts[i].Address, accounts[i].Balance)
+	}
+	v.supply.set(minted)
+	if !v.inMem {
+		if err := writeSupply(minted); err != nil {
+			fmt.Printf("Failed to save minted supply: %s\r\n", err)
+		}
+	}
+	_, err = v.VerifySupply()
+	return err
+}
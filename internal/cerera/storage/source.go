@@ -9,10 +9,21 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/cerera/internal/cerera/logger"
 	"github.com/cerera/internal/cerera/types"
 )
 
+// count of accounts between progress logs while vault sync
+var SyncProgressStep = 1000
+
+var syncLogger = logger.Named("vault")
+
+// serializes writes of vault file, transfers of different shards write it concurrently
+var fileMu sync.Mutex
+
 func encrypt(data []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -75,20 +86,35 @@ func SyncVault(path string) error {
 
 	scanner := bufio.NewScanner(file)
 	GetVault().Clear()
+	var start = time.Now()
+	var count, corrupted = 0, 0
 	for scanner.Scan() {
 		line := scanner.Bytes()
-		account := types.BytesToStateAccount(line)
-		GetVault().accounts.Append(account.Address, account)
+		account, err := types.BytesToStateAccountSafe(line)
+		if err != nil {
+			corrupted++
+			syncLogger.Warnf("Skip corrupted account at line %d of %s: %s", count+corrupted, path, err)
+			continue
+		}
+		GetVault().accounts.Append(account.Address, *account)
+		count++
+		if count%SyncProgressStep == 0 {
+			syncLogger.Infof("Read %d accounts from %s", count, path)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("failed to read account data from file: %w", err)
 	}
 
+	syncLogger.Infof("Synced %d accounts from %s in %s, corrupted %d", count, path, time.Since(start), corrupted)
+	GetVault().checkSyncedSupply()
 	return nil
 }
 
 func SaveToVault(account []byte) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
 	f, err := os.OpenFile("./vault.dat", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -111,6 +137,8 @@ func SaveToVault(account []byte) error {
 
 // UpdateVault updates an account in the vault file.
 func UpdateVault(account []byte) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
 	filePath := "./vault.dat"
 
 	// Read all accounts from the file
@@ -123,9 +151,11 @@ func UpdateVault(account []byte) error {
 	var accounts = make([]types.StateAccount, 0)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Bytes()
-		account := types.BytesToStateAccount(line)
-		accounts = append(accounts, account)
+		account, err := types.BytesToStateAccountSafe(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to parse account at line %d: %w", len(accounts)+1, err)
+		}
+		accounts = append(accounts, *account)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -133,10 +163,13 @@ func UpdateVault(account []byte) error {
 	}
 
 	// Update the specific account
-	updatedAccount := types.BytesToStateAccount(account)
+	updatedAccount, err := types.BytesToStateAccountSafe(account)
+	if err != nil {
+		return err
+	}
 	for i, acc := range accounts {
 		if acc.Address == updatedAccount.Address {
-			accounts[i] = updatedAccount
+			accounts[i] = *updatedAccount
 			break
 		}
 	}
@@ -174,3 +207,35 @@ func VaultSourceSize() (int64, error) {
 
 	return fi.Size(), nil
 }
+
+// ReplaceVault writes all accounts to new vault file and replaces old one,
+// so vault file is either old or complete new one.
+func ReplaceVault(accounts []types.StateAccount) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	filePath := "./vault.dat"
+	tmpPath := filePath + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create the vault file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	for _, acc := range accounts {
+		if _, err := writer.Write(append(acc.Bytes(), '\n')); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write to the vault file: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write to the vault file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+var ErrSupplyMismatch = errors.New("sum of balances differs from minted supply")
+
+// file with total minted supply of vault, decimal number
+const SupplyFilePath = "./supply.dat"
+
+// supply file access, replaced in tests
+var (
+	readSupply  = ReadSupplyFile
+	writeSupply = WriteSupplyFile
+)
+
+// ReadSupplyFile reads minted supply saved by WriteSupplyFile.
+func ReadSupplyFile() (*big.Int, error) {
+	data, err := os.ReadFile(SupplyFilePath)
+	if err != nil {
+		return nil, err
+	}
+	var minted, ok = new(big.Int).SetString(strings.TrimSpace(string(data)), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid supply file %s", SupplyFilePath)
+	}
+	return minted, nil
+}
+
+// WriteSupplyFile saves minted supply.
+func WriteSupplyFile(minted *big.Int) error {
+	var tmp = SupplyFilePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(minted.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, SupplyFilePath)
+}
+
+// supplyCounter tracks total of coins created in vault, transfers do not change it
+type supplyCounter struct {
+	mu     sync.Mutex
+	minted *big.Int
+}
+
+func (s *supplyCounter) add(delta *big.Int) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minted = new(big.Int).Add(copyBalance(s.minted), delta)
+	return new(big.Int).Set(s.minted)
+}
+
+func (s *supplyCounter) set(minted *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minted = copyBalance(minted)
+}
+
+func (s *supplyCounter) get() *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyBalance(s.minted)
+}
+
+// mint adds created coins to supply, saved supply is updated for vault file,
+// called under balanceMu
+func (v *D5Vault) mint(delta *big.Int) {
+	var minted = v.supply.add(delta)
+	if v.inMem {
+		return
+	}
+	if err := writeSupply(minted); err != nil {
+		fmt.Printf("Failed to save minted supply: %s\r\n", err)
+	}
+}
+
+// resetSupply sets minted supply to sum of balances, for vault which is
+// trusted as a whole
+func (v *D5Vault) resetSupply() {
+	var sum = v.sumBalances()
+	v.supply.set(sum)
+	if v.inMem {
+		return
+	}
+	if err := writeSupply(sum); err != nil {
+		fmt.Printf("Failed to save minted supply: %s\r\n", err)
+	}
+}
+
+// checkSyncedSupply compares balances read from vault file with saved
+// supply. Vault file without saved supply sets it.
+func (v *D5Vault) checkSyncedSupply() {
+	minted, err := readSupply()
+	if errors.Is(err, os.ErrNotExist) {
+		v.resetSupply()
+		return
+	}
+	if err != nil {
+		syncLogger.Warnf("Minted supply is not read, supply is not verified: %s", err)
+		v.supply.set(v.sumBalances())
+		return
+	}
+	v.supply.set(minted)
+	if _, err := v.VerifySupply(); err != nil {
+		syncLogger.Warnf("Vault may be corrupted: %s", err)
+	}
+}
+
+func (v *D5Vault) sumBalances() *big.Int {
+	var sum = big.NewInt(0)
+	v.accounts.ForEach(func(sa types.StateAccount) bool {
+		if sa.Balance != nil {
+			sum.Add(sum, sa.Balance)
+		}
+		return true
+	})
+	return sum
+}
+
+// VerifySupply sums balances of all accounts and compares sum with minted
+// supply. Sum is returned with ErrSupplyMismatch describing discrepancy.
+func (v *D5Vault) VerifySupply() (*big.Int, error) {
+	var sum = v.sumBalances()
+	return sum, checkSupply(sum, v.supply.get())
+}
+
+func checkSupply(sum, minted *big.Int) error {
+	if diff := new(big.Int).Sub(sum, minted); diff.Sign() != 0 {
+		return fmt.Errorf("%w: balances %s, minted %s, difference %s", ErrSupplyMismatch, sum, minted, diff)
+	}
+	return nil
+}
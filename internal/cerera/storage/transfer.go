@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/types"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrNegativeAmount      = errors.New("transfer amount should not be negative")
+)
+
+// Transfer moves cnt from one account to another and increments nonce of sender.
+// Balance of sender is checked and both accounts are changed under locks of
+// their shards, transfers of other shards go on meanwhile. Memory is changed only after
+// both accounts are written to vault file; if sender write fails, recipient
+// record is restored on disk. See VaultBatch.Transfer for deferred writes.
+// Non-empty txHash is recorded as input of recipient.
+func (v *D5Vault) Transfer(from, to types.Address, cnt *big.Int, txHash common.Hash) error {
+	return v.transfer(nil, from, to, cnt, txHash)
+}
+
+// transfer changes accounts, their writes are deferred to batch if it is not nil
+func (v *D5Vault) transfer(b *VaultBatch, from, to types.Address, cnt *big.Int, txHash common.Hash) error {
+	if cnt == nil || cnt.Sign() < 0 {
+		return ErrNegativeAmount
+	}
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	v.balanceMu.RLock()
+	defer v.balanceMu.RUnlock()
+	var unlock = v.accounts.lockBalances(from, to)
+	defer unlock()
+
+	var saFrom = v.Get(from)
+	var balance = copyBalance(saFrom.Balance)
+	if balance.Cmp(cnt) < 0 {
+		return fmt.Errorf("%w: have %s, want %s", ErrInsufficientBalance, balance, cnt)
+	}
+	if from == to {
+		return nil
+	}
+	var saTo = v.Get(to)
+
+	// new balances, accounts in trie are not changed until writes succeed
+	var newFrom, newTo = saFrom, saTo
+	newFrom.Balance = new(big.Int).Sub(balance, cnt)
+	newFrom.Nonce++
+	newTo.Balance = new(big.Int).Add(copyBalance(saTo.Balance), cnt)
+	if txHash != (common.Hash{}) {
+		newTo.Inputs = append(append(make([]common.Hash, 0, len(saTo.Inputs)+1), saTo.Inputs...), txHash)
+	}
+
+	if b != nil {
+		b.record(from, new(big.Int).Neg(cnt), 1, common.Hash{})
+		b.record(to, cnt, 0, txHash)
+	} else if !v.inMem {
+		var err = updateAccount(newTo.Bytes())
+		v.breaker.record(err)
+		if err != nil {
+			return err
+		}
+		err = updateAccount(newFrom.Bytes())
+		v.breaker.record(err)
+		if err != nil {
+			if rbErr := updateAccount(saTo.Bytes()); rbErr != nil {
+				fmt.Printf("Failed to restore account %s after failed transfer: %s\r\n", to, rbErr)
+			}
+			return err
+		}
+	}
+
+	v.history.touch(from, saFrom.Balance)
+	v.history.touch(to, saTo.Balance)
+	v.accounts.Append(from, newFrom)
+	v.accounts.Append(to, newTo)
+	v.notifyBalance(from, newFrom.Balance)
+	v.notifyBalance(to, newTo.Balance)
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/x509"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/cerera/internal/cerera/common"
 	"github.com/cerera/internal/cerera/config"
@@ -15,9 +18,12 @@ import (
 	"github.com/tyler-smith/go-bip39"
 )
 
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
 type Vault interface {
 	CoinBase() *ecdsa.PrivateKey
 	Create(name string, pass string) (string, string, *types.Address, error)
+	Restore(mnemonic string, pass string) (*types.Address, *ecdsa.PrivateKey, error)
 	Clear() error
 	Prepare()
 	Put(address types.Address, acc types.StateAccount)
@@ -30,18 +36,37 @@ type Vault interface {
 
 type D5Vault struct {
 	accounts *AccountsTrie
+	breaker  writeBreaker
+	code     *codeCache
+	memCode  memCodeStore // contract code of in-memory vault
+	storage  memStorage   // contract storage of in-memory vault
+	balances balanceFeed  // subscribers of balance changes
 	coinBase types.StateAccount
+	history  balanceHistory
+	inMem    bool
 	path     string
 	rootHash common.Hash
+
+	// held for writing by changes of many accounts, transfers hold it for
+	// reading and lock only shards of their accounts
+	balanceMu sync.RWMutex
+	supply    supplyCounter
+	locator   TxLocator // txs of chain for history of accounts
+
+	faucetMu    sync.Mutex
+	faucetTimes map[types.Address]time.Time // last faucet request of address
+	// own faucet cooldown of addresses, zero means no cooldown
+	faucetOverrides map[types.Address]time.Duration
 }
 
 var vlt D5Vault
 
 func Sync() []byte {
 	res := make([]byte, 0)
-	for _, sa := range vlt.accounts.accounts {
+	vlt.accounts.ForEach(func(sa types.StateAccount) bool {
 		res = append(res, sa.Bytes()...)
-	}
+		return true
+	})
 	return res
 }
 func GetVault() *D5Vault {
@@ -54,7 +79,9 @@ func NewD5Vault(cfg *config.Config) Vault {
 	var rootHashAddress = cfg.NetCfg.ADDR
 
 	vlt = D5Vault{
-		accounts: GetAccountsTrie(),
+		accounts: NewAccountsTrie(cfg.GetVaultShards()),
+		code:     newCodeCache(cfg.GetCodeCacheSize()),
+		inMem:    cfg.Vault.MEM,
 		rootHash: common.BytesToHash(rootHashAddress.Bytes()),
 	}
 
@@ -73,14 +100,21 @@ func NewD5Vault(cfg *config.Config) Vault {
 		Root:     vlt.rootHash,
 		CodeHash: types.EncodePrivateKeyToByte(types.DecodePrivKey(cfg.NetCfg.PRIV)),
 		Status:   "OP_ACC_NEW",
+		Type:     types.AccountRoot,
 		Bloom:    []byte{0xa, 0x0, 0x0, 0x0, 0xf, 0xd, 0xd, 0xd, 0xd, 0xd},
 		Inputs:   nil,
 		MPub:     publicKey.B58Serialize(),
 	}
 
 	vlt.accounts.Append(rootHashAddress, rootSA)
+	vlt.supply.set(rootSA.Balance)
 	vlt.coinBase = coinbase.CoinBaseStateAccount()
 
+	// in memory vault lives without fs
+	if vlt.inMem {
+		return &vlt
+	}
+
 	// sync with fs
 	if cfg.Vault.PATH == "EMPTY" {
 		if err := InitSecureVault(rootSA); err != nil {
@@ -106,6 +140,9 @@ func (v *D5Vault) Clear() error {
 
 // Create - create an account to store and return it
 func (v *D5Vault) Create(name string, pass string) (string, string, *types.Address, error) {
+	if err := v.breaker.allow(); err != nil {
+		return "", "", nil, err
+	}
 
 	entropy, _ := bip39.NewEntropy(256)
 	mnemonic, _ := bip39.NewMnemonic(entropy)
@@ -116,12 +153,16 @@ func (v *D5Vault) Create(name string, pass string) (string, string, *types.Addre
 	masterKey, _ := bip32.NewMasterKey(seed)
 	publicKey := masterKey.PublicKey()
 
-	privateKey, err := types.GenerateAccount()
+	// signing key comes from seed, so mnemonic restores it
+	privateKey, err := types.DeriveSigningKeyFromSeed(seed)
 	if err != nil {
 		return "", "", nil, err
 	}
 	pubkey := &privateKey.PublicKey
 	address := types.PubkeyToAddress(*pubkey)
+	if !address.IsCanonical() {
+		return "", "", nil, types.ErrNonCanonicalAddress
+	}
 	derBytes := types.EncodePrivateKeyToByte(privateKey)
 	// derBytes, _ := x509.MarshalECPrivateKey(privateKey)
 
@@ -153,14 +194,60 @@ func (v *D5Vault) Create(name string, pass string) (string, string, *types.Addre
 	// x509EncodedPub, _ := x509.MarshalPKIXPublicKey(pubkey)
 	// pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
 
-	SaveToVault(newAccount.Bytes())
+	if !v.inMem {
+		v.breaker.record(saveAccount(newAccount.Bytes()))
+	}
 
 	return publicKey.B58Serialize(), mnemonic, &address, nil
 }
 
+// Restore derives signing key of account from mnemonic and passphrase it was
+// created with. Account should be known to vault.
+func (v *D5Vault) Restore(mnemonic string, pass string) (*types.Address, *ecdsa.PrivateKey, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, nil, ErrInvalidMnemonic
+	}
+	privateKey, err := types.DeriveSigningKeyFromSeed(bip39.NewSeed(mnemonic, pass))
+	if err != nil {
+		return nil, nil, err
+	}
+	address := types.PubkeyToAddress(privateKey.PublicKey)
+	if v.GetCopy(address) == nil {
+		return nil, nil, ErrAccountNotFound
+	}
+	return &address, privateKey, nil
+}
+
 func (v *D5Vault) Get(addr types.Address) types.StateAccount {
 	return v.accounts.GetAccount(addr)
 }
+
+// GetCopy returns deep copy of account which is safe to read and change
+// without vault locks, nil if account is unknown. Get is for mutation paths.
+func (v *D5Vault) GetCopy(addr types.Address) *types.StateAccount {
+	if v.accounts == nil {
+		return nil
+	}
+	var sa, ok = v.accounts.GetAccountOk(addr)
+	if !ok {
+		return nil
+	}
+	return sa.Copy()
+}
+
+// GetBalances returns exact balances of addresses, nil for unknown ones.
+func (v *D5Vault) GetBalances(addrs []types.Address) map[types.Address]*big.Int {
+	var accounts = v.accounts.GetAccounts(addrs)
+	var res = make(map[types.Address]*big.Int, len(addrs))
+	for _, addr := range addrs {
+		if sa, ok := accounts[addr]; ok {
+			res[addr] = copyBalance(sa.Balance)
+		} else {
+			res[addr] = nil
+		}
+	}
+	return res
+}
 func (v *D5Vault) GetKey(signKey string) []byte {
 	pubKey, _ := bip32.B58Deserialize(signKey)
 
@@ -176,17 +263,25 @@ func (v *D5Vault) GetAll() interface{} {
 	// refactor
 	// this function returns all active (register) addressses with balance
 	// [addr1:balance1, addr2:balance2, ..., addrN:balanceN]
-	SyncVault(v.path)
-	res := make(map[types.Address]float64)
-	for addr, v := range v.accounts.accounts {
-		res[addr] = types.BigIntToFloat(v.Balance)
+	// json encoding of map sorts addresses, so response order is stable
+	if !v.inMem {
+		SyncVault(v.path)
 	}
+	res := make(map[types.Address]float64)
+	v.accounts.ForEach(func(sa types.StateAccount) bool {
+		res[sa.Address] = types.BigIntToFloat(sa.Balance)
+		return true
+	})
 	return res
 }
 func (v *D5Vault) Put(address types.Address, acc types.StateAccount) {
 	v.accounts.Append(address, acc)
+	v.notifyBalance(address, acc.Balance)
 }
 func (v *D5Vault) Size() int64 {
+	if v.inMem {
+		return int64(len(Sync()))
+	}
 	var s, err = VaultSourceSize()
 	if err != nil {
 		return -1
@@ -194,34 +289,40 @@ func (v *D5Vault) Size() int64 {
 		return s
 	}
 }
-func (v *D5Vault) UpdateBalance(from types.Address, to types.Address, cnt *big.Int, txHash common.Hash) {
-
-	// decrement first
-	// wtf big int sub only?
 
+// UpdateBalance is kept for compatibility, see Transfer.
+func (v *D5Vault) UpdateBalance(from types.Address, to types.Address, cnt *big.Int, txHash common.Hash) error {
 	fmt.Println("Update balance")
-	var sa = v.Get(from)
-	sa.Balance = sa.Balance.Sub(sa.Balance, cnt)
-	// sa = v.accounts.GetAccount(from)
-
-	// increment second
-	var saDest = v.Get(to)
-	saDest.Balance = saDest.Balance.Add(saDest.Balance, cnt)
-
-	// when increment, add input to account - tx hash
-	// saDest.Inputs = append(saDest.Inputs, txHash)
-	// saDest = v.accounts.GetAccount(to)
-
-	// done
-	UpdateVault(saDest.Bytes())
-	UpdateVault(sa.Bytes())
+	return v.Transfer(from, to, cnt, txHash)
 }
 
-// faucet method without creating transaction
-func (v *D5Vault) FaucetBalance(to types.Address, val *big.Int) {
+// faucet method without creating transaction. Like Transfer, account in
+// memory is changed only after it is written to vault file.
+func (v *D5Vault) FaucetBalance(to types.Address, val *big.Int) error {
+	if err := v.breaker.allow(); err != nil {
+		return err
+	}
+	if err := v.checkFaucet(to, val); err != nil {
+		return err
+	}
+	v.balanceMu.Lock()
+	defer v.balanceMu.Unlock()
 	var destSA = v.Get(to)
-	destSA.Balance.Add(destSA.Balance, val)
-	UpdateVault(destSA.Bytes())
+	var newDest = destSA
+	newDest.Balance = new(big.Int).Add(copyBalance(destSA.Balance), val)
+	if !v.inMem {
+		var err = updateAccount(newDest.Bytes())
+		v.breaker.record(err)
+		if err != nil {
+			return err
+		}
+	}
+	v.history.touch(to, destSA.Balance)
+	v.accounts.Append(to, newDest)
+	v.mint(val)
+	v.markFaucet(to)
+	v.notifyBalance(to, newDest.Balance)
+	return nil
 }
 func (v *D5Vault) CheckRunnable(r *big.Int, s *big.Int, tx *types.GTransaction) bool {
 
@@ -1 +1,1205 @@
 package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cerera/internal/cerera/block"
+	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
+	"github.com/cerera/internal/cerera/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// memSupply keeps minted supply of vault in memory instead of supply file
+func memSupply(t *testing.T) {
+	var saved *big.Int
+	readSupply = func() (*big.Int, error) {
+		if saved == nil {
+			return nil, os.ErrNotExist
+		}
+		return saved, nil
+	}
+	writeSupply = func(minted *big.Int) error {
+		saved = new(big.Int).Set(minted)
+		return nil
+	}
+	t.Cleanup(func() { readSupply, writeSupply = ReadSupplyFile, WriteSupplyFile })
+}
+
+func prepareConfig() *config.Config {
+	pk, _ := types.GenerateAccount()
+	cfg := &config.Config{}
+	cfg.NetCfg.ADDR = types.PubkeyToAddress(pk.PublicKey)
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(pk)
+	cfg.Vault.PATH = "EMPTY"
+	return cfg
+}
+
+func TestInMemVault(t *testing.T) {
+	cfg := prepareConfig()
+	cfg.Vault.MEM = true
+	cfg.POOL.MEM = false
+	cfg.Chain.MEM = false
+
+	v := NewD5Vault(cfg)
+	_, _, addr, err := v.Create("test", "pass")
+	if err != nil {
+		t.Fatalf("Error while create account: %s", err)
+	}
+	if v.Get(*addr).Address != *addr {
+		t.Errorf("Account not found in memory vault: %s", addr)
+	}
+	if _, err := os.Stat("./vault.dat"); !os.IsNotExist(err) {
+		os.Remove("./vault.dat")
+		t.Errorf("In memory vault should not create vault file")
+	}
+	if cfg.Vault.PATH != "EMPTY" {
+		t.Errorf("In memory vault should not update vault path, have %s", cfg.Vault.PATH)
+	}
+}
+
+func TestPersistentVaultRestart(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := prepareConfig()
+	cfg.Vault.MEM = false
+	cfg.POOL.MEM = true
+	cfg.Chain.MEM = true
+
+	NewD5Vault(cfg)
+	_, _, addr, err := GetVault().Create("restart", "pass")
+	if err != nil {
+		t.Fatalf("Error while create account: %s", err)
+	}
+	if cfg.Vault.PATH != "./vault.dat" {
+		t.Fatalf("Persistent vault should update vault path, have %s", cfg.Vault.PATH)
+	}
+
+	// node restarts with same config
+	NewD5Vault(cfg)
+	if GetVault().GetCopy(*addr) == nil {
+		t.Errorf("Account %s is lost after restart of persistent vault", addr)
+	}
+}
+
+func TestRestoreFromMnemonic(t *testing.T) {
+	cfg := prepareConfig()
+	cfg.Vault.MEM = true
+	v := NewD5Vault(cfg)
+
+	_, mnemonic, addr, err := v.Create("restore", "pass")
+	if err != nil {
+		t.Fatalf("Error while create account: %s", err)
+	}
+	restored, key, err := v.Restore(mnemonic, "pass")
+	if err != nil {
+		t.Fatalf("Error while restore account: %s", err)
+	}
+	if *restored != *addr || !bytes.Equal(types.EncodePrivateKeyToByte(key), v.Get(*addr).CodeHash) {
+		t.Errorf("Mnemonic should restore signing key of %s, have %s", addr, restored)
+	}
+	if _, _, err := v.Restore(mnemonic, "other"); err != ErrAccountNotFound {
+		t.Errorf("Expected %s for other passphrase, have %v", ErrAccountNotFound, err)
+	}
+	if _, _, err := v.Restore("not a mnemonic", "pass"); err != ErrInvalidMnemonic {
+		t.Errorf("Expected %s, have %v", ErrInvalidMnemonic, err)
+	}
+}
+
+func TestSyncVaultLogs(t *testing.T) {
+	memSupply(t)
+	vlt = D5Vault{accounts: GetAccountsTrie()}
+	var path = filepath.Join(t.TempDir(), "vault.dat")
+	var data = make([]byte, 0)
+	for i := 0; i < 2500; i++ {
+		sa := types.StateAccount{
+			Address: types.BytesToAddress([]byte{0x1, byte(i >> 8), byte(i)}),
+			Balance: big.NewInt(int64(i)),
+		}
+		data = append(data, sa.Bytes()...)
+		data = append(data, '\n')
+	}
+	data = append(data, []byte("{corrupted\n")...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs = observeSyncLog(t)
+	if err := SyncVault(path); err != nil {
+		t.Fatalf("Error while sync vault: %s", err)
+	}
+	if vlt.accounts.Size() != 2500 {
+		t.Errorf("Different vault size! Have %d, want %d", vlt.accounts.Size(), 2500)
+	}
+	// 2 progress lines, 1 corrupted account, 1 summary
+	if logs.Len() != 4 {
+		t.Errorf("Different count of log lines! Have %d, want %d: %v", logs.Len(), 4, logs.All())
+	}
+	if logs.FilterLevelExact(zapcore.WarnLevel).FilterMessageSnippet("Skip corrupted account").Len() != 1 {
+		t.Errorf("Corrupted account should be logged as warning, have %v", logs.All())
+	}
+	if logs.FilterMessageSnippet("Synced 2500 accounts").FilterMessageSnippet("corrupted 1").Len() != 1 {
+		t.Errorf("Summary not found in log: %v", logs.All())
+	}
+}
+
+// observeSyncLog replaces logger of vault sync by observer until test ends
+func observeSyncLog(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	var old = syncLogger
+	syncLogger = zap.New(core).Sugar()
+	t.Cleanup(func() { syncLogger = old })
+	return logs
+}
+
+func TestAccountsTrieOrder(t *testing.T) {
+	at := GetAccountsTrie()
+	for _, b := range []byte{0x5, 0x1, 0x9, 0x3} {
+		at.Append(types.Address{b}, types.StateAccount{Address: types.Address{b}})
+	}
+
+	first := at.GetAll()
+	second := at.GetAll()
+	if len(first) != 4 {
+		t.Fatalf("Different count of accounts! Have %d, want %d", len(first), 4)
+	}
+	for i := range first {
+		if first[i].Address != second[i].Address {
+			t.Errorf("Different order between calls at %d: %s and %s", i, first[i].Address, second[i].Address)
+		}
+		if i > 0 && bytes.Compare(first[i-1].Address[:], first[i].Address[:]) >= 0 {
+			t.Errorf("Accounts are not sorted at %d", i)
+		}
+	}
+
+	at.Append(types.Address{0x4}, types.StateAccount{Address: types.Address{0x4}})
+	all := at.GetAll()
+	if all[2].Address != (types.Address{0x4}) {
+		t.Errorf("New account should be inserted at sorted position, have %s", all[2].Address)
+	}
+
+	var visited = 0
+	at.ForEach(func(sa types.StateAccount) bool {
+		visited++
+		return visited < 2
+	})
+	if visited != 2 {
+		t.Errorf("ForEach should stop when fn returns false, visited %d", visited)
+	}
+}
+
+func TestWriteBreaker(t *testing.T) {
+	memSupply(t)
+	var addr = types.Address{0x1, 0x2}
+	vlt = D5Vault{accounts: GetAccountsTrie(), path: filepath.Join(t.TempDir(), "vault.dat")}
+	vlt.accounts.Append(addr, types.StateAccount{Address: addr, Balance: big.NewInt(0)})
+
+	var cooldown = FaucetCooldown
+	FaucetCooldown = 0
+	defer func() { FaucetCooldown = cooldown }()
+
+	var writes = 0
+	updateAccount = func(account []byte) error {
+		writes++
+		return errors.New("disk is full")
+	}
+	defer func() { updateAccount = UpdateVault }()
+
+	for i := 0; i < BreakerThreshold; i++ {
+		if err := vlt.FaucetBalance(addr, FaucetMinValue); err == nil {
+			t.Errorf("Write failure should be returned")
+		}
+	}
+	if !vlt.ReadOnly() {
+		t.Fatalf("Vault should be read only after %d failures", BreakerThreshold)
+	}
+
+	var balance = new(big.Int).Set(vlt.Get(addr).Balance)
+	if err := vlt.FaucetBalance(addr, FaucetMinValue); err != ErrVaultReadOnly {
+		t.Errorf("Read only vault should reject mutation, have %v", err)
+	}
+	if _, _, _, err := vlt.Create("test", "pass"); err != ErrVaultReadOnly {
+		t.Errorf("Read only vault should reject account creation, have %v", err)
+	}
+	if vlt.Get(addr).Balance.Cmp(balance) != 0 || writes != BreakerThreshold {
+		t.Errorf("Read only vault should not mutate state")
+	}
+
+	// health check only reports state of vault
+	updateAccount = func(account []byte) error { return nil }
+	if err := vlt.HealthCheck(); err != ErrVaultReadOnly {
+		t.Errorf("Health check of read only vault should fail with %v, have %v", ErrVaultReadOnly, err)
+	}
+	if !vlt.ReadOnly() {
+		t.Fatalf("Health check should not switch vault from read only mode")
+	}
+	if err := vlt.Recover(); err != nil {
+		t.Fatalf("Recover failed: %s", err)
+	}
+	if vlt.ReadOnly() || vlt.HealthCheck() != nil {
+		t.Errorf("Vault should accept mutations after recover")
+	}
+	if err := vlt.FaucetBalance(addr, FaucetMinValue); err != nil {
+		t.Errorf("Mutation after recover failed: %s", err)
+	}
+}
+
+func TestWriteBreakerConcurrent(t *testing.T) {
+	var b writeBreaker
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < BreakerThreshold; j++ {
+				b.allow()
+				b.record(errors.New("disk is full"))
+			}
+		}()
+	}
+	wg.Wait()
+	if !b.isOpen() || b.failures != 8*BreakerThreshold {
+		t.Errorf("Expected open breaker after %d failures, have %d", 8*BreakerThreshold, b.failures)
+	}
+}
+
+func TestAccountsTrieShards(t *testing.T) {
+	at := NewAccountsTrie(4)
+	for i := 0; i < 64; i++ {
+		at.Append(types.Address{byte(i)}, types.StateAccount{Address: types.Address{byte(i)}})
+	}
+	if at.Size() != 64 {
+		t.Errorf("Different trie size! Have %d, want %d", at.Size(), 64)
+	}
+	for _, s := range at.shards {
+		if len(s.accounts) != 16 {
+			t.Errorf("Accounts should be spread over shards, have %d in shard", len(s.accounts))
+		}
+	}
+	all := at.GetAll()
+	for i := range all {
+		if all[i].Address != (types.Address{byte(i)}) {
+			t.Errorf("Accounts of shards should be merged in address order, have %s at %d", all[i].Address, i)
+		}
+	}
+}
+
+func benchmarkAccountsTrie(b *testing.B, shards int) {
+	at := NewAccountsTrie(shards)
+	var addrs = make([]types.Address, 256)
+	for i := range addrs {
+		addrs[i] = types.Address{byte(i)}
+		at.Append(addrs[i], types.StateAccount{Address: addrs[i], Balance: big.NewInt(0)})
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i = 0
+		for pb.Next() {
+			var addr = addrs[i%len(addrs)]
+			var sa = at.GetAccount(addr)
+			at.Append(addr, sa)
+			i += 7
+		}
+	})
+}
+
+func BenchmarkAccountsTrieSingleLock(b *testing.B) {
+	benchmarkAccountsTrie(b, 1)
+}
+
+func BenchmarkAccountsTrieSharded(b *testing.B) {
+	benchmarkAccountsTrie(b, DefaultTrieShards)
+}
+
+func TestTransferShardsConcurrent(t *testing.T) {
+	memSupply(t)
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	var addrs = make([]types.Address, 8)
+	for i := range addrs {
+		addrs[i] = types.Address{byte(i)}
+		vlt.Put(addrs[i], types.StateAccount{Address: addrs[i], Balance: big.NewInt(1000)})
+	}
+	// transfers in both directions between accounts of different shards
+	var wg sync.WaitGroup
+	for i := range addrs {
+		wg.Add(1)
+		go func(from, to types.Address) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				vlt.Transfer(from, to, big.NewInt(1), common.Hash{})
+			}
+		}(addrs[i], addrs[(i+1)%len(addrs)])
+	}
+	wg.Wait()
+	for _, addr := range addrs {
+		if sa := vlt.Get(addr); sa.Balance.Int64() != 1000 || sa.Nonce != 100 {
+			t.Errorf("Expected balance 1000 and nonce 100 of %s, have %d and %d", addr, sa.Balance, sa.Nonce)
+		}
+	}
+}
+
+// benchmarkTransfer runs transfers between 16 accounts, first byte of their
+// addresses is step apart
+func benchmarkTransfer(b *testing.B, step int) {
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	var addrs = make([]types.Address, 16)
+	for i := range addrs {
+		addrs[i] = types.Address{byte(i * step)}
+		vlt.Put(addrs[i], types.StateAccount{Address: addrs[i], Balance: big.NewInt(int64(b.N) + 1)})
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i = rand.Intn(len(addrs))
+		for pb.Next() {
+			vlt.Transfer(addrs[i%len(addrs)], addrs[(i+1)%len(addrs)], big.NewInt(1), common.Hash{})
+			i++
+		}
+	})
+}
+
+// all accounts are in one shard
+func BenchmarkTransferOneShard(b *testing.B) {
+	benchmarkTransfer(b, DefaultTrieShards)
+}
+
+func BenchmarkTransfer(b *testing.B) {
+	benchmarkTransfer(b, 1)
+}
+
+func TestFaucetStatus(t *testing.T) {
+	var addr = types.Address{0x1, 0x3}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.accounts.Append(addr, types.StateAccount{Address: addr, Balance: big.NewInt(0)})
+
+	status := vlt.FaucetStatus(addr)
+	if !status.Eligible || status.Remaining != 0 {
+		t.Errorf("Fresh address should be eligible for faucet, have %+v", status)
+	}
+	if status.Min.Cmp(FaucetMinValue) != 0 || status.Max.Cmp(FaucetMaxValue) != 0 {
+		t.Errorf("Different faucet limits! Have %s-%s, want %s-%s", status.Min, status.Max, FaucetMinValue, FaucetMaxValue)
+	}
+	if vlt.Get(addr).Balance.Sign() != 0 {
+		t.Errorf("Faucet status should not dispense")
+	}
+
+	if err := vlt.FaucetBalance(addr, FaucetMaxValue); err != nil {
+		t.Fatalf("Error while faucet: %s", err)
+	}
+	status = vlt.FaucetStatus(addr)
+	if status.Eligible {
+		t.Errorf("Address should not be eligible during cooldown")
+	}
+	if status.Remaining <= 0 || status.Remaining > FaucetCooldown.Seconds() {
+		t.Errorf("Remaining cooldown should be in (0, %f], have %f", FaucetCooldown.Seconds(), status.Remaining)
+	}
+	if err := vlt.FaucetBalance(addr, FaucetMinValue); err != ErrFaucetCooldown {
+		t.Errorf("Faucet during cooldown should be rejected, have %v", err)
+	}
+	if err := vlt.FaucetBalance(types.Address{0x1, 0x4}, new(big.Int).Add(FaucetMaxValue, big.NewInt(1))); err != ErrFaucetValue {
+		t.Errorf("Faucet over max value should be rejected, have %v", err)
+	}
+}
+
+func TestFaucetCooldownOverride(t *testing.T) {
+	var bot, user = types.Address{0x1, 0x5}, types.Address{0x1, 0x6}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.accounts.Append(bot, types.StateAccount{Address: bot, Balance: big.NewInt(0)})
+	vlt.accounts.Append(user, types.StateAccount{Address: user, Balance: big.NewInt(0)})
+
+	vlt.SetFaucetCooldownOverride(bot, 0)
+	for i := 0; i < 3; i++ {
+		if err := vlt.FaucetBalance(bot, FaucetMinValue); err != nil {
+			t.Errorf("Address without cooldown should get faucet, have %v", err)
+		}
+	}
+	if err := vlt.FaucetBalance(user, FaucetMinValue); err != nil {
+		t.Fatalf("Error while faucet: %s", err)
+	}
+	if err := vlt.FaucetBalance(user, FaucetMinValue); err != ErrFaucetCooldown {
+		t.Errorf("Override of other address should not affect default, have %v", err)
+	}
+
+	vlt.SetFaucetCooldownOverride(user, time.Hour)
+	if status := vlt.FaucetStatus(user); status.Remaining > time.Hour.Seconds() || status.Remaining <= 0 {
+		t.Errorf("Remaining cooldown should follow override, have %f", status.Remaining)
+	}
+
+	vlt.ClearFaucetCooldownOverride(bot)
+	if err := vlt.FaucetBalance(bot, FaucetMinValue); err != ErrFaucetCooldown {
+		t.Errorf("Cleared override should fall back to default cooldown, have %v", err)
+	}
+}
+
+func TestContractCodeCache(t *testing.T) {
+	var addr = types.Address{0x1, 0x2}
+	var code = []byte{0x60, 0x80, 0x60, 0x40}
+	vlt = D5Vault{accounts: GetAccountsTrie(), code: newCodeCache(2)}
+
+	var stored = make(map[types.Address][]byte)
+	var reads = 0
+	readCode = func(a types.Address) ([]byte, common.Hash, error) {
+		reads++
+		c, ok := stored[a]
+		if !ok {
+			return nil, common.Hash{}, ErrCodeNotFound
+		}
+		return c, codeHash(c), nil
+	}
+	writeCode = func(a types.Address, c []byte) error {
+		stored[a] = c
+		return nil
+	}
+	defer func() { readCode, writeCode = ReadCodeFile, WriteCodeFile }()
+	var hits, misses = testutil.ToFloat64(codeCacheHits), testutil.ToFloat64(codeCacheMisses)
+
+	if err := vlt.StoreContractCode(addr, code); err != nil {
+		t.Fatalf("Error while store code: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		res, err := vlt.GetContractCode(addr)
+		if err != nil || !bytes.Equal(res, code) {
+			t.Errorf("Wrong contract code, have %x (%v), want %x", res, err, code)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("Second read should be served from cache, have %d store reads", reads)
+	}
+	if testutil.ToFloat64(codeCacheHits)-hits != 1 || testutil.ToFloat64(codeCacheMisses)-misses != 1 {
+		t.Errorf("Expected one cache hit and one miss")
+	}
+
+	var newCode = []byte{0x60, 0x00}
+	if err := vlt.StoreContractCode(addr, newCode); err != nil {
+		t.Fatalf("Error while store code: %s", err)
+	}
+	if res, _ := vlt.GetContractCode(addr); !bytes.Equal(res, newCode) || reads != 2 {
+		t.Errorf("Cache should be invalidated on store, have %x after %d reads", res, reads)
+	}
+
+	// corrupted code is not cached
+	readCode = func(a types.Address) ([]byte, common.Hash, error) {
+		reads++
+		return code, common.Hash{0x1}, nil
+	}
+	if _, err := vlt.GetContractCode(types.Address{0x3}); err != ErrCodeHashMismatch {
+		t.Errorf("Hash mismatch should be returned, have %v", err)
+	}
+	if _, ok := vlt.code.get(types.Address{0x3}); ok {
+		t.Errorf("Code with wrong hash should not be cached")
+	}
+}
+
+func TestConfirmedBalance(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	vlt.Put(to, types.StateAccount{Address: to, Balance: big.NewInt(0)})
+	vlt.CommitHeight(10)
+
+	// transfer included into block 11
+	if err := vlt.UpdateBalance(from, to, big.NewInt(30), common.Hash{}); err != nil {
+		t.Fatalf("Error while update balance: %s", err)
+	}
+	vlt.CommitHeight(11)
+
+	var tests = []struct {
+		height  int
+		minConf int
+		to      int64
+		from    int64
+	}{
+		{11, 0, 30, 70},
+		{11, 1, 0, 100},
+		{12, 1, 30, 70},
+		{12, 2, 0, 100},
+		{13, 2, 30, 70},
+	}
+	for _, test := range tests {
+		if test.height > 11 {
+			vlt.CommitHeight(test.height)
+		}
+		if b := vlt.ConfirmedBalance(to, test.minConf); b.Int64() != test.to {
+			t.Errorf("Confirmed balance of receiver at %d with %d conf, have %d, want %d", test.height, test.minConf, b, test.to)
+		}
+		if b := vlt.ConfirmedBalance(from, test.minConf); b.Int64() != test.from {
+			t.Errorf("Confirmed balance of sender at %d with %d conf, have %d, want %d", test.height, test.minConf, b, test.from)
+		}
+	}
+
+	// history deeper than kept is not trusted
+	vlt.CommitHeight(13 + BalanceHistoryDepth)
+	if b := vlt.ConfirmedBalance(to, BalanceHistoryDepth+5); b.Sign() != 0 {
+		t.Errorf("Balance beyond history should be zero, have %d", b)
+	}
+	if b := vlt.ConfirmedBalance(to, 1); b.Int64() != 30 {
+		t.Errorf("Old transfer should be confirmed, have %d", b)
+	}
+}
+
+func TestRewindHistory(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	vlt.CommitHeight(10)
+
+	// transfers of blocks 11 and 12 are replaced by other blocks
+	var undo []*BlockUndo
+	for i, value := range []int64{30, 20} {
+		var batch = vlt.BeginBatch()
+		if err := batch.Transfer(from, to, big.NewInt(value), common.Hash{}); err != nil {
+			t.Fatalf("Error while transfer: %s", err)
+		}
+		if err := batch.Commit(); err != nil {
+			t.Fatalf("Error while commit: %s", err)
+		}
+		vlt.CommitHeight(11 + i)
+		undo = append(undo, batch.Undo())
+	}
+	for i := len(undo) - 1; i >= 0; i-- {
+		if err := vlt.Revert(undo[i]); err != nil {
+			t.Fatalf("Error while revert: %s", err)
+		}
+	}
+	vlt.RewindHistory(10)
+	vlt.CommitHeight(11)
+	vlt.CommitHeight(12)
+
+	if b := vlt.ConfirmedBalance(to, 1); b.Sign() != 0 {
+		t.Errorf("Balance of replaced block should not be confirmed, have %d", b)
+	}
+	if b := vlt.ConfirmedBalance(from, 1); b.Int64() != 100 {
+		t.Errorf("Confirmed balance of sender should be 100, have %d", b)
+	}
+}
+
+func TestSetLabel(t *testing.T) {
+	var addr = types.Address{0x1, 0x2}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(addr, types.StateAccount{Address: addr, Balance: big.NewInt(10)})
+
+	if err := vlt.SetLabel(addr, "savings"); err != nil {
+		t.Fatalf("Error while set label: %s", err)
+	}
+	if label := vlt.Get(addr).Label; label != "savings" {
+		t.Errorf("Different label, have %q, want %q", label, "savings")
+	}
+	if err := vlt.SetLabel(types.Address{0x3}, "other"); err != ErrAccountNotFound {
+		t.Errorf("Label of unknown account should fail, have %v", err)
+	}
+	if err := vlt.SetLabel(addr, strings.Repeat("a", MaxLabelLength+1)); err != ErrLabelTooLong {
+		t.Errorf("Long label should fail, have %v", err)
+	}
+	if err := vlt.SetLabel(addr, ""); err != nil || vlt.Get(addr).Label != "" {
+		t.Errorf("Empty label should remove label, have %q (%v)", vlt.Get(addr).Label, err)
+	}
+}
+
+func TestGetCopy(t *testing.T) {
+	var addr = types.Address{0x1, 0x2}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(addr, types.StateAccount{
+		Address: addr,
+		Balance: big.NewInt(10),
+		Bloom:   []byte{0xa, 0x0},
+		Inputs:  []common.Hash{{0x1}},
+	})
+
+	var cpy = vlt.GetCopy(addr)
+	cpy.Balance.Add(cpy.Balance, big.NewInt(5))
+	cpy.Bloom[0] = 0xf
+	cpy.Inputs[0] = common.Hash{0x2}
+	cpy.Inputs = append(cpy.Inputs, common.Hash{0x3})
+
+	var stored = vlt.Get(addr)
+	if stored.Balance.Int64() != 10 || stored.Bloom[0] != 0xa || len(stored.Inputs) != 1 || stored.Inputs[0] != (common.Hash{0x1}) {
+		t.Errorf("Change of copy should not affect stored account, have %+v", stored)
+	}
+	if vlt.GetCopy(types.Address{0x3}) != nil {
+		t.Errorf("Copy of unknown account should be nil")
+	}
+}
+
+func TestIterate(t *testing.T) {
+	var empty D5Vault
+	if err := empty.Iterate(func(types.Address, *types.StateAccount) bool { return true }); err != ErrVaultNotInitialized {
+		t.Errorf("Expected %s, have %v", ErrVaultNotInitialized, err)
+	}
+
+	var v = &D5Vault{accounts: NewAccountsTrie(4)}
+	for i := 5; i > 0; i-- {
+		var addr = types.BytesToAddress([]byte{byte(i), 0x1})
+		v.accounts.Append(addr, types.StateAccount{Address: addr, Balance: big.NewInt(int64(i))})
+	}
+
+	var seen []types.Address
+	err := v.Iterate(func(addr types.Address, acc *types.StateAccount) bool {
+		seen = append(seen, addr)
+		// walk does not hold locks, so vault can be changed from callback
+		v.accounts.Append(addr, types.StateAccount{Address: addr, Balance: big.NewInt(100)})
+		acc.Balance.SetInt64(-1)
+		return len(seen) < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Iterate should stop when fn returns false, seen %d", len(seen))
+	}
+	for i := 1; i < len(seen); i++ {
+		if bytes.Compare(seen[i-1][:], seen[i][:]) >= 0 {
+			t.Errorf("Accounts should be walked in order of address bytes")
+		}
+	}
+	if v.Get(seen[0]).Balance.Int64() != 100 {
+		t.Errorf("Change of passed account should not affect vault")
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	vlt.Put(to, types.StateAccount{Address: to, Balance: big.NewInt(5)})
+
+	if err := vlt.Transfer(from, to, big.NewInt(101), common.Hash{}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("Expected %s, have %v", ErrInsufficientBalance, err)
+	}
+	if err := vlt.Transfer(from, to, big.NewInt(-1), common.Hash{}); err != ErrNegativeAmount {
+		t.Errorf("Expected %s, have %v", ErrNegativeAmount, err)
+	}
+	if err := vlt.Transfer(from, to, big.NewInt(100), common.Hash{}); err != nil {
+		t.Fatalf("Error while transfer: %s", err)
+	}
+	if vlt.Get(from).Balance.Int64() != 0 || vlt.Get(to).Balance.Int64() != 105 {
+		t.Errorf("Different balances! Have %d and %d, want 0 and 105", vlt.Get(from).Balance, vlt.Get(to).Balance)
+	}
+	if vlt.Get(from).Nonce != 1 || vlt.Get(to).Nonce != 0 {
+		t.Errorf("Only sender nonce should be incremented, have %d and %d", vlt.Get(from).Nonce, vlt.Get(to).Nonce)
+	}
+}
+
+func TestTransferRollback(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), path: filepath.Join(t.TempDir(), "vault.dat")}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	vlt.Put(to, types.StateAccount{Address: to, Balance: big.NewInt(5)})
+
+	// disk keeps last written record of each account
+	var disk = make(map[types.Address]int64)
+	updateAccount = func(account []byte) error {
+		var sa = types.BytesToStateAccount(account)
+		if sa.Address == from {
+			return errors.New("disk is full")
+		}
+		disk[sa.Address] = sa.Balance.Int64()
+		return nil
+	}
+	defer func() { updateAccount = UpdateVault }()
+
+	if err := vlt.Transfer(from, to, big.NewInt(30), common.Hash{}); err == nil {
+		t.Fatalf("Write failure of sender should be returned")
+	}
+	if vlt.Get(from).Balance.Int64() != 100 || vlt.Get(to).Balance.Int64() != 5 {
+		t.Errorf("Memory should not change after failed transfer, have %d and %d", vlt.Get(from).Balance, vlt.Get(to).Balance)
+	}
+	if disk[to] != 5 {
+		t.Errorf("Recipient record should be restored on disk, have %d", disk[to])
+	}
+}
+
+func TestFaucetWriteFailure(t *testing.T) {
+	memSupply(t)
+	var addr = types.Address{0x1, 0x7}
+	vlt = D5Vault{accounts: GetAccountsTrie(), path: filepath.Join(t.TempDir(), "vault.dat")}
+	vlt.Put(addr, types.StateAccount{Address: addr, Balance: big.NewInt(5)})
+
+	updateAccount = func(account []byte) error { return errors.New("disk is full") }
+	defer func() { updateAccount = UpdateVault }()
+
+	if err := vlt.FaucetBalance(addr, FaucetMinValue); err == nil {
+		t.Fatalf("Write failure should be returned")
+	}
+	if vlt.Get(addr).Balance.Int64() != 5 {
+		t.Errorf("Memory should not change after failed faucet, have %d", vlt.Get(addr).Balance)
+	}
+	if vlt.supply.get().Sign() != 0 {
+		t.Errorf("Supply should not change after failed faucet, have %s", vlt.supply.get())
+	}
+	if !vlt.FaucetStatus(addr).Eligible {
+		t.Errorf("Failed faucet should not start cooldown")
+	}
+
+	// account without balance is credited from zero
+	var empty = types.Address{0x1, 0x8}
+	vlt.Put(empty, types.StateAccount{Address: empty})
+	updateAccount = func(account []byte) error { return nil }
+	if err := vlt.FaucetBalance(empty, FaucetMinValue); err != nil {
+		t.Fatalf("Error while faucet: %s", err)
+	}
+	if vlt.Get(empty).Balance.Cmp(FaucetMinValue) != 0 {
+		t.Errorf("Different balance! Have %s, want %s", vlt.Get(empty).Balance, FaucetMinValue)
+	}
+}
+
+func TestInMemContractCode(t *testing.T) {
+	var addr = types.Address{0x1, 0x2}
+	var code = []byte{0x60, 0x80, 0x60, 0x40}
+	vlt = D5Vault{accounts: GetAccountsTrie(), code: newCodeCache(2), inMem: true}
+
+	writeCode = func(a types.Address, c []byte) error {
+		t.Errorf("In-memory vault should not write code file")
+		return nil
+	}
+	defer func() { writeCode = WriteCodeFile }()
+
+	if _, err := vlt.GetContractCode(addr); err != ErrCodeNotFound {
+		t.Errorf("Expected %s, have %v", ErrCodeNotFound, err)
+	}
+	if err := vlt.StoreContractCode(addr, code); err != nil {
+		t.Fatalf("Error while store code: %s", err)
+	}
+	code[0] = 0x0
+	res, err := vlt.GetContractCode(addr)
+	if err != nil || !bytes.Equal(res, []byte{0x60, 0x80, 0x60, 0x40}) {
+		t.Errorf("Wrong contract code, have %x (%v)", res, err)
+	}
+
+	// hash is checked the same way as for code file
+	var other = types.Address{0x3}
+	vlt.StoreContractCode(other, []byte{0x1})
+	var rec = vlt.memCode.records[other]
+	rec.Hash = common.Hash{0x1}
+	vlt.memCode.records[other] = rec
+	if _, err := vlt.GetContractCode(other); err != ErrCodeHashMismatch {
+		t.Errorf("Hash mismatch should be returned, have %v", err)
+	}
+}
+
+func TestInMemContractStorage(t *testing.T) {
+	var addr = types.Address{0x1, 0x2}
+	var key = common.BytesToHash([]byte{0x1})
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+
+	if vlt.GetStorage(addr, key).Sign() != 0 {
+		t.Errorf("Unset slot should be zero")
+	}
+	var val = big.NewInt(42)
+	if err := vlt.SetStorage(addr, key, val); err != nil {
+		t.Fatalf("Error while set storage: %s", err)
+	}
+	val.SetInt64(0)
+	if vlt.GetStorage(addr, key).Int64() != 42 {
+		t.Errorf("Different value! Have %d, want 42", vlt.GetStorage(addr, key))
+	}
+	if vlt.GetStorage(types.Address{0x3}, key).Sign() != 0 {
+		t.Errorf("Slots of other contract should be zero")
+	}
+
+	// zero value clears slot
+	vlt.SetStorage(addr, key, big.NewInt(0))
+	if _, ok := vlt.storage.slots[addr]; ok {
+		t.Errorf("Zero value should delete slot")
+	}
+
+	vlt.inMem = false
+	if err := vlt.SetStorage(addr, key, big.NewInt(1)); err != ErrStorageNotPersisted {
+		t.Errorf("Expected %s, have %v", ErrStorageNotPersisted, err)
+	}
+}
+
+func TestGetStorageRange(t *testing.T) {
+	var addr, other = types.Address{0x1, 0x2}, types.Address{0x1, 0x3}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	for i := 1; i <= 5; i++ {
+		vlt.SetStorage(addr, common.BigToHash(big.NewInt(int64(i*256))), big.NewInt(int64(i)))
+		vlt.SetStorage(other, common.BigToHash(big.NewInt(int64(i))), big.NewInt(100))
+	}
+
+	var seen = make(map[string]*big.Int)
+	var cursor *big.Int
+	var pages = 0
+	for {
+		res, next, err := vlt.GetStorageRange(addr, cursor, 2)
+		if err != nil {
+			t.Fatalf("Error while get storage range: %s", err)
+		}
+		pages++
+		for k, v := range res {
+			seen[k] = v
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if pages != 3 || len(seen) != 5 {
+		t.Errorf("Expected 5 slots in 3 pages, have %d in %d", len(seen), pages)
+	}
+	for k, v := range seen {
+		if v.Int64() == 100 {
+			t.Errorf("Slot %s of other contract in range", k)
+		}
+	}
+	if v := seen[common.BigToHash(big.NewInt(768)).Hex()]; v == nil || v.Int64() != 3 {
+		t.Errorf("Different value of slot 768! Have %v, want 3", v)
+	}
+
+	res, next, _ := vlt.GetStorageRange(addr, big.NewInt(769), 10)
+	if len(res) != 2 || next != nil {
+		t.Errorf("Range should start from key, have %d slots", len(res))
+	}
+	if _, _, err := vlt.GetStorageRange(addr, nil, 0); err != ErrInvalidRangeLimit {
+		t.Errorf("Expected %s, have %v", ErrInvalidRangeLimit, err)
+	}
+	if _, _, err := vlt.GetStorageRange(addr, new(big.Int).Lsh(big.NewInt(1), 256), 1); err != ErrInvalidStorageKey {
+		t.Errorf("Expected %s, have %v", ErrInvalidStorageKey, err)
+	}
+}
+
+func TestSubscribeBalance(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	vlt.Put(to, types.StateAccount{Address: to, Balance: big.NewInt(0)})
+
+	fromCh, unsubFrom := vlt.SubscribeBalance(from)
+	toCh, unsubTo := vlt.SubscribeBalance(to)
+	defer unsubTo()
+
+	if err := vlt.Transfer(from, to, big.NewInt(30), common.Hash{}); err != nil {
+		t.Fatalf("Error while transfer: %s", err)
+	}
+	if b := <-fromCh; b.Int64() != 70 {
+		t.Errorf("Different balance of sender! Have %d, want 70", b)
+	}
+	if b := <-toCh; b.Int64() != 30 {
+		t.Errorf("Different balance of recipient! Have %d, want 30", b)
+	}
+
+	var cooldown = FaucetCooldown
+	FaucetCooldown = 0
+	defer func() { FaucetCooldown = cooldown }()
+	if err := vlt.FaucetBalance(to, FaucetMinValue); err != nil {
+		t.Fatalf("Error while faucet: %s", err)
+	}
+	var want = new(big.Int).Add(big.NewInt(30), FaucetMinValue)
+	if b := <-toCh; b.Cmp(want) != 0 {
+		t.Errorf("Different balance after faucet! Have %d, want %d", b, want)
+	}
+
+	// slow subscriber does not block vault
+	vlt.Transfer(from, to, big.NewInt(1), common.Hash{})
+	vlt.Transfer(from, to, big.NewInt(1), common.Hash{})
+	if b := <-fromCh; b.Int64() != 69 {
+		t.Errorf("Only first update fits into channel, have %d", b)
+	}
+
+	unsubFrom()
+	unsubFrom()
+	if _, ok := <-fromCh; ok {
+		t.Errorf("Channel should be closed after unsubscribe")
+	}
+	vlt.Transfer(from, to, big.NewInt(1), common.Hash{})
+}
+
+func TestVaultSnapshot(t *testing.T) {
+	memSupply(t)
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	for i := 0; i < 50; i++ {
+		var addr = types.BytesToAddress([]byte{byte(i), 0x1})
+		vlt.Put(addr, types.StateAccount{Address: addr, Balance: big.NewInt(int64(i)), Nonce: uint64(i)})
+	}
+	// balances of accounts were minted by blocks
+	vlt.supply.set(big.NewInt(49 * 50 / 2))
+	var buf bytes.Buffer
+	if err := vlt.ExportSnapshot(&buf); err != nil {
+		t.Fatalf("Error while export snapshot: %s", err)
+	}
+	var snapshot = append([]byte{}, buf.Bytes()...)
+
+	var stale = types.Address{0xf, 0xf}
+	var target = &D5Vault{accounts: GetAccountsTrie(), path: filepath.Join(t.TempDir(), "vault.dat")}
+	target.Put(stale, types.StateAccount{Address: stale, Balance: big.NewInt(1)})
+
+	var written []types.StateAccount
+	replaceVault = func(accounts []types.StateAccount) error {
+		written = accounts
+		return nil
+	}
+	defer func() { replaceVault = ReplaceVault }()
+
+	// truncated or damaged snapshot does not change vault
+	if err := target.ImportSnapshot(bytes.NewReader(snapshot[:len(snapshot)-40])); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("Truncated snapshot should be rejected, have %v", err)
+	}
+	var damaged = append([]byte{}, snapshot...)
+	damaged[len(damaged)-40] ^= 0xff
+	if err := target.ImportSnapshot(bytes.NewReader(damaged)); err == nil {
+		t.Errorf("Damaged snapshot should be rejected")
+	}
+	// balances of snapshot are not covered by its minted supply
+	vlt.supply.set(big.NewInt(1))
+	var inflated bytes.Buffer
+	vlt.ExportSnapshot(&inflated)
+	if err := target.ImportSnapshot(&inflated); !errors.Is(err, ErrSupplyMismatch) {
+		t.Errorf("Snapshot of inflated balances should be rejected, have %v", err)
+	}
+	if target.accounts.Size() != 1 || written != nil {
+		t.Fatalf("Rejected snapshot should not change vault")
+	}
+
+	if err := target.ImportSnapshot(bytes.NewReader(snapshot)); err != nil {
+		t.Fatalf("Error while import snapshot: %s", err)
+	}
+	if target.accounts.Size() != 50 || len(written) != 50 {
+		t.Errorf("Expected 50 accounts in memory and file, have %d and %d", target.accounts.Size(), len(written))
+	}
+	if _, ok := target.accounts.GetAccountOk(stale); ok {
+		t.Errorf("Import should replace all accounts")
+	}
+	var addr = types.BytesToAddress([]byte{byte(7), 0x1})
+	if sa := target.Get(addr); sa.Balance.Int64() != 7 || sa.Nonce != 7 {
+		t.Errorf("Different account after import, have %+v", sa)
+	}
+	if _, err := target.VerifySupply(); err != nil || target.supply.get().Int64() != 49*50/2 {
+		t.Errorf("Minted supply of snapshot should be imported, have %s, %v", target.supply.get(), err)
+	}
+
+	// failed write of vault file keeps memory
+	replaceVault = func(accounts []types.StateAccount) error { return errors.New("disk is full") }
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	buf.Reset()
+	vlt.ExportSnapshot(&buf)
+	if err := target.ImportSnapshot(&buf); err == nil || target.accounts.Size() != 50 {
+		t.Errorf("Failed file write should keep accounts in memory, have %v", err)
+	}
+}
+
+func TestVaultBatch(t *testing.T) {
+	memSupply(t)
+	var a, b, c = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}, types.Address{0x5, 0x6}
+	vlt = D5Vault{accounts: NewAccountsTrie(4), path: filepath.Join(t.TempDir(), "vault.dat")}
+	vlt.Put(a, types.StateAccount{Address: a, Balance: big.NewInt(100)})
+	vlt.Put(b, types.StateAccount{Address: b, Balance: big.NewInt(5)})
+	vlt.supply.set(big.NewInt(105))
+
+	var writes = 0
+	var written []types.StateAccount
+	var fail error
+	replaceVault = func(accounts []types.StateAccount) error {
+		writes++
+		written = accounts
+		return fail
+	}
+	var updates = 0
+	updateAccount = func(account []byte) error {
+		updates++
+		return nil
+	}
+	defer func() { replaceVault, updateAccount = ReplaceVault, UpdateVault }()
+
+	batch := vlt.BeginBatch()
+	batch.Transfer(a, b, big.NewInt(30), common.Hash{})
+	batch.Transfer(b, c, big.NewInt(10), common.Hash{})
+	if vlt.Get(a).Balance.Int64() != 70 || vlt.Get(c).Balance.Int64() != 10 || writes != 0 || updates != 0 {
+		t.Errorf("Memory should change at once and file on commit, have %d writes", writes+updates)
+	}
+	if err := batch.Commit(); err != nil || writes != 1 || len(written) != 3 {
+		t.Errorf("Batch should be written once, have %d writes of %d accounts, %v", writes, len(written), err)
+	}
+
+	// failed commit rolls back memory
+	fail = errors.New("disk is full")
+	batch = vlt.BeginBatch()
+	batch.Transfer(a, b, big.NewInt(70), common.Hash{})
+	if err := batch.Commit(); err == nil {
+		t.Fatalf("Write failure should be returned")
+	}
+	if vlt.Get(a).Balance.Int64() != 70 || vlt.Get(b).Balance.Int64() != 25 || vlt.Get(a).Nonce != 1 {
+		t.Errorf("Memory should be rolled back, have %d and %d", vlt.Get(a).Balance, vlt.Get(b).Balance)
+	}
+	fail = nil
+
+	// changes made by others while batch is open are written at once and
+	// kept when batch is rolled back
+	batch = vlt.BeginBatch()
+	batch.Transfer(b, a, big.NewInt(25), common.Hash{})
+	if err := vlt.FaucetBalance(a, FaucetMinValue); err != nil {
+		t.Fatal(err)
+	}
+	if updates != 1 {
+		t.Errorf("Faucet should be written at once, have %d writes", updates)
+	}
+	batch.Rollback()
+	var want = new(big.Int).Add(big.NewInt(70), FaucetMinValue)
+	if vlt.Get(b).Balance.Int64() != 25 || vlt.Get(a).Balance.Cmp(want) != 0 {
+		t.Errorf("Rollback should keep faucet, have %d and %d", vlt.Get(a).Balance, vlt.Get(b).Balance)
+	}
+	if _, err := vlt.VerifySupply(); err != nil {
+		t.Errorf("Rollback should keep minted supply: %s", err)
+	}
+}
+
+func TestRevertBlock(t *testing.T) {
+	var a, b = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	var input = common.BytesToHash([]byte{0x1})
+	vlt = D5Vault{accounts: NewAccountsTrie(4), inMem: true}
+	vlt.Put(a, types.StateAccount{Address: a, Balance: big.NewInt(100), Nonce: 1})
+
+	batch := vlt.BeginBatch()
+	batch.Transfer(a, b, big.NewInt(30), input)
+	batch.Commit()
+	var undo = batch.Undo()
+	// later changes of accounts are not part of block
+	vlt.FaucetBalance(b, FaucetMinValue)
+
+	if err := vlt.Revert(undo); err != nil {
+		t.Fatal(err)
+	}
+	if sa := vlt.Get(a); sa.Balance.Int64() != 100 || sa.Nonce != 1 {
+		t.Errorf("Expected balance 100 and nonce 1, have %d and %d", sa.Balance, sa.Nonce)
+	}
+	if sa := vlt.Get(b); sa.Balance.Cmp(FaucetMinValue) != 0 || len(sa.Inputs) != 0 {
+		t.Errorf("Expected recipient with faucet only, have %d, inputs %v", sa.Balance, sa.Inputs)
+	}
+}
+
+func TestVerifySupply(t *testing.T) {
+	memSupply(t)
+	var path = filepath.Join(t.TempDir(), "vault.dat")
+	var a, b = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: NewAccountsTrie(4), path: path}
+	vlt.accounts.Append(a, types.StateAccount{Address: a, Balance: big.NewInt(1000)})
+	vlt.supply.set(big.NewInt(1000))
+	updateAccount = func(account []byte) error { return nil }
+	defer func() { updateAccount = UpdateVault }()
+
+	// transfers keep supply, faucet mints
+	if err := vlt.Transfer(a, b, big.NewInt(400), common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vlt.FaucetBalance(b, FaucetMinValue); err != nil {
+		t.Fatal(err)
+	}
+	var want = new(big.Int).Add(big.NewInt(1000), FaucetMinValue)
+	if sum, err := vlt.VerifySupply(); err != nil || sum.Cmp(want) != 0 {
+		t.Errorf("Supply should match, have %s, %v", sum, err)
+	}
+	if minted, _ := readSupply(); minted == nil || minted.Cmp(want) != 0 {
+		t.Errorf("Minted supply should be saved, have %s", minted)
+	}
+
+	// corrupted balance is found on demand and after sync
+	vlt.accounts.Append(a, types.StateAccount{Address: a, Balance: big.NewInt(601)})
+	if _, err := vlt.VerifySupply(); !errors.Is(err, ErrSupplyMismatch) {
+		t.Errorf("Expected %s, have %v", ErrSupplyMismatch, err)
+	}
+	var saA, saB = vlt.Get(a), vlt.Get(b)
+	var data = append(append(saA.Bytes(), '\n'), append(saB.Bytes(), '\n')...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var logs = observeSyncLog(t)
+	if err := SyncVault(path); err != nil {
+		t.Fatal(err)
+	}
+	if logs.FilterLevelExact(zapcore.WarnLevel).FilterMessageSnippet(ErrSupplyMismatch.Error()).Len() != 1 {
+		t.Errorf("Supply mismatch should be logged after sync, have %v", logs.All())
+	}
+}
+
+type located struct {
+	tx     *types.GTransaction
+	from   types.Address
+	height int
+}
+
+// txLocator finds txs of test chain, block time is height*1000
+type txLocator map[common.Hash]located
+
+func (l txLocator) LocateTransaction(hash common.Hash) (*types.GTransaction, types.Address, *block.Block, bool) {
+	var loc, ok = l[hash]
+	if !ok {
+		return nil, types.Address{}, nil, false
+	}
+	var b = block.NewBlockWithHeader(&block.Header{Height: loc.height, Timestamp: uint64(loc.height) * 1000})
+	return loc.tx, loc.from, b, true
+}
+
+func TestTransactionHistory(t *testing.T) {
+	var from, to = types.Address{0x1, 0x2}, types.Address{0x3, 0x4}
+	vlt = D5Vault{accounts: GetAccountsTrie(), inMem: true}
+	vlt.Put(from, types.StateAccount{Address: from, Balance: big.NewInt(100)})
+	if _, err := vlt.TransactionHistory(to, common.Hash{}, 10); err != ErrNoTxLocator {
+		t.Errorf("Expected %s, have %v", ErrNoTxLocator, err)
+	}
+
+	var locator = txLocator{}
+	var hashes []common.Hash
+	for i := 1; i <= 4; i++ {
+		var tx = types.NewTransaction(uint64(i), to, big.NewInt(int64(i)), 10, big.NewInt(100), nil)
+		if err := vlt.Transfer(from, to, big.NewInt(int64(i)), tx.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, tx.Hash())
+		// last input is not in chain
+		if i < 4 {
+			locator[tx.Hash()] = located{tx: tx, from: from, height: i}
+		}
+	}
+	if inputs := vlt.Get(to).Inputs; len(inputs) != 4 || inputs[0] != hashes[0] {
+		t.Fatalf("Transfers should be inputs of recipient, have %v", inputs)
+	}
+	vlt.SetTxLocator(locator)
+
+	records, err := vlt.TransactionHistory(to, common.Hash{}, 2)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Expected 2 records, have %d, %v", len(records), err)
+	}
+	if r := records[0]; r.Hash != hashes[2] || r.Amount.Int64() != 3 || r.Counterparty != from || r.Height != 3 || r.Timestamp != 3000 {
+		t.Errorf("Expected newest tx %s first, have %+v", hashes[2], r)
+	}
+	records, err = vlt.TransactionHistory(to, records[1].Hash, 2)
+	if err != nil || len(records) != 1 || records[0].Hash != hashes[0] {
+		t.Errorf("Expected oldest tx %s on last page, have %v, %v", hashes[0], records, err)
+	}
+
+	data, _ := json.Marshal(records[0])
+	if !strings.Contains(string(data), `"amount":"1"`) || !strings.Contains(string(data), `"hash":"`+hashes[0].Hex()+`"`) {
+		t.Errorf("Record should be in unified format, have %s", data)
+	}
+
+	for _, c := range []struct {
+		name   string
+		addr   types.Address
+		cursor common.Hash
+		limit  int
+		err    error
+	}{
+		{"zero limit", to, common.Hash{}, 0, ErrHistoryLimit},
+		{"large limit", to, common.Hash{}, MaxHistoryLimit + 1, ErrHistoryLimit},
+		{"cursor", to, hashes[3], 2, ErrHistoryCursor},
+		{"account", types.Address{0x9}, common.Hash{}, 2, ErrUnknownAccount},
+	} {
+		if _, err := vlt.TransactionHistory(c.addr, c.cursor, c.limit); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %s, have %v", c.name, c.err, err)
+		}
+	}
+}
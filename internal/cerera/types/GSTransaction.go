@@ -44,6 +44,14 @@ func (tx *GSTransaction) copy() TxData {
 	return cpy
 }
 
+func (tx *GSTransaction) compression() byte {
+	return CompressionNone
+}
+
+func (tx *GSTransaction) scheme() byte {
+	return SigSchemeECDSAP256
+}
+
 func (tx *GSTransaction) txType() byte {
 	return LegacyTxType
 }
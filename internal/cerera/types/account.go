@@ -1,8 +1,10 @@
 package types
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"sort"
 
 	"github.com/cerera/internal/cerera/common"
 )
@@ -16,6 +18,7 @@ type StateAccount struct {
 	Nonce    uint64
 	Root     common.Hash // merkle root of the storage trie
 	Status   string
+	Type     AccountType `json:",omitempty"`
 	// Treasury []*coinbase.CoinBase
 	Inputs     []common.Hash
 	Passphrase common.Hash
@@ -23,9 +26,135 @@ type StateAccount struct {
 	MPub string
 	// MPriv    *bip32.Key
 	Mnemonic string
+	// compressed signing public key, optional
+	PubKey []byte `json:",omitempty"`
+	// label of account set by wallet, node local metadata, not part of state
+	Label string `json:",omitempty"`
 }
 
+// input of account, hash of incoming transaction
+type InputEntry struct {
+	Hash common.Hash `json:"hash"`
+}
+
+// max count of inputs in one page, greater limit is cut to it
+const MaxInputsPage = 1000
+
+// InputsPage returns inputs of account sorted by hash, starting from offset
+// and bounded by limit, and total count of inputs.
+func (sa *StateAccount) InputsPage(offset, limit int) ([]InputEntry, int) {
+	var total = len(sa.Inputs)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || offset >= total {
+		return []InputEntry{}, total
+	}
+
+	var sorted = make([]common.Hash, total)
+	copy(sorted, sa.Inputs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Compare(sorted[j]) < 0
+	})
+
+	if limit > MaxInputsPage {
+		limit = MaxInputsPage
+	}
+	// limit is compared with rest of inputs, offset + limit may overflow
+	if limit > total-offset {
+		limit = total - offset
+	}
+	var end = offset + limit
+	var page = make([]InputEntry, 0, end-offset)
+	for _, h := range sorted[offset:end] {
+		page = append(page, InputEntry{Hash: h})
+	}
+	return page, total
+}
+
+// GetInputSum returns sum of values of input txs of account, values are
+// looked up by tx hash. Inputs without known value are returned as missing.
+func (sa *StateAccount) GetInputSum(values map[common.Hash]*big.Int) (*big.Int, []common.Hash) {
+	var sum = big.NewInt(0)
+	var missing = make([]common.Hash, 0)
+	for _, h := range sa.Inputs {
+		if v, ok := values[h]; ok && v != nil {
+			sum.Add(sum, v)
+		} else {
+			missing = append(missing, h)
+		}
+	}
+	return sum, missing
+}
+
+// GetInput returns value of input tx of account, value is looked up by tx
+// hash. Reports false if tx is not input of account or its value is not known.
+// Returned value is copy.
+func (sa *StateAccount) GetInput(txHash common.Hash, values map[common.Hash]*big.Int) (*big.Int, bool) {
+	for _, h := range sa.Inputs {
+		if h != txHash {
+			continue
+		}
+		if v, ok := values[h]; ok && v != nil {
+			return new(big.Int).Set(v), true
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// DeleteInput removes input tx from account, missing input is ignored.
+func (sa *StateAccount) DeleteInput(txHash common.Hash) {
+	for i, h := range sa.Inputs {
+		if h == txHash {
+			sa.Inputs = append(sa.Inputs[:i:i], sa.Inputs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Copy returns deep copy of account, changes of copy do not affect original.
+func (sa *StateAccount) Copy() *StateAccount {
+	var cpy = *sa
+	if sa.Balance != nil {
+		cpy.Balance = new(big.Int).Set(sa.Balance)
+	}
+	cpy.Bloom = CopyBytes(sa.Bloom)
+	cpy.CodeHash = CopyBytes(sa.CodeHash)
+	cpy.PubKey = CopyBytes(sa.PubKey)
+	if sa.Inputs != nil {
+		cpy.Inputs = make([]common.Hash, len(sa.Inputs))
+		copy(cpy.Inputs, sa.Inputs)
+	}
+	return &cpy
+}
+
+// SetPublicKey stores signing public key of account in compressed form.
+func (sa *StateAccount) SetPublicKey(pub *ecdsa.PublicKey) {
+	var c = CompressPublicKey(pub)
+	sa.PubKey = c[:]
+}
+
+// PublicKey returns signing public key of account, nil if it is not stored.
+func (sa *StateAccount) PublicKey() (*ecdsa.PublicKey, error) {
+	if sa.PubKey == nil {
+		return nil, nil
+	}
+	if len(sa.PubKey) != CompressedPublicKeyLength {
+		return nil, ErrInvalidPubkey
+	}
+	return DecompressPublicKey([CompressedPublicKeyLength]byte(sa.PubKey))
+}
+
+// length of bloom holding counter (byte 1) and its overflow flag (byte 2)
+const bloomCounterLength = 3
+
+// BloomUp increments counter of bloom, on overflow flag is set. Bloom too
+// short for counter is left as is.
 func (sa *StateAccount) BloomUp() {
+	if len(sa.Bloom) < bloomCounterLength {
+		return
+	}
 	var tmpBloom = sa.Bloom[1]
 	if sa.Bloom[1] < 0xf {
 		sa.Bloom[1] = tmpBloom + 0x1
@@ -34,7 +163,12 @@ func (sa *StateAccount) BloomUp() {
 	}
 }
 
+// BloomDown decrements counter of bloom, on underflow flag is set. Bloom too
+// short for counter is left as is.
 func (sa *StateAccount) BloomDown() {
+	if len(sa.Bloom) < bloomCounterLength {
+		return
+	}
 	var tmpBloom = sa.Bloom[1]
 	if sa.Bloom[1] > 0x1 {
 		sa.Bloom[1] = tmpBloom - 0x1
@@ -51,9 +185,10 @@ func (sa *StateAccount) Bytes() []byte {
 	return buf
 }
 
+// BytesToStateAccount decodes account in json or compact encoding, format is detected by first byte.
+// Panics on malformed data, use BytesToStateAccountSafe for untrusted data.
 func BytesToStateAccount(data []byte) StateAccount {
-	sa := &StateAccount{}
-	err := json.Unmarshal(data, sa)
+	sa, err := BytesToStateAccountSafe(data)
 	if err != nil {
 		panic(err)
 	}
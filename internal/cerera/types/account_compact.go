@@ -0,0 +1,281 @@
+package types
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/cerera/internal/cerera/common"
+)
+
+// first byte of compact account encoding, json encoding always starts with '{'
+const (
+	AccountCompactMagic   byte = 0xac
+	AccountCompactVersion byte = 0x1
+)
+
+var ErrInvalidCompactAccount = errors.New("invalid compact account encoding")
+
+// bits of compact header, set bit means field is present
+const (
+	fieldAddress uint16 = 1 << iota
+	fieldBalance
+	fieldBalanceNeg
+	fieldBloom
+	fieldCodeHash
+	fieldName
+	fieldNonce
+	fieldRoot
+	fieldStatus
+	fieldInputs
+	fieldPassphrase
+	fieldMPub
+	fieldMnemonic
+	fieldLabel
+	fieldPubKey
+	fieldType
+)
+
+// BytesCompact encodes account in binary form where zero fields are omitted.
+// Header is magic byte, version and bitmask of present fields.
+func (sa *StateAccount) BytesCompact() []byte {
+	var mask uint16
+	var buf = make([]byte, 4, 64)
+
+	var putBytes = func(b []byte) {
+		buf = binary.AppendUvarint(buf, uint64(len(b)))
+		buf = append(buf, b...)
+	}
+	if sa.Address != (Address{}) {
+		mask |= fieldAddress
+		buf = append(buf, sa.Address[:]...)
+	}
+	// nil and zero balances differ
+	if sa.Balance != nil {
+		mask |= fieldBalance
+		if sa.Balance.Sign() < 0 {
+			mask |= fieldBalanceNeg
+		}
+		putBytes(sa.Balance.Bytes())
+	}
+	// nil and empty slices differ
+	if sa.Bloom != nil {
+		mask |= fieldBloom
+		putBytes(sa.Bloom)
+	}
+	if sa.CodeHash != nil {
+		mask |= fieldCodeHash
+		putBytes(sa.CodeHash)
+	}
+	if sa.Name != "" {
+		mask |= fieldName
+		putBytes([]byte(sa.Name))
+	}
+	if sa.Nonce != 0 {
+		mask |= fieldNonce
+		buf = binary.AppendUvarint(buf, sa.Nonce)
+	}
+	if sa.Root != (common.Hash{}) {
+		mask |= fieldRoot
+		buf = append(buf, sa.Root[:]...)
+	}
+	if sa.Status != "" {
+		mask |= fieldStatus
+		putBytes([]byte(sa.Status))
+	}
+	if sa.Inputs != nil {
+		mask |= fieldInputs
+		buf = binary.AppendUvarint(buf, uint64(len(sa.Inputs)))
+		for _, h := range sa.Inputs {
+			buf = append(buf, h[:]...)
+		}
+	}
+	if sa.Passphrase != (common.Hash{}) {
+		mask |= fieldPassphrase
+		buf = append(buf, sa.Passphrase[:]...)
+	}
+	if sa.MPub != "" {
+		mask |= fieldMPub
+		putBytes([]byte(sa.MPub))
+	}
+	if sa.Mnemonic != "" {
+		mask |= fieldMnemonic
+		putBytes([]byte(sa.Mnemonic))
+	}
+	if sa.Label != "" {
+		mask |= fieldLabel
+		putBytes([]byte(sa.Label))
+	}
+	if sa.PubKey != nil {
+		mask |= fieldPubKey
+		putBytes(sa.PubKey)
+	}
+	if sa.Type != AccountNormal {
+		mask |= fieldType
+		buf = append(buf, byte(sa.Type))
+	}
+
+	buf[0] = AccountCompactMagic
+	buf[1] = AccountCompactVersion
+	binary.BigEndian.PutUint16(buf[2:4], mask)
+	return buf
+}
+
+// BytesToStateAccountCompact decodes account encoded by BytesCompact, nil if data is malformed.
+func BytesToStateAccountCompact(data []byte) *StateAccount {
+	sa, err := decodeCompactAccount(data)
+	if err != nil {
+		return nil
+	}
+	return sa
+}
+
+// compactReader reads fields of compact encoding, first error sticks
+type compactReader struct {
+	data  []byte
+	off   int    // offset of next read in whole encoding
+	field string // field which is read now
+	err   error
+}
+
+func (r *compactReader) fail(err error) {
+	if r.err == nil {
+		r.err = &AccountDecodeError{Field: r.field, Offset: r.off, Err: err}
+	}
+}
+
+func (r *compactReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail(ErrBadLengthPrefix)
+		return 0
+	}
+	r.data = r.data[n:]
+	r.off += n
+	return v
+}
+
+func (r *compactReader) fixed(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.fail(ErrShortBuffer)
+		return nil
+	}
+	var b = r.data[:n]
+	r.data = r.data[n:]
+	r.off += n
+	return b
+}
+
+func (r *compactReader) bytes() []byte {
+	var n = r.uvarint()
+	if r.err == nil && n > uint64(len(r.data)) {
+		r.fail(ErrShortBuffer)
+		return nil
+	}
+	return CopyBytes(r.fixed(int(n)))
+}
+
+func decodeCompactAccount(data []byte) (*StateAccount, error) {
+	if len(data) < 4 {
+		return nil, &AccountDecodeError{Field: "header", Err: ErrShortBuffer}
+	}
+	if data[0] != AccountCompactMagic || data[1] != AccountCompactVersion {
+		return nil, &AccountDecodeError{Field: "header", Err: ErrInvalidCompactAccount}
+	}
+	var mask = binary.BigEndian.Uint16(data[2:4])
+	var r = &compactReader{data: data[4:], off: 4}
+	var sa = &StateAccount{}
+
+	if mask&fieldAddress != 0 {
+		r.field = "Address"
+		copy(sa.Address[:], r.fixed(common.AddressLength))
+	}
+	if mask&fieldBalance != 0 {
+		r.field = "Balance"
+		sa.Balance = new(big.Int).SetBytes(r.bytes())
+		if mask&fieldBalanceNeg != 0 {
+			sa.Balance.Neg(sa.Balance)
+		}
+	}
+	if mask&fieldBloom != 0 {
+		r.field = "Bloom"
+		sa.Bloom = r.bytes()
+	}
+	if mask&fieldCodeHash != 0 {
+		r.field = "CodeHash"
+		sa.CodeHash = r.bytes()
+	}
+	if mask&fieldName != 0 {
+		r.field = "Name"
+		sa.Name = string(r.bytes())
+	}
+	if mask&fieldNonce != 0 {
+		r.field = "Nonce"
+		sa.Nonce = r.uvarint()
+	}
+	if mask&fieldRoot != 0 {
+		r.field = "Root"
+		copy(sa.Root[:], r.fixed(common.HashLength))
+	}
+	if mask&fieldStatus != 0 {
+		r.field = "Status"
+		sa.Status = string(r.bytes())
+	}
+	if mask&fieldInputs != 0 {
+		r.field = "Inputs"
+		var n = r.uvarint()
+		// count can not exceed hashes which fit into rest of data
+		if r.err == nil && n > uint64(len(r.data)/common.HashLength) {
+			r.fail(ErrInputsOverflow)
+		}
+		if r.err == nil {
+			sa.Inputs = make([]common.Hash, n)
+			for i := range sa.Inputs {
+				copy(sa.Inputs[i][:], r.fixed(common.HashLength))
+			}
+		}
+	}
+	if mask&fieldPassphrase != 0 {
+		r.field = "Passphrase"
+		copy(sa.Passphrase[:], r.fixed(common.HashLength))
+	}
+	if mask&fieldMPub != 0 {
+		r.field = "MPub"
+		sa.MPub = string(r.bytes())
+	}
+	if mask&fieldMnemonic != 0 {
+		r.field = "Mnemonic"
+		sa.Mnemonic = string(r.bytes())
+	}
+	if mask&fieldLabel != 0 {
+		r.field = "Label"
+		sa.Label = string(r.bytes())
+	}
+	if mask&fieldPubKey != 0 {
+		r.field = "PubKey"
+		sa.PubKey = r.bytes()
+	}
+	if mask&fieldType != 0 {
+		r.field = "Type"
+		if b := r.fixed(1); b != nil {
+			sa.Type = AccountType(b[0])
+			if !sa.Type.IsValid() {
+				r.fail(ErrInvalidAccountType)
+			}
+		}
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.field = "trailer"
+		r.fail(ErrInvalidCompactAccount)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return sa, nil
+}
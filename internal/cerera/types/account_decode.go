@@ -0,0 +1,56 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrShortBuffer     = errors.New("short buffer")
+	ErrBadLengthPrefix = errors.New("bad length prefix")
+	ErrInputsOverflow  = errors.New("count of inputs exceeds data")
+)
+
+// AccountDecodeError tells which field of encoded account is broken and where.
+type AccountDecodeError struct {
+	Field  string
+	Offset int // offset in encoded data
+	Err    error
+}
+
+func (e *AccountDecodeError) Error() string {
+	return fmt.Sprintf("decode account: field %s at offset %d: %s", e.Field, e.Offset, e.Err)
+}
+
+func (e *AccountDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// BytesToStateAccountSafe decodes account in json or compact encoding,
+// returns *AccountDecodeError instead of panic on malformed data.
+func BytesToStateAccountSafe(data []byte) (*StateAccount, error) {
+	if len(data) == 0 {
+		return nil, &AccountDecodeError{Field: "header", Err: ErrShortBuffer}
+	}
+	if data[0] == AccountCompactMagic {
+		return decodeCompactAccount(data)
+	}
+
+	sa := &StateAccount{}
+	if err := json.Unmarshal(data, sa); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, &AccountDecodeError{Field: "json", Offset: int(syntaxErr.Offset), Err: err}
+		case errors.Is(err, ErrInvalidAccountType):
+			return nil, &AccountDecodeError{Field: "Type", Offset: len(data), Err: err}
+		case errors.As(err, &typeErr):
+			return nil, &AccountDecodeError{Field: typeErr.Field, Offset: int(typeErr.Offset), Err: err}
+		default:
+			return nil, &AccountDecodeError{Field: "json", Offset: len(data), Err: err}
+		}
+	}
+	return sa, nil
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/cerera/internal/cerera/common"
@@ -68,6 +70,23 @@ func TestBloomDown(t *testing.T) {
 	assert.Equal(t, byte(0x2), account.Bloom[1], "BloomDown should decrement the second byte")
 }
 
+func TestBloomShort(t *testing.T) {
+	for _, bloom := range [][]byte{nil, {}, {0x1}, {0x0, 0xf}} {
+		account := StateAccount{Bloom: CopyBytes(bloom)}
+		assert.NotPanics(t, account.BloomUp)
+		assert.NotPanics(t, account.BloomDown)
+		assert.Equal(t, bloom, account.Bloom, "Short bloom should not change")
+	}
+
+	// counter at bounds sets flag
+	account := StateAccount{Bloom: []byte{0x0, 0xf, 0x0}}
+	account.BloomUp()
+	assert.Equal(t, []byte{0x0, 0xf, 0xf}, account.Bloom)
+	account = StateAccount{Bloom: []byte{0x0, 0x1, 0x0}}
+	account.BloomDown()
+	assert.Equal(t, []byte{0x0, 0x1, 0xf}, account.Bloom)
+}
+
 func TestBytes(t *testing.T) {
 	account := CreateTestStateAccount()
 	data := account.Bytes()
@@ -80,3 +99,210 @@ func TestBytesToStateAccount(t *testing.T) {
 	newAccount := BytesToStateAccount(data)
 	assert.Equal(t, account, newAccount, "BytesToStateAccount should return an account identical to the original")
 }
+
+func TestInputsPage(t *testing.T) {
+	account := CreateTestStateAccount()
+	for i := 100; i > 0; i-- {
+		account.Inputs = append(account.Inputs, common.BytesToHash([]byte{byte(i)}))
+	}
+
+	page, total := account.InputsPage(0, 30)
+	assert.Equal(t, 100, total)
+	assert.Len(t, page, 30)
+	assert.Equal(t, common.BytesToHash([]byte{1}), page[0].Hash, "inputs should be sorted by hash")
+	assert.Equal(t, common.BytesToHash([]byte{30}), page[29].Hash)
+
+	page, total = account.InputsPage(90, 30)
+	assert.Equal(t, 100, total)
+	assert.Len(t, page, 10, "last page should be bounded by total")
+	assert.Equal(t, common.BytesToHash([]byte{100}), page[9].Hash)
+
+	page, _ = account.InputsPage(100, 30)
+	assert.Empty(t, page)
+
+	page, _ = account.InputsPage(90, math.MaxInt)
+	assert.Len(t, page, 10, "huge limit should not overflow")
+
+	for i := 0; i < MaxInputsPage; i++ {
+		account.Inputs = append(account.Inputs, common.BytesToHash([]byte{0x1, byte(i >> 8), byte(i)}))
+	}
+	page, _ = account.InputsPage(0, MaxInputsPage+1)
+	assert.Len(t, page, MaxInputsPage, "limit should be capped")
+	account.Inputs = account.Inputs[:100]
+
+	first, _ := account.InputsPage(10, 5)
+	second, _ := account.InputsPage(10, 5)
+	assert.Equal(t, first, second, "pages should be deterministic")
+	assert.Equal(t, common.BytesToHash([]byte{100}), account.Inputs[0], "inputs of account should not be reordered")
+}
+
+func TestGetDeleteInput(t *testing.T) {
+	var a, b = common.BytesToHash([]byte{0x1}), common.BytesToHash([]byte{0x2})
+	var values = map[common.Hash]*big.Int{a: big.NewInt(10), b: big.NewInt(20)}
+
+	// account without inputs
+	var empty = StateAccount{}
+	_, ok := empty.GetInput(a, values)
+	assert.False(t, ok)
+	assert.NotPanics(t, func() { empty.DeleteInput(a) })
+	assert.Nil(t, empty.Inputs)
+
+	account := StateAccount{Inputs: []common.Hash{a, b}}
+	v, ok := account.GetInput(b, values)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), v.Int64())
+	v.SetInt64(0)
+	assert.Equal(t, int64(20), values[b].Int64(), "returned value should be copy")
+
+	// input without known value and missing input
+	_, ok = account.GetInput(a, map[common.Hash]*big.Int{})
+	assert.False(t, ok)
+	_, ok = account.GetInput(common.BytesToHash([]byte{0x3}), values)
+	assert.False(t, ok)
+
+	var cpy = account.Copy()
+	account.DeleteInput(a)
+	account.DeleteInput(common.BytesToHash([]byte{0x3}))
+	assert.Equal(t, []common.Hash{b}, account.Inputs)
+	assert.Equal(t, []common.Hash{a, b}, cpy.Inputs)
+	_, ok = account.GetInput(a, values)
+	assert.False(t, ok)
+}
+
+func TestLabelSerialization(t *testing.T) {
+	account := CreateTestStateAccount()
+	account.Label = "savings"
+	newAccount := BytesToStateAccount(account.Bytes())
+	assert.Equal(t, account, newAccount, "label should survive round trip")
+
+	// accounts written before label field are read with empty label
+	account.Label = ""
+	data := account.Bytes()
+	assert.NotContains(t, string(data), "Label", "empty label should not be written")
+	assert.Equal(t, "", BytesToStateAccount(data).Label)
+}
+
+func TestBytesCompact(t *testing.T) {
+	account := CreateTestStateAccount()
+	account.Label = "savings"
+	account.Inputs = []common.Hash{common.BytesToHash([]byte{0x1}), common.BytesToHash([]byte{0x2})}
+	account.Balance = FloatToBigInt(12.5)
+
+	data := account.BytesCompact()
+	assert.Less(t, len(data), len(account.Bytes()), "compact encoding should be shorter")
+	decoded := BytesToStateAccountCompact(data)
+	assert.NotNil(t, decoded)
+	assert.Equal(t, account, *decoded)
+	assert.Equal(t, account, BytesToStateAccount(data), "format should be detected by magic byte")
+
+	// minimal account keeps nil and empty fields apart
+	minimal := StateAccount{Balance: big.NewInt(0), Bloom: []byte{}}
+	data = minimal.BytesCompact()
+	assert.Equal(t, 4+1+1, len(data), "only header and present fields are written")
+	assert.Equal(t, minimal, *BytesToStateAccountCompact(data))
+
+	negative := StateAccount{Balance: big.NewInt(-42)}
+	assert.Equal(t, negative, *BytesToStateAccountCompact(negative.BytesCompact()))
+
+	assert.Nil(t, BytesToStateAccountCompact(data[:len(data)-1]), "truncated data")
+	assert.Nil(t, BytesToStateAccountCompact(append(data, 0x0)), "trailing data")
+	assert.Nil(t, BytesToStateAccountCompact([]byte("{}")), "json is not compact")
+}
+
+func TestBytesToStateAccountSafe(t *testing.T) {
+	account := CreateTestStateAccount()
+	account.Inputs = []common.Hash{common.BytesToHash([]byte{0x1})}
+
+	decoded, err := BytesToStateAccountSafe(account.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, account.Address, decoded.Address)
+
+	var decodeErr *AccountDecodeError
+	_, err = BytesToStateAccountSafe(nil)
+	assert.ErrorIs(t, err, ErrShortBuffer)
+
+	// truncated json
+	encoded := account.Bytes()
+	_, err = BytesToStateAccountSafe(encoded[:len(encoded)/2])
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "json", decodeErr.Field)
+
+	// json of wrong type
+	_, err = BytesToStateAccountSafe([]byte(`{"Nonce":"one"}`))
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "Nonce", decodeErr.Field)
+
+	compact := account.BytesCompact()
+	_, err = BytesToStateAccountSafe(compact[:10])
+	assert.ErrorIs(t, err, ErrShortBuffer)
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "Address", decodeErr.Field)
+	assert.Equal(t, 4, decodeErr.Offset)
+
+	// length prefix of balance without terminating byte
+	broken := append([]byte{}, compact[:4+48]...)
+	broken = append(broken, 0xff)
+	_, err = BytesToStateAccountSafe(broken)
+	assert.ErrorIs(t, err, ErrBadLengthPrefix)
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "Balance", decodeErr.Field)
+
+	// count of inputs larger than rest of data
+	inputs := StateAccount{Inputs: []common.Hash{{}}}
+	data := inputs.BytesCompact()
+	data[4] = 0x7f
+	_, err = BytesToStateAccountSafe(data)
+	assert.ErrorIs(t, err, ErrInputsOverflow)
+
+	assert.Panics(t, func() { BytesToStateAccount(data) }, "panic based decoder is kept")
+	assert.Nil(t, BytesToStateAccountCompact(data))
+}
+
+func TestAccountPublicKey(t *testing.T) {
+	account := CreateTestStateAccount()
+	pub, err := account.PublicKey()
+	assert.NoError(t, err)
+	assert.Nil(t, pub, "key is optional")
+
+	pk, _ := GenerateAccount()
+	account.SetPublicKey(&pk.PublicKey)
+	assert.Equal(t, CompressedPublicKeyLength, len(account.PubKey))
+	decoded := BytesToStateAccountCompact(account.BytesCompact())
+	assert.NotNil(t, decoded)
+	assert.Equal(t, account, *decoded)
+	pub, err = decoded.PublicKey()
+	assert.NoError(t, err)
+	assert.True(t, pk.PublicKey.Equal(pub))
+
+	decoded.PubKey = decoded.PubKey[1:]
+	_, err = decoded.PublicKey()
+	assert.ErrorIs(t, err, ErrInvalidPubkey)
+}
+
+func TestAccountType(t *testing.T) {
+	assert.Equal(t, "coinbase", AccountCoinbase.String())
+	assert.True(t, AccountCoinbase.IsValid())
+	assert.False(t, AccountType(5).IsValid())
+
+	// normal type is omitted, so accounts encoded before types decode the same
+	account := CreateTestStateAccount()
+	assert.NotContains(t, string(account.Bytes()), `"Type"`)
+
+	account.Type = AccountRoot
+	for _, encoded := range [][]byte{account.Bytes(), account.BytesCompact()} {
+		decoded, err := BytesToStateAccountSafe(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, AccountRoot, decoded.Type)
+	}
+
+	var decodeErr *AccountDecodeError
+	_, err := BytesToStateAccountSafe([]byte(`{"Type":7}`))
+	assert.ErrorIs(t, err, ErrInvalidAccountType)
+	assert.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "Type", decodeErr.Field)
+
+	account.Type = AccountType(9)
+	_, err = BytesToStateAccountSafe(account.BytesCompact())
+	assert.ErrorIs(t, err, ErrInvalidAccountType)
+	assert.Panics(t, func() { account.Bytes() })
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// AccountType is kind of account, encoded as one byte
+type AccountType byte
+
+const (
+	AccountNormal AccountType = iota
+	AccountContract
+	AccountRoot // root account of vault
+	AccountFaucet
+	AccountCoinbase
+)
+
+var ErrInvalidAccountType = errors.New("invalid account type")
+
+func (t AccountType) String() string {
+	switch t {
+	case AccountNormal:
+		return "normal"
+	case AccountContract:
+		return "contract"
+	case AccountRoot:
+		return "root"
+	case AccountFaucet:
+		return "faucet"
+	case AccountCoinbase:
+		return "coinbase"
+	default:
+		return fmt.Sprintf("unknown type %d", byte(t))
+	}
+}
+
+// IsValid reports whether type is one of known account types.
+func (t AccountType) IsValid() bool {
+	return t <= AccountCoinbase
+}
+
+// MarshalJSON encodes type as number, unknown type is not encoded.
+func (t AccountType) MarshalJSON() ([]byte, error) {
+	if !t.IsValid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidAccountType, byte(t))
+	}
+	return json.Marshal(byte(t))
+}
+
+// UnmarshalJSON decodes type from number, unknown type is rejected.
+func (t *AccountType) UnmarshalJSON(input []byte) error {
+	var b byte
+	if err := json.Unmarshal(input, &b); err != nil {
+		return err
+	}
+	if !AccountType(b).IsValid() {
+		return fmt.Errorf("%w: %d", ErrInvalidAccountType, b)
+	}
+	*t = AccountType(b)
+	return nil
+}
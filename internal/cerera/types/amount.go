@@ -0,0 +1,85 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// count of fractional digits of coin, one coin is 10^7 of smallest units
+const CoinDecimals = 7
+
+// max count of fractional digits of decimal amount
+const MaxDecimals = 18
+
+var (
+	ErrInvalidDecimals = errors.New("decimals count is out of range")
+	ErrInvalidAmount   = errors.New("invalid decimal amount")
+	ErrTooManyDecimals = errors.New("too many fractional digits")
+)
+
+// WeiToDecimalString formats amount of smallest units as decimal number with
+// given count of fractional digits, trailing zeros are trimmed. Unlike
+// BigIntToFloat it is exact for any amount.
+func WeiToDecimalString(wei *big.Int, decimals int) string {
+	if wei == nil {
+		return "0"
+	}
+	if decimals <= 0 {
+		return wei.String()
+	}
+	var unit = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	var abs = new(big.Int).Abs(wei)
+	var whole, frac = new(big.Int).QuoRem(abs, unit, new(big.Int))
+
+	var sb strings.Builder
+	if wei.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(whole.String())
+	if digits := strings.TrimRight(fmt.Sprintf("%0*s", decimals, frac.String()), "0"); digits != "" {
+		sb.WriteByte('.')
+		sb.WriteString(digits)
+	}
+	return sb.String()
+}
+
+// DecimalStringToWei parses decimal number to amount of smallest units with
+// given count of fractional digits. Amount with more fractional digits than
+// decimals is rejected, it can not be represented exactly.
+func DecimalStringToWei(s string, decimals int) (*big.Int, error) {
+	if decimals < 0 || decimals > MaxDecimals {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidDecimals, decimals)
+	}
+	var num = strings.TrimSpace(s)
+	var neg bool
+	if strings.HasPrefix(num, "-") || strings.HasPrefix(num, "+") {
+		neg = num[0] == '-'
+		num = num[1:]
+	}
+	whole, frac, _ := strings.Cut(num, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("%w: %q has %d, max %d", ErrTooManyDecimals, s, len(frac), decimals)
+	}
+	var wei, ok = new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if neg {
+		wei.Neg(wei)
+	}
+	return wei, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestWeiToDecimalString(t *testing.T) {
+	var large, _ = new(big.Int).SetString("123456789012345678901234567890", 10)
+	var tests = []struct {
+		wei      *big.Int
+		decimals int
+		want     string
+	}{
+		{nil, CoinDecimals, "0"},
+		{big.NewInt(0), CoinDecimals, "0"},
+		{big.NewInt(10000000), CoinDecimals, "1"},
+		{big.NewInt(15000000), CoinDecimals, "1.5"},
+		{big.NewInt(1), CoinDecimals, "0.0000001"},
+		{big.NewInt(-2500), CoinDecimals, "-0.00025"},
+		{big.NewInt(-30000000), CoinDecimals, "-3"},
+		{big.NewInt(42), 0, "42"},
+		{large, MaxDecimals, "123456789012.34567890123456789"},
+	}
+	for _, tt := range tests {
+		if have := WeiToDecimalString(tt.wei, tt.decimals); have != tt.want {
+			t.Errorf("Format of %s with %d decimals, have %s, want %s", tt.wei, tt.decimals, have, tt.want)
+		}
+	}
+}
+
+func TestDecimalStringToWei(t *testing.T) {
+	var tests = []struct {
+		s    string
+		want int64
+	}{
+		{"0", 0},
+		{"1", 10000000},
+		{"1.5", 15000000},
+		{"1.", 10000000},
+		{".5", 5000000},
+		{"0.0000001", 1},
+		{"-0.00025", -2500},
+		{" +2.10 ", 21000000},
+	}
+	for _, tt := range tests {
+		wei, err := DecimalStringToWei(tt.s, CoinDecimals)
+		if err != nil || wei.Int64() != tt.want {
+			t.Errorf("Parse of %q, have %s, %v, want %d", tt.s, wei, err, tt.want)
+		}
+	}
+
+	var large = "123456789012.345678901234567890"
+	wei, err := DecimalStringToWei(large, MaxDecimals)
+	if err != nil || WeiToDecimalString(wei, MaxDecimals) != "123456789012.34567890123456789" {
+		t.Errorf("Large amount should round trip, have %s, %v", wei, err)
+	}
+
+	for _, s := range []string{"", ".", "-", "1.2.3", "1e5", "0x10", "1,5"} {
+		if _, err := DecimalStringToWei(s, CoinDecimals); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse of %q, expected %s, have %v", s, ErrInvalidAmount, err)
+		}
+	}
+	if _, err := DecimalStringToWei("0.00000001", CoinDecimals); !errors.Is(err, ErrTooManyDecimals) {
+		t.Errorf("Expected %s, have %v", ErrTooManyDecimals, err)
+	}
+	if _, err := DecimalStringToWei("1", MaxDecimals+1); !errors.Is(err, ErrInvalidDecimals) {
+		t.Errorf("Expected %s, have %v", ErrInvalidDecimals, err)
+	}
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// compression of tx data, data is hashed and transferred compressed
+const (
+	CompressionNone byte = 0x0
+	CompressionGzip byte = 0x1
+)
+
+// upper bound of decompressed tx data, protects from compression bombs
+const MaxDecompressedDataSize = 4 << 20
+
+var (
+	ErrUnknownCompression = errors.New("unknown data compression")
+	ErrInvalidCompressed  = errors.New("invalid compressed data")
+	ErrDataTooLarge       = errors.New("decompressed data is too large")
+)
+
+// CompressData compresses tx data with gzip.
+// Data is returned as is with CompressionNone when compression does not shrink it.
+func CompressData(data []byte) (byte, []byte) {
+	if len(data) == 0 {
+		return CompressionNone, data
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := zw.Write(data); err != nil {
+		return CompressionNone, data
+	}
+	if err := zw.Close(); err != nil {
+		return CompressionNone, data
+	}
+	if buf.Len() >= len(data) {
+		return CompressionNone, data
+	}
+	return CompressionGzip, buf.Bytes()
+}
+
+func decompressData(compression byte, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidCompressed
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(io.LimitReader(zr, MaxDecompressedDataSize+1))
+		if err != nil {
+			return nil, ErrInvalidCompressed
+		}
+		if len(out) > MaxDecompressedDataSize {
+			return nil, ErrDataTooLarge
+		}
+		return out, nil
+	default:
+		return nil, ErrUnknownCompression
+	}
+}
+
+// Compression returns id of compression of tx data.
+func (tx *GTransaction) Compression() byte {
+	return tx.inner.compression()
+}
+
+// RawData returns data as carried by tx, compressed if tx is compressed.
+func (tx *GTransaction) RawData() []byte { return tx.inner.data() }
+
+// DecodeData returns decompressed data of tx.
+func (tx *GTransaction) DecodeData() ([]byte, error) {
+	return decompressData(tx.inner.compression(), tx.inner.data())
+}
@@ -0,0 +1,20 @@
+package types
+
+import "math"
+
+// GasSchedule is cost of tx before execution: base cost of every tx and cost
+// of each byte of its data
+type GasSchedule struct {
+	Base    uint64
+	PerByte uint64
+}
+
+// IntrinsicGas returns gas tx needs for its size, so large data is not
+// carried for the price of empty tx. Result is capped by max uint64.
+func (tx *GTransaction) IntrinsicGas(s GasSchedule) uint64 {
+	var size = uint64(len(tx.Data()))
+	if s.PerByte > 0 && size > (math.MaxUint64-s.Base)/s.PerByte {
+		return math.MaxUint64
+	}
+	return s.Base + size*s.PerByte
+}
@@ -3,13 +3,17 @@ package types
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"hash"
 	"math/big"
+	"strings"
 
 	"github.com/cerera/internal/cerera/common"
 	"golang.org/x/crypto/blake2b"
@@ -26,19 +30,23 @@ type INRI interface {
 	hash.Hash
 }
 
+// NewINRISeq returns incremental hasher giving the same 64 byte output as
+// INRISeq for data written in any number of parts. Sum does not change state,
+// so more data can be written after it, Reset restarts hashing from scratch.
+// Write does not allocate, data is not buffered.
 func NewINRISeq() INRI {
 	state, _ := blake2b.New512(nil)
 	return state.(INRI)
 }
 
+// INRISeq returns 64 byte hash of concatenated data.
 func INRISeq(data ...[]byte) []byte {
-	b := make([]byte, 48)
 	d := NewINRISeq()
 
 	for _, b := range data {
 		d.Write(b)
 	}
-	return d.Sum(b)
+	return d.Sum(nil)
 }
 
 func INRISeqHash(data ...[]byte) (h common.Hash) {
@@ -67,12 +75,16 @@ func PubkeyToAddress(p ecdsa.PublicKey) Address {
 func PrivKeyToAddress(p ecdsa.PrivateKey) Address {
 	pubBytes := FromECDSAPub(&p.PublicKey)
 
-	return BytesToAddress(INRISeq(pubBytes[1:])[32:])
+	return BytesToAddress(INRISeq(pubBytes[1:])[16:])
 }
 
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var ErrInvalidBase58 = errors.New("invalid base58 string")
+
 // Base58Encode encodes a byte slice to a base58 string
 func Base58Encode(input []byte) string {
-	alphabet := "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	alphabet := base58Alphabet
 
 	// Encoding as big-endian integers
 	x := new(big.Int).SetBytes(input)
@@ -98,6 +110,27 @@ func Base58Encode(input []byte) string {
 	return output
 }
 
+// Base58Decode decodes a base58 string produced by Base58Encode
+func Base58Decode(input string) ([]byte, error) {
+	x := new(big.Int)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	for _, c := range []byte(input) {
+		idx := strings.IndexByte(base58Alphabet, c)
+		if idx < 0 {
+			return nil, ErrInvalidBase58
+		}
+		x.Mul(x, radix)
+		x.Add(x, big.NewInt(int64(idx)))
+	}
+
+	// Decoding leading zeros
+	var zeros int
+	for zeros < len(input) && input[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
+
 func GenerateKey() (*ecdsa.PrivateKey, error) {
 	return ecdsa.GenerateKey(chainElliptic, rand.Reader)
 }
@@ -152,6 +185,54 @@ func GenerateAccount() (*ecdsa.PrivateKey, error) {
 	return pk, nil
 }
 
+// min length of bip32 seed
+const MinSeedLength = 16
+
+var ErrShortSeed = errors.New("seed is too short for key derivation")
+
+// key of hmac separating signing key from other keys of the same seed
+var signingKeyDomain = []byte("cerera signing key")
+
+// DeriveSigningKeyFromSeed derives signing key of account from bip32 seed of
+// mnemonic, the same seed always gives the same key. Scalar of key is taken
+// from hmac of seed reduced to range [1, N-1] of curve.
+func DeriveSigningKeyFromSeed(seed []byte) (*ecdsa.PrivateKey, error) {
+	if len(seed) < MinSeedLength {
+		return nil, ErrShortSeed
+	}
+	var mac = hmac.New(sha512.New, signingKeyDomain)
+	mac.Write(seed)
+	var n = new(big.Int).Sub(chainElliptic.Params().N, big.NewInt(1))
+	var d = new(big.Int).SetBytes(mac.Sum(nil))
+	d.Mod(d, n).Add(d, big.NewInt(1))
+
+	var pk = new(ecdsa.PrivateKey)
+	pk.Curve = chainElliptic
+	pk.D = d
+	pk.X, pk.Y = chainElliptic.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+	return pk, nil
+}
+
+// length of SEC1 compressed public key
+const CompressedPublicKeyLength = 33
+
+// CompressPublicKey encodes public key as SEC1 compressed point:
+// prefix 0x2 or 0x3 by parity of Y and X coordinate.
+func CompressPublicKey(pub *ecdsa.PublicKey) [CompressedPublicKeyLength]byte {
+	var res [CompressedPublicKeyLength]byte
+	copy(res[:], elliptic.MarshalCompressed(chainElliptic, pub.X, pub.Y))
+	return res
+}
+
+// DecompressPublicKey decodes SEC1 compressed point, point which is not on curve is rejected.
+func DecompressPublicKey(data [CompressedPublicKeyLength]byte) (*ecdsa.PublicKey, error) {
+	x, y := elliptic.UnmarshalCompressed(chainElliptic, data[:])
+	if x == nil {
+		return nil, ErrInvalidPubkey
+	}
+	return &ecdsa.PublicKey{Curve: chainElliptic, X: x, Y: y}, nil
+}
+
 func EncodeKeys(privateKey *ecdsa.PrivateKey) (string, string) {
 	x509Encoded, _ := x509.MarshalECPrivateKey(privateKey)
 	pemEncoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: x509Encoded})
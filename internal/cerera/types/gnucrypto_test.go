@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -184,3 +185,117 @@ func TestEncodePrivateKeyToToString(t *testing.T) {
 		t.Fatalf("Decoded private key does not match the original private key")
 	}
 }
+
+func TestDeriveSigningKeyFromSeed(t *testing.T) {
+	var seed = []byte("0123456789abcdef0123456789abcdef")
+	k1, err := DeriveSigningKeyFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, _ := DeriveSigningKeyFromSeed(seed)
+	if k1.D.Cmp(k2.D) != 0 || PubkeyToAddress(k1.PublicKey) != PubkeyToAddress(k2.PublicKey) {
+		t.Errorf("Same seed should give same key")
+	}
+	if !k1.Curve.IsOnCurve(k1.X, k1.Y) {
+		t.Errorf("Public key should be on curve")
+	}
+	seed[0] ^= 0x1
+	if k3, _ := DeriveSigningKeyFromSeed(seed); k3.D.Cmp(k1.D) == 0 {
+		t.Errorf("Different seeds should give different keys")
+	}
+	if _, err := DeriveSigningKeyFromSeed(seed[:MinSeedLength-1]); err != ErrShortSeed {
+		t.Errorf("Expected %s, have %v", ErrShortSeed, err)
+	}
+}
+
+func TestINRISeqStreaming(t *testing.T) {
+	var data = make([]byte, 1<<20+17)
+	rand.Read(data)
+	var want = INRISeq(data[:1000], data[1000:])
+	if len(want) != 64 {
+		t.Fatalf("Expected 64 byte hash, have %d", len(want))
+	}
+	if !bytes.Equal(want, INRISeq(data)) {
+		t.Errorf("Hash should not depend on split of data")
+	}
+
+	var d = NewINRISeq()
+	for _, chunk := range []int{1, 7, 4096, 65536} {
+		d.Reset()
+		for i := 0; i < len(data); i += chunk {
+			d.Write(data[i:min(i+chunk, len(data))])
+		}
+		if !bytes.Equal(d.Sum(nil), want) {
+			t.Errorf("Streaming hash by %d bytes differs from one-shot", chunk)
+		}
+	}
+
+	// sum keeps state, writes after it continue data
+	d.Reset()
+	d.Write(data[:10])
+	d.Sum(nil)
+	d.Write(data[10:20])
+	if !bytes.Equal(d.Sum(nil), INRISeq(data[:20])) {
+		t.Errorf("Sum should not change state of hasher")
+	}
+	d.Reset()
+	if !bytes.Equal(d.Sum(nil), INRISeq()) {
+		t.Errorf("Reset should restart hashing")
+	}
+}
+
+func BenchmarkINRISeq(b *testing.B) {
+	var data = make([]byte, 4<<20)
+	rand.Read(data)
+	b.Run("oneshot", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			INRISeq(data)
+		}
+	})
+	b.Run("streaming", func(b *testing.B) {
+		var d = NewINRISeq()
+		var sum = make([]byte, 0, 64)
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			d.Reset()
+			for j := 0; j < len(data); j += 32 << 10 {
+				d.Write(data[j : j+32<<10])
+			}
+			sum = d.Sum(sum[:0])
+		}
+	})
+}
+
+func TestCompressPublicKey(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		pk, _ := GenerateAccount()
+		var c = CompressPublicKey(&pk.PublicKey)
+		if c[0] != 0x2 && c[0] != 0x3 {
+			t.Fatalf("Invalid prefix of compressed key: %x", c[0])
+		}
+		pub, err := DecompressPublicKey(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pk.PublicKey.Equal(pub) {
+			t.Fatalf("Different key after decompression")
+		}
+	}
+
+	var pk, _ = GenerateAccount()
+	var c = CompressPublicKey(&pk.PublicKey)
+	var badPrefix = c
+	badPrefix[0] = 0x4
+	// x of p is out of field
+	var outOfField [CompressedPublicKeyLength]byte
+	outOfField[0] = 0x2
+	elliptic.P256().Params().P.FillBytes(outOfField[1:])
+	for _, bad := range [][CompressedPublicKeyLength]byte{badPrefix, outOfField, {}} {
+		if _, err := DecompressPublicKey(bad); err != ErrInvalidPubkey {
+			t.Errorf("Expected %s for %x, have %v", ErrInvalidPubkey, bad, err)
+		}
+	}
+}
@@ -26,6 +26,7 @@ var (
 	ErrUint64Range   = &decError{"hex number > 64 bits"}
 	ErrUintRange     = &decError{fmt.Sprintf("hex number > %d bits", uintBits)}
 	ErrBig256Range   = &decError{"hex number > 256 bits"}
+	ErrBig512Range   = &decError{"hex number > 512 bits"}
 )
 
 type decError struct{ msg string }
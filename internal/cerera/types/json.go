@@ -119,8 +119,8 @@ func UnmarshalFixedUnprefixedText(typname string, input, out []byte) error {
 // The zero value marshals as "0x0".
 //
 // Negative integers are not supported at this time. Attempting to marshal them will
-// return an error. Values larger than 256bits are rejected by Unmarshal but will be
-// marshaled without error.
+// return an error. Values larger than 512bits are rejected by Unmarshal but will be
+// marshaled without error. Big keeps signature values of tx, v keeps r||s.
 type Big big.Int
 
 // MarshalText implements encoding.TextMarshaler
@@ -142,8 +142,8 @@ func (b *Big) UnmarshalText(input []byte) error {
 	if err != nil {
 		return err
 	}
-	if len(raw) > 64 {
-		return ErrBig256Range
+	if len(raw) > 128 {
+		return ErrBig512Range
 	}
 	words := make([]big.Word, len(raw)/bigWordNibbles+1)
 	end := len(raw)
@@ -21,6 +21,9 @@ type PGTransaction struct {
 
 	Payload []byte
 	FullGas *big.Int
+	Scheme  byte // signature scheme, SigSchemeECDSAP256 by default
+	// compression of Data, CompressionNone by default
+	Compression byte
 }
 
 func NewTransactionEnrich(nonce uint64,
@@ -54,7 +57,8 @@ func NewTransaction(nonce uint64,
 		Gas:      gasLimit,
 		GasPrice: gasPrice,
 		Data:     data,
-		Time:     time.Now(),
+		// zone is hashed, Local with zero offset comes back from json as UTC
+		Time: time.Now().UTC(),
 	})
 }
 
@@ -79,6 +83,9 @@ func (tx *PGTransaction) copy() TxData {
 		S:        new(big.Int),
 		Payload:  CopyBytes(tx.Payload),
 		Time:     tx.time(),
+		Scheme:   tx.Scheme,
+
+		Compression: tx.Compression,
 	}
 	if tx.Value != nil {
 		cpy.Value.Set(tx.Value)
@@ -98,6 +105,10 @@ func (tx *PGTransaction) copy() TxData {
 	return cpy
 }
 
+func (tx *PGTransaction) compression() byte {
+	return tx.Compression
+}
+
 func (tx *PGTransaction) nonce() uint64 {
 	return tx.Nonce
 }
@@ -130,6 +141,10 @@ func (tx *PGTransaction) to() *Address {
 	return tx.To
 }
 
+func (tx *PGTransaction) scheme() byte {
+	return tx.Scheme
+}
+
 func (tx *PGTransaction) txType() byte {
 	return LegacyTxType
 }
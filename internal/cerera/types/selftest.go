@@ -0,0 +1,120 @@
+package types
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+var ErrSelfTest = errors.New("crypto self-test failed")
+
+// encoders checked by self-test, replaced in tests to simulate broken build
+var (
+	accountEncoder = func(sa *StateAccount) []byte { return sa.Bytes() }
+	base58Encoder  = Base58Encode
+)
+
+// SelfTest checks round-trips of keys, addresses, signatures and encodings.
+// Returns error which names the broken step.
+func SelfTest() error {
+	var steps = []struct {
+		name string
+		fn   func(*ecdsa.PrivateKey) error
+	}{
+		{"key encoding", selfTestKey},
+		{"sign and recover", selfTestSign},
+		{"account encoding", selfTestAccount},
+		{"base58", selfTestBase58},
+	}
+	pk, err := GenerateAccount()
+	if err != nil {
+		return fmt.Errorf("%w: generate key: %s", ErrSelfTest, err)
+	}
+	for _, step := range steps {
+		if err := step.fn(pk); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrSelfTest, step.name, err)
+		}
+	}
+	return nil
+}
+
+func selfTestKey(pk *ecdsa.PrivateKey) error {
+	decoded := DecodePrivKey(EncodePrivateKeyToToString(pk))
+	if decoded == nil || decoded.D.Cmp(pk.D) != 0 {
+		return errors.New("private key differs after pem round-trip")
+	}
+	if PubkeyToAddress(decoded.PublicKey) != PubkeyToAddress(pk.PublicKey) {
+		return errors.New("address differs after pem round-trip")
+	}
+	return nil
+}
+
+func selfTestSign(pk *ecdsa.PrivateKey) error {
+	msg := []byte("cerera self-test")
+	sig, err := Sign(msg, pk)
+	if err != nil {
+		return err
+	}
+	// signature is r, s and public key of signer
+	n := (pk.Curve.Params().N.BitLen() + 7) / 8
+	if len(sig) <= 2*n {
+		return ErrInvalidSignatureLen
+	}
+	r := new(big.Int).SetBytes(sig[:n])
+	s := new(big.Int).SetBytes(sig[n : 2*n])
+	keyLen := (len(sig) - 2*n) / 2
+	pub := ecdsa.PublicKey{
+		Curve: pk.Curve,
+		X:     new(big.Int).SetBytes(sig[2*n : 2*n+keyLen]),
+		Y:     new(big.Int).SetBytes(sig[2*n+keyLen:]),
+	}
+	h := blake2b.Sum256(msg)
+	if !ecdsa.Verify(&pub, h[:], r, s) {
+		return errors.New("signature does not verify")
+	}
+	if PubkeyToAddress(pub) != PubkeyToAddress(pk.PublicKey) {
+		return errors.New("recovered address differs from signer")
+	}
+	return nil
+}
+
+func selfTestAccount(pk *ecdsa.PrivateKey) (err error) {
+	// decoding of broken data panics
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decode: %v", r)
+		}
+	}()
+	sa := &StateAccount{
+		Address: PubkeyToAddress(pk.PublicKey),
+		Balance: big.NewInt(1234567890),
+		Nonce:   7,
+		MPub:    Base58Encode(FromECDSAPub(&pk.PublicKey)),
+	}
+	decoded := BytesToStateAccount(accountEncoder(sa))
+	if decoded.Address != sa.Address || decoded.Nonce != sa.Nonce ||
+		decoded.Balance == nil || decoded.Balance.Cmp(sa.Balance) != 0 {
+		return errors.New("account differs after round-trip")
+	}
+	if decoded.MPub != sa.MPub {
+		return errors.New("master public key differs after round-trip")
+	}
+	return nil
+}
+
+func selfTestBase58(pk *ecdsa.PrivateKey) error {
+	// leading zeros are encoded separately
+	input := append([]byte{0, 0}, FromECDSAPub(&pk.PublicKey)...)
+	decoded, err := Base58Decode(base58Encoder(input))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(decoded, input) {
+		return errors.New("data differs after round-trip")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("Self-test should pass on healthy build, have %s", err)
+	}
+
+	var origBase58 = base58Encoder
+	base58Encoder = func(input []byte) string {
+		// drops last byte
+		return Base58Encode(input[:len(input)-1])
+	}
+	err := SelfTest()
+	base58Encoder = origBase58
+	if !errors.Is(err, ErrSelfTest) {
+		t.Errorf("Self-test should fail with broken base58 encoder, have %v", err)
+	}
+
+	var origAccount = accountEncoder
+	accountEncoder = func(sa *StateAccount) []byte {
+		// truncated master public key
+		var broken = *sa
+		broken.MPub = sa.MPub[:len(sa.MPub)/2]
+		return broken.Bytes()
+	}
+	err = SelfTest()
+	accountEncoder = origAccount
+	if !errors.Is(err, ErrSelfTest) {
+		t.Errorf("Self-test should fail with broken account encoder, have %v", err)
+	}
+
+	accountEncoder = func(sa *StateAccount) []byte { return []byte("{") }
+	err = SelfTest()
+	accountEncoder = origAccount
+	if !errors.Is(err, ErrSelfTest) {
+		t.Errorf("Self-test should fail on undecodable account, have %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package types
+
+import "errors"
+
+// signature schemes of transactions, verification is dispatched by scheme id
+const (
+	SigSchemeECDSAP256 byte = 0x0 // ecdsa over p256, asn.1 free r||s signature
+)
+
+var ErrUnknownSigScheme = errors.New("unknown signature scheme")
+
+// KnownSigScheme reports whether node can verify signatures of scheme.
+func KnownSigScheme(scheme byte) bool {
+	switch scheme {
+	case SigSchemeECDSAP256:
+		return true
+	}
+	return false
+}
+
+// SigScheme returns id of signature scheme of tx.
+func (tx *GTransaction) SigScheme() byte {
+	return tx.inner.scheme()
+}
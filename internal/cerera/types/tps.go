@@ -26,6 +26,7 @@ var (
 	ErrSignFailed          = errors.New("signing failed")
 	ErrRecoverFailed       = errors.New("recovery failed")
 	ErrTxTypeNotSupported  = errors.New("Tx not supported")
+	ErrNonCanonicalAddress = errors.New("address is not canonical")
 )
 
 type Address [common.AddressLength]byte
@@ -132,6 +133,26 @@ func (a Address) IsEmpty() bool {
 	return cnt == len(bts)
 }
 
+// count of zero bytes at edge of address which marks it as built from short input
+const MaxAddressZeroPad = 8
+
+// IsCanonical reports whether address has full length. Addresses are taken
+// from key hash, so zero address or address padded with zeros at start or
+// at end (like HexToAddress("0x0102") or Address{0x1, 0x2}) is malformed.
+func (a Address) IsCanonical() bool {
+	var lead, trail = 0, 0
+	for lead < len(a) && a[lead] == 0x0 {
+		lead++
+	}
+	if lead == len(a) {
+		return false
+	}
+	for trail < len(a) && a[len(a)-1-trail] == 0x0 {
+		trail++
+	}
+	return lead < MaxAddressZeroPad && trail < MaxAddressZeroPad
+}
+
 func (a Address) MarshalText() ([]byte, error) {
 	// fmt.Printf("call marshal of address: %s\r\n", a.Hex())
 	return common.Bytes(a[:]).MarshalText()
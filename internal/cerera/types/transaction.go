@@ -46,6 +46,8 @@ type TxData interface {
 
 	dna() []byte
 	time() time.Time
+	scheme() byte
+	compression() byte
 
 	rawSignatureValues() (r, s, v *big.Int)
 	setSignatureValues(chainID, r, s, v *big.Int)
@@ -60,6 +62,9 @@ type txJSON struct {
 	Type    common.Uint64 `json:"type,omitempty"`
 	To      *Address      `json:"to,omitempty"`
 	Time    time.Time     `json:"time,omitempty"`
+	Scheme  common.Uint64 `json:"scheme,omitempty"`
+	// compression of data
+	Compression common.Uint64 `json:"compression,omitempty"`
 	// Common transaction fields:
 	Dna      *common.Bytes  `json:"dna,omitempty"`
 	GasPrice *common.Big    `json:"gasPrice,omitempty"`
@@ -178,10 +183,50 @@ func (tx *GTransaction) Value() *big.Int {
 	return new(big.Int).Set(tx.inner.value())
 }
 
-func (tx *GTransaction) Data() []byte { return tx.inner.data() }
+// Data returns decompressed data of tx, nil if data can not be decompressed.
+func (tx *GTransaction) Data() []byte {
+	data, err := tx.DecodeData()
+	if err != nil {
+		return nil
+	}
+	return data
+}
 
 func (tx *GTransaction) Dna() []byte { return tx.inner.dna() }
 
+// length of method selector at the beginning of contract call data
+const MethodSelectorLength = 4
+
+// IsContractCreation reports whether tx deploys a contract, i.e. has no recipient.
+func (tx *GTransaction) IsContractCreation() bool {
+	return tx.inner.to() == nil
+}
+
+// MethodSelector returns first bytes of data which select contract method.
+// Returns false for contract creation and for data shorter than selector.
+func (tx *GTransaction) MethodSelector() ([MethodSelectorLength]byte, bool) {
+	var selector [MethodSelectorLength]byte
+	var data = tx.Data()
+	if tx.IsContractCreation() || len(data) < MethodSelectorLength {
+		return selector, false
+	}
+	copy(selector[:], data[:MethodSelectorLength])
+	return selector, true
+}
+
+// CallData returns arguments of contract method, data without selector.
+// For contract creation whole data is returned as contract code.
+func (tx *GTransaction) CallData() []byte {
+	var data = tx.Data()
+	if tx.IsContractCreation() {
+		return CopyBytes(data)
+	}
+	if len(data) < MethodSelectorLength {
+		return nil
+	}
+	return CopyBytes(data[MethodSelectorLength:])
+}
+
 func (tx *GTransaction) Size() uint64 {
 	if size := tx.size.Load(); size != nil {
 		return size.(uint64)
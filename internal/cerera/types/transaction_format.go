@@ -0,0 +1,125 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/cerera/internal/cerera/common"
+)
+
+var ErrInvalidTxFormat = errors.New("invalid transaction format")
+
+// txFormatJSON is unified format of transaction for api clients: bytes,
+// hashes and addresses are hex, amounts are decimal strings, gas and nonce
+// are plain numbers. MarshalJSON encoding is kept for blocks, since hashes
+// of stored blocks depend on it.
+type txFormatJSON struct {
+	Hash        common.Hash  `json:"hash"`
+	Type        uint8        `json:"type"`
+	From        Address      `json:"from"`
+	To          *Address     `json:"to"`
+	Nonce       uint64       `json:"nonce"`
+	Gas         uint64       `json:"gas"`
+	GasPrice    string       `json:"gasPrice"`
+	Value       string       `json:"value"`
+	Data        common.Bytes `json:"data"`
+	Payload     common.Bytes `json:"payload"`
+	Dna         common.Bytes `json:"dna"`
+	Time        time.Time    `json:"time"`
+	Scheme      byte         `json:"scheme"`
+	Compression byte         `json:"compression"`
+	V           *Big         `json:"v"`
+	R           *Big         `json:"r"`
+	S           *Big         `json:"s"`
+}
+
+func decimalOrZero(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+func parseDecimal(field, s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("%w: %s %q", ErrInvalidTxFormat, field, s)
+	}
+	return v, nil
+}
+
+// MarshalFormatJSON encodes transaction in unified format. Sender is
+// included when it is known from signing or Sender.
+func (tx *GTransaction) MarshalFormatJSON() ([]byte, error) {
+	itx, ok := tx.inner.(*PGTransaction)
+	if !ok {
+		return nil, ErrTxTypeNotSupported
+	}
+	var r, s, v = tx.RawSignatureValues()
+	return json.Marshal(txFormatJSON{
+		Hash:        tx.Hash(),
+		Type:        tx.Type(),
+		From:        tx.From(),
+		To:          tx.To(),
+		Nonce:       itx.Nonce,
+		Gas:         itx.Gas,
+		GasPrice:    decimalOrZero(itx.GasPrice),
+		Value:       decimalOrZero(itx.Value),
+		Data:        itx.Data,
+		Payload:     itx.Payload,
+		Dna:         itx.Dna,
+		Time:        tx.GetTime(),
+		Scheme:      itx.Scheme,
+		Compression: itx.Compression,
+		V:           (*Big)(v),
+		R:           (*Big)(r),
+		S:           (*Big)(s),
+	})
+}
+
+// UnmarshalFormatJSON decodes transaction in unified format. Sender is not
+// taken from input, it is recovered from signature.
+func (tx *GTransaction) UnmarshalFormatJSON(input []byte) error {
+	var dec txFormatJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Type != LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
+	if dec.To == nil {
+		return fmt.Errorf("%w: missing to", ErrInvalidTxFormat)
+	}
+	if dec.V == nil || dec.R == nil || dec.S == nil {
+		return fmt.Errorf("%w: missing signature values", ErrInvalidTxFormat)
+	}
+	gasPrice, err := parseDecimal("gasPrice", dec.GasPrice)
+	if err != nil {
+		return err
+	}
+	value, err := parseDecimal("value", dec.Value)
+	if err != nil {
+		return err
+	}
+	var itx = &PGTransaction{
+		Nonce:       dec.Nonce,
+		GasPrice:    gasPrice,
+		Gas:         dec.Gas,
+		To:          dec.To,
+		Value:       value,
+		Data:        dec.Data,
+		Payload:     dec.Payload,
+		Dna:         dec.Dna,
+		Time:        dec.Time,
+		Scheme:      dec.Scheme,
+		Compression: dec.Compression,
+		V:           (*big.Int)(dec.V),
+		R:           (*big.Int)(dec.R),
+		S:           (*big.Int)(dec.S),
+	}
+	tx.setDecoded(itx, uint64(len(input)))
+	return nil
+}
@@ -36,6 +36,8 @@ func (tx *GTransaction) MarshalJSON() ([]byte, error) {
 		enc.Type = 4
 		enc.Hash = tx.Hash()
 		enc.Payload = (*common.Bytes)(&itx.Payload)
+		enc.Scheme = common.Uint64(itx.Scheme)
+		enc.Compression = common.Uint64(itx.Compression)
 		var r, s, v = tx.RawSignatureValues()
 		enc.R = (*Big)(r)
 		enc.S = (*Big)(s)
@@ -139,6 +141,16 @@ func (tx *GTransaction) UnmarshalJSON(input []byte) error {
 		itx.Dna = *dec.Dna
 
 		itx.Time = dec.Time
+
+		if dec.Scheme > 0xff {
+			return ErrUnknownSigScheme
+		}
+		itx.Scheme = byte(dec.Scheme)
+
+		if dec.Compression > 0xff {
+			return ErrUnknownCompression
+		}
+		itx.Compression = byte(dec.Compression)
 	default:
 		return ErrTxTypeNotSupported
 	}
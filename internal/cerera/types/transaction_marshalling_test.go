@@ -1 +1,78 @@
 package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionFormat(t *testing.T) {
+	acc, _ := GenerateAccount()
+	var to = HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	var value, _ = new(big.Int).SetString("123456789012345678901234567890", 10)
+	itx := NewTx(&PGTransaction{
+		To:       &to,
+		Value:    value,
+		GasPrice: big.NewInt(15),
+		Gas:      1000000,
+		Nonce:    0x7,
+		Data:     []byte{0xca, 0xfe},
+		Dna:      []byte{0x1, 0x2},
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	signer := NewSimpleSignerWithPen(big.NewInt(25331), acc)
+	tx, err := SignTx(itx, signer, acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, _ := Sender(signer, tx)
+
+	data, err := tx.MarshalFormatJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var want = map[string]interface{}{
+		"hash":     tx.Hash().Hex(),
+		"from":     strings.ToLower(from.Hex()),
+		"to":       strings.ToLower(to.Hex()),
+		"data":     "0xcafe",
+		"value":    "123456789012345678901234567890",
+		"gasPrice": "15",
+		"gas":      float64(1000000),
+		"nonce":    float64(7),
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("Field %s, have %v, want %v", k, fields[k], v)
+		}
+	}
+
+	var dec GTransaction
+	if err := dec.UnmarshalFormatJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	r, s, v := tx.RawSignatureValues()
+	dr, ds, dv := dec.RawSignatureValues()
+	if dec.Hash() != tx.Hash() || dec.Value().Cmp(value) != 0 || dec.Gas() != tx.Gas() || !bytes.Equal(dec.Data(), tx.Data()) {
+		t.Errorf("Decoded tx differs, have hash %s, want %s", dec.Hash(), tx.Hash())
+	}
+	if dr.Cmp(r) != 0 || ds.Cmp(s) != 0 || dv.Cmp(v) != 0 {
+		t.Errorf("Signature values differ after round trip")
+	}
+	if dfrom, err := Sender(signer, &dec); err != nil || dfrom != from {
+		t.Errorf("Sender should be recovered from signature, have %s, %v", dfrom, err)
+	}
+
+	fields["value"] = "-1"
+	bad, _ := json.Marshal(fields)
+	if err := dec.UnmarshalFormatJSON(bad); err == nil {
+		t.Errorf("Negative value should be rejected")
+	}
+}
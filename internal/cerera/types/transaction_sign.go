@@ -25,6 +25,8 @@ type sigCache struct {
 
 // in this file only work with GTransacion now
 
+// Sign returns r||s of signature of msg followed by public key x||y of
+// signer, each value is padded to byte length of curve order.
 func Sign(msg []byte, privKey *ecdsa.PrivateKey) ([]byte, error) {
 	// fmt.Println("message lenght (tx): ", len(msg))
 	h := blake2b.Sum256(msg)
@@ -42,10 +44,9 @@ func Sign(msg []byte, privKey *ecdsa.PrivateKey) ([]byte, error) {
 	copy(signature[n-len(rb):], rb)
 	copy(signature[2*n-len(sb):], sb)
 
-	xBytes, yBytes := privKey.PublicKey.X.Bytes(), privKey.PublicKey.Y.Bytes()
-	backup := make([]byte, 0)
-	backup = append(backup, xBytes...)
-	backup = append(backup, yBytes...)
+	backup := make([]byte, 2*n)
+	privKey.PublicKey.X.FillBytes(backup[:n])
+	privKey.PublicKey.Y.FillBytes(backup[n:])
 
 	return append(signature, backup...), nil
 }
@@ -108,8 +109,15 @@ func (s1 SimpleSigner) Equal(s2 Signer) bool {
 	return ok && ss.chainId.Cmp(s1.chainId) == 0
 }
 
+// Hash returns hash of tx which is signed, chain id is hashed too, so tx
+// signed for one chain is not valid on other one.
 func (fs SimpleSigner) Hash(tx *GTransaction) common.Hash {
-	return crvTxHash(tx.inner)
+	var h = crvTxHash(tx.inner)
+	var chainId []byte
+	if fs.chainId != nil {
+		chainId = fs.chainId.Bytes()
+	}
+	return common.Hash(blake2b.Sum256(append(h.Bytes(), chainId...)))
 }
 
 func (fs SimpleSigner) Pen() *ecdsa.PrivateKey {
@@ -121,11 +129,15 @@ func (fs SimpleSigner) Sender(tx *GTransaction) (Address, error) {
 		return Address{}, ErrTxTypeNotSupported
 	}
 	r, s, v := tx.RawSignatureValues()
-	if len(r.Bits()) > 0 && len(s.Bits()) > 0 {
-		return recoverPlain(fs.Hash(tx), r, s, v, false)
-	} else {
+	if len(r.Bits()) == 0 || len(s.Bits()) == 0 {
 		return Address{}, ErrInvalidSig
 	}
+	switch tx.SigScheme() {
+	case SigSchemeECDSAP256:
+		return recoverPlain(fs.Hash(tx), r, s, v, false)
+	default:
+		return Address{}, ErrUnknownSigScheme
+	}
 }
 
 func (fs SimpleSigner) SignatureValues(tx *GTransaction, sig []byte) (R, S, V *big.Int, err error) {
@@ -171,27 +183,58 @@ func crvTxHash(t TxData) (h common.Hash) {
 	hw.Write(t.dna())
 	hw.Write(t.value().Bytes())
 	hw.Write(tNonce)
-	hw.Write(t.to()[:])
+	// contract creation has no recipient
+	if to := t.to(); to != nil {
+		hw.Write(to[:])
+	}
 	hw.Write(t.gasPrice().Bytes())
 	hw.Write(tGas)
 
 	dateBytes, _ := t.time().MarshalBinary()
 	hw.Write(dateBytes)
+	// default scheme is not hashed, so hashes of txs before schemes stay the same
+	if scheme := t.scheme(); scheme != SigSchemeECDSAP256 {
+		hw.Write([]byte{scheme})
+	}
+	// compressed data is hashed as is, id of compression binds the way to decode it
+	if compression := t.compression(); compression != CompressionNone {
+		hw.Write([]byte{0xc, compression})
+	}
 	h.SetBytes(hw.Sum(nil))
 	return h
 }
 
+// recoverPlain returns address of signer. R and S of tx keep public key of
+// signer, V keeps r||s of signature which is verified against sighash.
 func recoverPlain(sighash common.Hash, R, S, V *big.Int, a bool) (Address, error) {
-	return BytesToAddress(INRISeq(append(R.Bytes())[1:])[12:]), nil
+	if !chainElliptic.IsOnCurve(R, S) {
+		return Address{}, ErrInvalidSig
+	}
+	var n = (chainElliptic.Params().N.BitLen() + 7) / 8
+	if V == nil || V.Sign() <= 0 || V.BitLen() > 16*n {
+		return Address{}, ErrInvalidSig
+	}
+	var rs = V.FillBytes(make([]byte, 2*n))
+	var r, s = new(big.Int).SetBytes(rs[:n]), new(big.Int).SetBytes(rs[n:])
+	var pub = ecdsa.PublicKey{Curve: chainElliptic, X: R, Y: S}
+	// Sign hashes message once more
+	var h = blake2b.Sum256(sighash.Bytes())
+	if !ecdsa.Verify(&pub, h[:], r, s) {
+		return Address{}, ErrInvalidSig
+	}
+	return PubkeyToAddress(pub), nil
 }
 
+// decodeSignature splits signature made by Sign: r and s of tx keep public
+// key of signer, v keeps r||s of signature
 func decodeSignature(sig []byte) (r, s, v *big.Int) {
-	// fmt.Printf("decode signature len: %v\r\n", len(sig))
-	rsSig := sig[64:]
-	vSig := sig[1:][:16]
-	r = new(big.Int).SetBytes(rsSig[:32])
-	s = new(big.Int).SetBytes(rsSig[32:])
-	v = new(big.Int).SetBytes(vSig)
+	var n = (chainElliptic.Params().N.BitLen() + 7) / 8
+	if len(sig) != 4*n {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+	v = new(big.Int).SetBytes(sig[:2*n])
+	r = new(big.Int).SetBytes(sig[2*n : 3*n])
+	s = new(big.Int).SetBytes(sig[3*n:])
 	return r, s, v
 }
 
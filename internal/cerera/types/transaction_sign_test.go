@@ -83,3 +83,55 @@ func TestHashTx(t *testing.T) {
 		t.Errorf("similar hashes! Have %s\r\n want %s\r\n", otherTransaction.Hash(), transaction.Hash())
 	}
 }
+
+func TestSigScheme(t *testing.T) {
+	var acc, _ = GenerateAccount()
+	var to = HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea24807B491717081c42F2575F09B6bc60206")
+	signer := NewSimpleSignerWithPen(big.NewInt(25331), acc)
+	var create = func(scheme byte) *GTransaction {
+		return NewTx(&PGTransaction{
+			To:       &to,
+			Value:    big.NewInt(10),
+			GasPrice: big.NewInt(15),
+			Gas:      1000000,
+			Nonce:    0x1,
+			Time:     time.Now(),
+			Scheme:   scheme,
+		})
+	}
+
+	tx, err := SignTx(create(SigSchemeECDSAP256), signer, acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.SigScheme() != SigSchemeECDSAP256 || !KnownSigScheme(tx.SigScheme()) {
+		t.Errorf("Default scheme should be ecdsa p256, have %d", tx.SigScheme())
+	}
+	if _, err := signer.Sender(tx); err != nil {
+		t.Errorf("Tx of default scheme should verify, have %s", err)
+	}
+
+	unknown, err := SignTx(create(0x7f), signer, acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KnownSigScheme(unknown.SigScheme()) {
+		t.Errorf("Scheme %d should be unknown", unknown.SigScheme())
+	}
+	if _, err := signer.Sender(unknown); err != ErrUnknownSigScheme {
+		t.Errorf("Tx of unknown scheme should be rejected, have %v", err)
+	}
+
+	// scheme is a part of tx identity and survives json
+	if create(0x7f).Hash() == create(SigSchemeECDSAP256).Hash() {
+		t.Errorf("Txs of different schemes should have different hashes")
+	}
+	data, _ := unknown.MarshalJSON()
+	var decoded GTransaction
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SigScheme() != 0x7f {
+		t.Errorf("Scheme should survive json, have %d", decoded.SigScheme())
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"bytes"
+	"math"
 	"math/big"
 	"testing"
 	"time"
@@ -61,3 +63,152 @@ func TestSerialize(t *testing.T) {
 		t.Errorf("Differenet sizes! Have %d, want %d", tx.Size(), itx.Size())
 	}
 }
+
+func TestContractCreation(t *testing.T) {
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+	itx := NewTx(&PGTransaction{
+		To:       nil,
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(15),
+		Gas:      1000000,
+		Data:     code,
+		Time:     time.Now(),
+	})
+
+	if !itx.IsContractCreation() {
+		t.Errorf("Tx without recipient should create contract")
+	}
+	if _, ok := itx.MethodSelector(); ok {
+		t.Errorf("Contract creation should not have method selector")
+	}
+	if string(itx.CallData()) != string(code) {
+		t.Errorf("Different call data! Have %x, want %x", itx.CallData(), code)
+	}
+}
+
+func TestContractCall(t *testing.T) {
+	var to = HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea2873A1191717081c42F2575F09B6bc60206")
+	itx := NewTransaction(1, to, big.NewInt(0), 1000, big.NewInt(15), []byte{0xa9, 0x05, 0x9c, 0xbb, 0x1, 0x2})
+
+	if itx.IsContractCreation() {
+		t.Errorf("Tx with recipient should not create contract")
+	}
+	selector, ok := itx.MethodSelector()
+	if !ok {
+		t.Fatalf("Contract call should have method selector")
+	}
+	if selector != [4]byte{0xa9, 0x05, 0x9c, 0xbb} {
+		t.Errorf("Different selectors! Have %x, want %x", selector, []byte{0xa9, 0x05, 0x9c, 0xbb})
+	}
+	if string(itx.CallData()) != string([]byte{0x1, 0x2}) {
+		t.Errorf("Different call data! Have %x, want %x", itx.CallData(), []byte{0x1, 0x2})
+	}
+}
+
+func TestPlainTransfer(t *testing.T) {
+	var to = HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea2873A1191717081c42F2575F09B6bc60206")
+	itx := NewTransaction(1, to, big.NewInt(10), 1000, big.NewInt(15), nil)
+
+	if itx.IsContractCreation() {
+		t.Errorf("Transfer should not create contract")
+	}
+	if _, ok := itx.MethodSelector(); ok {
+		t.Errorf("Transfer should not have method selector")
+	}
+	if len(itx.CallData()) != 0 {
+		t.Errorf("Transfer should not have call data, have %x", itx.CallData())
+	}
+}
+
+func TestCompressedData(t *testing.T) {
+	// repetitive call data shrinks well
+	var to = HexToAddress("0xe7925c3c6FC91Cc41319eE320D297549fF0a1Cfd16425e7ad95ED556337ea2873A1191717081c42F2575F09B6bc60206")
+	code := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, bytes.Repeat([]byte{0x60, 0x80, 0x60, 0x40, 0x52}, 200)...)
+	compression, compressed := CompressData(code)
+	if compression != CompressionGzip || len(compressed) >= len(code) {
+		t.Fatalf("Large code should be compressed, have %d of %d bytes", len(compressed), len(code))
+	}
+	itx := NewTx(&PGTransaction{
+		To:          &to,
+		Value:       big.NewInt(0),
+		GasPrice:    big.NewInt(15),
+		Gas:         1000000,
+		Data:        compressed,
+		Dna:         []byte{0xf},
+		Time:        time.Now().UTC(), // decoded time is in utc, location is part of hash
+		Compression: compression,
+	})
+	if !bytes.Equal(itx.CallData(), code[MethodSelectorLength:]) {
+		t.Errorf("Different call data! Have %d bytes, want %d", len(itx.CallData()), len(code)-MethodSelectorLength)
+	}
+	if !bytes.Equal(itx.RawData(), compressed) {
+		t.Errorf("Tx should carry compressed data")
+	}
+
+	var plain = NewTx(&PGTransaction{
+		To:       &to,
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(15),
+		Gas:      1000000,
+		Data:     code,
+		Dna:      []byte{0xf},
+		Time:     itx.GetTime(),
+	})
+	if plain.Hash() == itx.Hash() {
+		t.Errorf("Compressed and plain txs should have different hashes")
+	}
+
+	txBytes, err := itx.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx GTransaction
+	if err := tx.UnmarshalJSON(txBytes); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash() != itx.Hash() {
+		t.Errorf("Different hashes after round-trip! Have %s, want %s", tx.Hash(), itx.Hash())
+	}
+	if !bytes.Equal(tx.CallData(), code[MethodSelectorLength:]) {
+		t.Errorf("Different call data after round-trip")
+	}
+
+	// short data is not worth compression
+	compression, short := CompressData([]byte{0x1, 0x2})
+	if compression != CompressionNone || !bytes.Equal(short, []byte{0x1, 0x2}) {
+		t.Errorf("Short data should stay uncompressed")
+	}
+
+	var broken = NewTx(&PGTransaction{
+		Value:       big.NewInt(0),
+		GasPrice:    big.NewInt(15),
+		Data:        []byte{0x1, 0x2, 0x3},
+		Compression: CompressionGzip,
+	})
+	if _, err := broken.DecodeData(); err != ErrInvalidCompressed {
+		t.Errorf("Expected %s, have %v", ErrInvalidCompressed, err)
+	}
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	var to = HexToAddress("0xc9C5c06E295d8FB8E97f4df93C4919D557D0B284521d71A7fCA1e1C3F289849989E80B0B81ED4EDB361d1f8F67DDf613")
+	var schedule = GasSchedule{Base: 10, PerByte: 2}
+	var tests = []struct {
+		size int
+		want uint64
+	}{
+		{0, 10},
+		{5, 20},
+		{1 << 20, 10 + 2<<20},
+	}
+	for _, tt := range tests {
+		var tx = NewTransaction(1, to, big.NewInt(1), 0, big.NewInt(1), make([]byte, tt.size))
+		if have := tx.IntrinsicGas(schedule); have != tt.want {
+			t.Errorf("Intrinsic gas of %d bytes, have %d, want %d", tt.size, have, tt.want)
+		}
+	}
+	var tx = NewTransaction(1, to, big.NewInt(1), 0, big.NewInt(1), make([]byte, 4))
+	if have := tx.IntrinsicGas(GasSchedule{Base: 1, PerByte: math.MaxUint64 / 2}); have != math.MaxUint64 {
+		t.Errorf("Intrinsic gas should be capped, have %d", have)
+	}
+}
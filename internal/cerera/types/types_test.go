@@ -73,6 +73,28 @@ func TestIsHexAddress(t *testing.T) {
 	}
 }
 
+func TestAddressIsCanonical(t *testing.T) {
+	pk, _ := GenerateAccount()
+	tests := []struct {
+		name string
+		addr Address
+		exp  bool
+	}{
+		{"generated", PubkeyToAddress(pk.PublicKey), true},
+		{"full hex", HexToAddress("0x9642903868c35526a8b34686a5d4184125562e7300a4556790379884e81ee30507352e49266351711164874923761a17"), true},
+		{"short literal", Address{0x1, 0x2}, false},
+		{"short hex", HexToAddress("0x5aaeb6053f3e94c9b9a09f3435e7ef1beaed1"), false},
+		{"zero", Address{}, false},
+	}
+
+	for _, test := range tests {
+		if result := test.addr.IsCanonical(); result != test.exp {
+			t.Errorf("IsCanonical(%s) of %x == %v; expected %v",
+				test.name, test.addr, result, test.exp)
+		}
+	}
+}
+
 func TestAddressUnmarshalJSON(t *testing.T) {
 	var tests = []struct {
 		Input     string
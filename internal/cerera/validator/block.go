@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cerera/internal/cerera/block"
+)
+
+var (
+	ErrNilHeader     = errors.New("block without header")
+	ErrBlockHeight   = errors.New("block height does not follow tip")
+	ErrBlockPrevHash = errors.New("block is not built on tip")
+	ErrBlockGas      = errors.New("block gas used exceeds gas limit")
+	ErrBlockTxRoot   = errors.New("block root does not match transactions")
+	ErrBlockPoW      = errors.New("block proof of work is invalid")
+	ErrBlockChainId  = errors.New("block is of other chain than tip")
+)
+
+// CheckBlock returns reason why block can not be added on top of tip.
+// Block should be sealed, its chain id (Ctx of header) is the one of tip,
+// which comes from genesis of configured chain. Nil tip skips checks of
+// parent, zero gas limit of block means no limit.
+func CheckBlock(b block.Block, tip *block.Block) error {
+	if b.Head == nil {
+		return ErrNilHeader
+	}
+	if res := block.VerifyBlockHashWithDetails(&b); !res.Valid() {
+		return fmt.Errorf("%w: %s", ErrBlockPoW, res.Reason)
+	}
+	if b.Head.GasLimit > 0 && b.Head.GasUsed > b.Head.GasLimit {
+		return fmt.Errorf("%w: %d > %d", ErrBlockGas, b.Head.GasUsed, b.Head.GasLimit)
+	}
+	if !block.VerifyTxRoot(&b) {
+		return fmt.Errorf("%w: %s", ErrBlockTxRoot, b.Head.Root)
+	}
+	if tip == nil {
+		return nil
+	}
+	if tip.Head == nil {
+		return ErrNilHeader
+	}
+	if b.Head.Ctx != tip.Head.Ctx {
+		return fmt.Errorf("%w: %d, tip %d", ErrBlockChainId, b.Head.Ctx, tip.Head.Ctx)
+	}
+	if b.Head.Height != tip.Head.Height+1 {
+		return fmt.Errorf("%w: %d after %d", ErrBlockHeight, b.Head.Height, tip.Head.Height)
+	}
+	if b.Head.PrevHash != tip.Hash() {
+		return fmt.Errorf("%w: %s", ErrBlockPrevHash, b.Head.PrevHash)
+	}
+	return nil
+}
+
+// ValidateBlock checks that block could be added on top of tip.
+func (v *DDDDDValidator) ValidateBlock(b block.Block, tip *block.Block) bool {
+	if err := CheckBlock(b, tip); err != nil {
+		fmt.Printf("REJECTED\r\n\tBlock %s\r\n", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package validator
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+var (
+	ErrNoSigner       = errors.New("validator has no signer")
+	ErrSenderMismatch = errors.New("sender differs from signer of tx")
+)
+
+// parsePemKey decodes private key as it is kept in vault
+func parsePemKey(b []byte) (*ecdsa.PrivateKey, error) {
+	pemBlock, _ := pem.Decode(b)
+	if pemBlock == nil {
+		return nil, errors.New("error ParsePKC58 key")
+	}
+	aKey, err := x509.ParseECPrivateKey(pemBlock.Bytes)
+	if err != nil {
+		return nil, errors.New("error ParsePKC58 key")
+	}
+	return aKey, nil
+}
+
+// HealthCheck returns error when validator can not verify signatures of txs.
+func (v *DDDDDValidator) HealthCheck() error {
+	if v.signer == nil {
+		return ErrNoSigner
+	}
+	return nil
+}
+
+// VerifySignature recovers address of tx signer with signer of validator.
+// Cached sender of tx is not trusted, address is taken from signature values.
+func (v *DDDDDValidator) VerifySignature(tx *types.GTransaction) (types.Address, error) {
+	if v.signer == nil {
+		return types.Address{}, ErrNoSigner
+	}
+	return v.signer.Sender(tx)
+}
@@ -3,8 +3,6 @@ package validator
 import (
 	"context"
 	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
@@ -16,6 +14,7 @@ import (
 	"github.com/cerera/internal/cerera/storage"
 
 	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/coinbase"
 )
 
 var v Validator
@@ -26,6 +25,7 @@ func Get() Validator {
 
 type Validator interface {
 	GasPrice() *big.Int
+	MinGasPriceFor(kind byte) *big.Int
 	Faucet(addrStr string, valFor int) error
 	PreSend(to types.Address, value float64, gas uint64, msg string) *types.GTransaction
 	SetUp(chainId *big.Int)
@@ -34,24 +34,31 @@ type Validator interface {
 	ValidateRawTransaction(tx *types.GTransaction) bool
 	// validate and execute transaction
 	ValidateTransaction(t *types.GTransaction, from types.Address) bool
-	ValidateBlock(b block.Block) bool
+	// validate and execute transaction of block, vault is written with batch
+	ExecuteTransaction(t *types.GTransaction, from types.Address, batch *storage.VaultBatch) bool
+	// validate block on top of current tip of chain
+	ValidateBlock(b block.Block, tip *block.Block) bool
 }
 
 type DDDDDValidator struct {
-	currentStatus int
-	minGasPrice   *big.Int
-	storage       string
-	signatureKey  *ecdsa.PrivateKey
-	signer        types.Signer
-	balance       *big.Int
+	currentStatus  int
+	minGasPrice    *big.Int
+	storage        string
+	signatureKey   *ecdsa.PrivateKey
+	signer         types.Signer
+	balance        *big.Int
+	gasSchedule    types.GasSchedule // intrinsic gas of txs
+	createGasPrice *big.Int          // gas price floor of contract creation, nil means minGasPrice
 }
 
 func NewValidator(ctx context.Context, cfg config.Config) Validator {
 	var p = types.DecodePrivKey(cfg.NetCfg.PRIV)
 	v = &DDDDDValidator{
-		signatureKey: p,
-		signer:       types.NewSimpleSignerWithPen(cfg.Chain.ChainID, p),
-		balance:      big.NewInt(0), // Initialize balance
+		signatureKey:   p,
+		signer:         types.NewSimpleSignerWithPen(cfg.Chain.ChainID, p),
+		balance:        big.NewInt(0), // Initialize balance
+		gasSchedule:    cfg.GetGasSchedule(),
+		createGasPrice: cfg.GetCreateGasPrice(),
 	}
 	return v
 }
@@ -60,11 +67,52 @@ func (v *DDDDDValidator) GasPrice() *big.Int {
 	return v.minGasPrice
 }
 
+// kinds of txs with own gas price floor
+const (
+	TxKindTransfer byte = iota
+	TxKindCall          // call of contract method
+	TxKindCreate        // contract creation
+	TxKindCoinbase      // reward of block
+	TxKindFaucet
+)
+
+// TxKind returns kind of tx by which its gas price floor is chosen. Faucet
+// does not create txs, so no tx is of faucet kind.
+func TxKind(tx *types.GTransaction) byte {
+	switch {
+	case coinbase.IsCoinBaseTransaction(tx):
+		return TxKindCoinbase
+	case tx.IsContractCreation():
+		return TxKindCreate
+	}
+	if _, ok := tx.MethodSelector(); ok {
+		return TxKindCall
+	}
+	return TxKindTransfer
+}
+
+// MinGasPriceFor returns gas price floor of txs of kind. Coinbase and faucet
+// are free, contract creation may have higher floor than other txs.
+func (v *DDDDDValidator) MinGasPriceFor(kind byte) *big.Int {
+	var floor = v.minGasPrice
+	switch kind {
+	case TxKindCoinbase, TxKindFaucet:
+		return big.NewInt(0)
+	case TxKindCreate:
+		if v.createGasPrice != nil && (floor == nil || v.createGasPrice.Cmp(floor) > 0) {
+			floor = v.createGasPrice
+		}
+	}
+	if floor == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(floor)
+}
+
 func (v *DDDDDValidator) Faucet(addrStr string, valFor int) error {
 	if valFor > 0 {
 		var vault = storage.GetVault()
-		vault.FaucetBalance(types.HexToAddress(addrStr), types.FloatToBigInt(float64(valFor)))
-		return nil
+		return vault.FaucetBalance(types.HexToAddress(addrStr), types.FloatToBigInt(float64(valFor)))
 	}
 	return errors.New("value < 0")
 }
@@ -91,8 +139,22 @@ func (v *DDDDDValidator) Signer() types.Signer {
 	return v.signer
 }
 
+// system addresses are short intentionally, all others should be canonical
+func checkAddress(addr types.Address) error {
+	if addr == types.HexToAddress(coinbase.AddressHex) || addr.IsCanonical() {
+		return nil
+	}
+	return fmt.Errorf("%w: %x", types.ErrNonCanonicalAddress, addr)
+}
+
 // Validate and execute transaction
 func (validator *DDDDDValidator) ValidateTransaction(tx *types.GTransaction, from types.Address) bool {
+	return validator.ExecuteTransaction(tx, from, nil)
+}
+
+// ExecuteTransaction validates and executes transaction, changes of vault
+// belong to batch when it is not nil
+func (validator *DDDDDValidator) ExecuteTransaction(tx *types.GTransaction, from types.Address, batch *storage.VaultBatch) bool {
 	// no edit tx here !!!
 	// check user can send signed tx
 	// this function should be rewriting and simplified by refactoring onto n functions
@@ -100,27 +162,106 @@ func (validator *DDDDDValidator) ValidateTransaction(tx *types.GTransaction, fro
 	var localVault = storage.GetVault()
 	var r, s, _ = tx.RawSignatureValues()
 	fmt.Printf("Sender is: %s\r\n", from)
+	if signer, err := validator.VerifySignature(tx); err != nil {
+		fmt.Printf("REJECTED\r\n\tSignature %s, tx=%s\r\n", err, tx.Hash())
+		return false
+	} else if signer != from {
+		fmt.Printf("REJECTED\r\n\t%s: %s, tx=%s\r\n", ErrSenderMismatch, signer, tx.Hash())
+		return false
+	}
+	// route by kind of tx: there is no vm yet, so contracts can not be deployed,
+	// calls with method selector are executed as transfer to contract address
+	if tx.IsContractCreation() {
+		fmt.Printf("REJECTED\r\n\tContract creation is not supported, tx=%s\r\n", tx.Hash())
+		return false
+	}
+	if !types.KnownSigScheme(tx.SigScheme()) {
+		fmt.Printf("REJECTED\r\n\t%s %d, tx=%s\r\n", types.ErrUnknownSigScheme, tx.SigScheme(), tx.Hash())
+		return false
+	}
+	if _, err := tx.DecodeData(); err != nil {
+		fmt.Printf("REJECTED\r\n\tData %s, tx=%s\r\n", err, tx.Hash())
+		return false
+	}
+	if err := checkAddress(*tx.To()); err != nil {
+		fmt.Printf("REJECTED\r\n\tRecipient %s, tx=%s\r\n", err, tx.Hash())
+		return false
+	}
+	if err := checkAddress(from); err != nil {
+		fmt.Printf("REJECTED\r\n\tSender %s, tx=%s\r\n", err, tx.Hash())
+		return false
+	}
+	if selector, ok := tx.MethodSelector(); ok {
+		fmt.Printf("Contract call %x to %s\r\n", selector, tx.To())
+	}
+	if intrinsic := tx.IntrinsicGas(validator.gasSchedule); tx.Gas() < intrinsic {
+		fmt.Printf("REJECTED\r\n\tGas %d is below intrinsic gas %d, tx=%s\r\n", tx.Gas(), intrinsic, tx.Hash())
+		return false
+	}
+	if floor := validator.MinGasPriceFor(TxKind(tx)); tx.GasPrice().Cmp(floor) < 0 {
+		fmt.Printf("REJECTED\r\n\tGas price %d is below %d, tx=%s\r\n", tx.GasPrice(), floor, tx.Hash())
+		return false
+	}
+	// sender should exist, tx should be next by nonce and be paid with its gas
+	var sa = localVault.GetCopy(from)
+	if sa == nil {
+		fmt.Printf("REJECTED\r\n\tUnknown sender, tx=%s\r\n", tx.Hash())
+		return false
+	}
+	if tx.Nonce() != sa.Nonce {
+		fmt.Printf("REJECTED\r\n\tNonce %d, want %d, tx=%s\r\n", tx.Nonce(), sa.Nonce, tx.Hash())
+		return false
+	}
 	var gas = tx.Gas()
 	var val = tx.Value()
-	var out = localVault.Get(from).Balance
-	var delta = big.NewInt(0).Sub(out, val)
-	if delta.Cmp(big.NewInt(0)) < 0 {
+	var out = big.NewInt(0)
+	if sa.Balance != nil {
+		out = sa.Balance
+	}
+	if out.Cmp(tx.Cost()) < 0 {
+		fmt.Printf("REJECTED\r\n\tBalance %d is less than cost %d, tx=%s\r\n", out, tx.Cost(), tx.Hash())
 		return false
+	}
+	fmt.Printf(
+		"APPROVED\r\n\tSigned transaction with hash=%s\r\n\t gas=%d\r\n\t value=%d\r\n\t  current balance=%d\r\n",
+		tx.Hash(),
+		gas,
+		val,
+		out,
+	)
+	var err error
+	if batch != nil {
+		err = batch.Transfer(from, *tx.To(), val, tx.Hash())
 	} else {
-		fmt.Printf(
-			"APPROVED\r\n\tSigned transaction with hash=%s\r\n\t gas=%d\r\n\t value=%d\r\n\t  current balance=%d\r\n",
-			tx.Hash(),
-			gas,
-			val,
-			out,
-		)
-		localVault.UpdateBalance(from, *tx.To(), val, tx.Hash())
+		err = localVault.UpdateBalance(from, *tx.To(), val, tx.Hash())
+	}
+	if err != nil {
+		fmt.Printf("Error while update balance: %s\r\n", err)
+		return false
 	}
 	localVault.CheckRunnable(r, s, tx)
 	return true
 }
 
 func (validator *DDDDDValidator) ValidateRawTransaction(tx *types.GTransaction) bool {
+	if !types.KnownSigScheme(tx.SigScheme()) {
+		fmt.Printf("REJECTED\r\n\t%s %d, tx=%s\r\n", types.ErrUnknownSigScheme, tx.SigScheme(), tx.Hash())
+		return false
+	}
+	if _, err := tx.DecodeData(); err != nil {
+		fmt.Printf("REJECTED\r\n\tData %s, tx=%s\r\n", err, tx.Hash())
+		return false
+	}
+	if intrinsic := tx.IntrinsicGas(validator.gasSchedule); tx.Gas() < intrinsic {
+		fmt.Printf("REJECTED\r\n\tGas %d is below intrinsic gas %d, tx=%s\r\n", tx.Gas(), intrinsic, tx.Hash())
+		return false
+	}
+	if tx.To() != nil {
+		if err := checkAddress(*tx.To()); err != nil {
+			fmt.Printf("REJECTED\r\n\tRecipient %s, tx=%s\r\n", err, tx.Hash())
+			return false
+		}
+	}
 	return true
 }
 
@@ -136,12 +277,9 @@ func (v *DDDDDValidator) SignRawTransactionWithKey(txHash common.Hash, signKey s
 
 	// sign tx
 	var vlt = storage.GetVault()
-	var signBytes = vlt.GetKey(signKey)
-
-	pemBlock, _ := pem.Decode([]byte(signBytes))
-	aKey, err1 := x509.ParseECPrivateKey(pemBlock.Bytes)
+	aKey, err1 := parsePemKey(vlt.GetKey(signKey))
 	if err1 != nil {
-		return common.EmptyHash(), errors.New("error ParsePKC58 key")
+		return common.EmptyHash(), err1
 	}
 
 	signTx, err2 := types.SignTx(tx, v.signer, aKey)
@@ -159,10 +297,3 @@ func (v *DDDDDValidator) SignRawTransactionWithKey(txHash common.Hash, signKey s
 	fmt.Printf("Now tx %s is %t\r\n", signTx.Hash(), signTx.IsSigned())
 	return signTx.Hash(), nil
 }
-
-func (v *DDDDDValidator) ValidateBlock(b block.Block) bool {
-	// when validator says that block is correct, node get reward for it
-	// it should be automatic as same level with autogen alogrythm of chain
-	// if block.Confirmations > 2 then node gets reward
-	return true
-}
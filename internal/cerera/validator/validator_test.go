@@ -1,13 +1,19 @@
 package validator
 
 import (
+	"errors"
 	"math/big"
 	"strconv"
 	"testing"
+	"unsafe"
 
+	"github.com/cerera/internal/cerera/block"
 	"github.com/cerera/internal/cerera/common"
+	"github.com/cerera/internal/cerera/config"
 	"github.com/cerera/internal/cerera/pool"
+	"github.com/cerera/internal/cerera/storage"
 	"github.com/cerera/internal/cerera/types"
+	"github.com/cerera/internal/coinbase"
 )
 
 func TestPoolSigningProc(t *testing.T) {
@@ -61,3 +67,305 @@ func TestPoolSigningProc(t *testing.T) {
 	//		t.Errorf("Error! Tx not signed! %s\r\n", tx.Hash())
 	//	}
 }
+
+func TestRejectContractCreation(t *testing.T) {
+	var vldtr = &DDDDDValidator{}
+	tx := types.NewTx(&types.PGTransaction{
+		To:       nil,
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(250),
+		Gas:      500,
+		Data:     []byte{0x60, 0x80},
+	})
+	if vldtr.ValidateTransaction(tx, types.Address{0x1}) {
+		t.Errorf("Contract creation should be rejected without vm")
+	}
+}
+
+func TestRejectNonCanonicalAddress(t *testing.T) {
+	var vldtr = &DDDDDValidator{}
+	var pk, _ = types.GenerateAccount()
+	var to = types.PubkeyToAddress(pk.PublicKey)
+	if !vldtr.ValidateRawTransaction(types.NewTransaction(1, to, big.NewInt(1), 500, big.NewInt(250), nil)) {
+		t.Errorf("Tx to canonical address should be accepted")
+	}
+	if vldtr.ValidateRawTransaction(types.NewTransaction(1, types.Address{0x1, 0x2}, big.NewInt(1), 500, big.NewInt(250), nil)) {
+		t.Errorf("Tx to short address should be rejected")
+	}
+	if vldtr.ValidateRawTransaction(types.NewTransaction(1, types.Address{}, big.NewInt(1), 500, big.NewInt(250), nil)) {
+		t.Errorf("Tx to zero address should be rejected")
+	}
+}
+
+func TestRejectUnknownSigScheme(t *testing.T) {
+	var vldtr = &DDDDDValidator{}
+	var pk, _ = types.GenerateAccount()
+	var to = types.PubkeyToAddress(pk.PublicKey)
+	var create = func(scheme byte) *types.GTransaction {
+		return types.NewTx(&types.PGTransaction{
+			To:       &to,
+			Value:    big.NewInt(1),
+			GasPrice: big.NewInt(250),
+			Gas:      500,
+			Scheme:   scheme,
+		})
+	}
+	if !vldtr.ValidateRawTransaction(create(types.SigSchemeECDSAP256)) {
+		t.Errorf("Tx of ecdsa p256 scheme should be accepted")
+	}
+	if vldtr.ValidateRawTransaction(create(0x7f)) {
+		t.Errorf("Tx of unknown scheme should be rejected")
+	}
+	if vldtr.ValidateTransaction(create(0x7f), to) {
+		t.Errorf("Tx of unknown scheme should not be executed")
+	}
+}
+
+func TestValidateBlock(t *testing.T) {
+	var vldtr = &DDDDDValidator{}
+	var tip = block.Genesis()
+	// any hash meets target of difficulty 1
+	var next = func() block.Block {
+		return *block.NewBlock(&block.Header{
+			Ctx:        tip.Head.Ctx,
+			Difficulty: big.NewInt(1),
+			Height:     tip.Head.Height + 1,
+			PrevHash:   tip.Hash(),
+			GasLimit:   1000,
+			GasUsed:    500,
+			Number:     big.NewInt(1),
+			Size:       int(unsafe.Sizeof(&block.Block{})),
+		})
+	}
+	if b := next(); !vldtr.ValidateBlock(b, &tip) {
+		t.Errorf("Block on top of tip should be accepted")
+	}
+	// block without transactions and parent is checked by itself
+	if b := next(); !vldtr.ValidateBlock(b, nil) {
+		t.Errorf("Block without tip should be accepted")
+	}
+	if err := CheckBlock(block.Block{}, &tip); !errors.Is(err, ErrNilHeader) {
+		t.Errorf("Expected %s, have %v", ErrNilHeader, err)
+	}
+	if err := CheckBlock(next(), &block.Block{}); !errors.Is(err, ErrNilHeader) {
+		t.Errorf("Expected %s, have %v", ErrNilHeader, err)
+	}
+	var b = next()
+	b.Head.Difficulty = nil
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockPoW) {
+		t.Errorf("Expected %s, have %v", ErrBlockPoW, err)
+	}
+	b = next()
+	b.Head.Difficulty = new(big.Int).Lsh(big.NewInt(1), 200)
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockPoW) {
+		t.Errorf("Expected %s, have %v", ErrBlockPoW, err)
+	}
+	if vldtr.ValidateBlock(b, nil) {
+		t.Errorf("Unsealed block should be rejected without tip too")
+	}
+	b = next()
+	b.Head.Ctx = tip.Head.Ctx + 1
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockChainId) {
+		t.Errorf("Expected %s, have %v", ErrBlockChainId, err)
+	}
+	b = next()
+	b.Head.Height = tip.Head.Height + 2
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockHeight) {
+		t.Errorf("Expected %s, have %v", ErrBlockHeight, err)
+	}
+	b = next()
+	b.Head.PrevHash = common.EmptyHash()
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockPrevHash) {
+		t.Errorf("Expected %s, have %v", ErrBlockPrevHash, err)
+	}
+	b = next()
+	b.Head.GasUsed = 1001
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockGas) {
+		t.Errorf("Expected %s, have %v", ErrBlockGas, err)
+	}
+	if vldtr.ValidateBlock(b, &tip) {
+		t.Errorf("Block over gas limit should be rejected")
+	}
+	b = next()
+	b.Transactions = append(b.Transactions, *types.NewTransaction(1, types.Address{0x1}, big.NewInt(1), 100, big.NewInt(1), nil))
+	if err := CheckBlock(b, &tip); !errors.Is(err, ErrBlockTxRoot) {
+		t.Errorf("Expected %s, have %v", ErrBlockTxRoot, err)
+	}
+	b.Head.Root = block.ComputeTxRoot(b.Transactions)
+	if err := CheckBlock(b, &tip); err != nil {
+		t.Errorf("Block with root of its txs should be accepted, have %s", err)
+	}
+}
+
+func TestValidateTransactionState(t *testing.T) {
+	var signer = types.NewSimpleSignerWithPen(big.NewInt(11), nil)
+	var vldtr = &DDDDDValidator{minGasPrice: big.NewInt(100), signer: signer}
+	var fromKey, _ = types.GenerateAccount()
+	var from = types.PubkeyToAddress(fromKey.PublicKey)
+	var pk, _ = types.GenerateAccount()
+	var to = types.PubkeyToAddress(pk.PublicKey)
+	var create = func(nonce uint64, value int64, gasPrice int64) *types.GTransaction {
+		var tx = types.NewTransaction(nonce, to, big.NewInt(value), 10, big.NewInt(gasPrice), nil)
+		signTx, err := types.SignTx(tx, signer, fromKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signTx
+	}
+
+	// vault is not initialized yet
+	if vldtr.ValidateTransaction(create(1, 1, 100), from) {
+		t.Errorf("Tx should be rejected without vault")
+	}
+
+	cfg := &config.Config{}
+	cfg.NetCfg.ADDR = to
+	cfg.NetCfg.PRIV = types.EncodePrivateKeyToToString(pk)
+	cfg.Vault.MEM = true
+	var vlt = storage.NewD5Vault(cfg)
+	if vldtr.ValidateTransaction(create(1, 1, 100), from) {
+		t.Errorf("Tx from unknown sender should be rejected")
+	}
+	vlt.Put(from, types.StateAccount{Address: from, Nonce: 1, Balance: big.NewInt(2000)})
+
+	if vldtr.ValidateTransaction(create(1, 1, 99), from) {
+		t.Errorf("Tx with gas price below floor should be rejected")
+	}
+	if vldtr.ValidateTransaction(create(2, 1, 100), from) {
+		t.Errorf("Tx with future nonce should be rejected")
+	}
+	// cost is value and gas 10*100
+	if vldtr.ValidateTransaction(create(1, 1001, 100), from) {
+		t.Errorf("Tx which costs more than balance should be rejected")
+	}
+	if !vldtr.ValidateTransaction(create(1, 1000, 100), from) {
+		t.Errorf("Tx paid by balance should be accepted")
+	}
+	if sa := vlt.Get(from); sa.Nonce != 2 || sa.Balance.Int64() != 1000 {
+		t.Errorf("Expected nonce 2 and balance 1000, have %d and %d", sa.Nonce, sa.Balance)
+	}
+	if vldtr.ValidateTransaction(create(1, 1, 100), from) {
+		t.Errorf("Replayed tx should be rejected")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	var signer = types.NewSimpleSignerWithPen(big.NewInt(11), nil)
+	var vldtr = &DDDDDValidator{signer: signer}
+	var pk, _ = types.GenerateAccount()
+	var addr = types.PubkeyToAddress(pk.PublicKey)
+
+	// key is read as in SignRawTransactionWithKey
+	key, err := parsePemKey(types.EncodePrivateKeyToByte(pk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx = types.NewTransaction(1, addr, big.NewInt(1), 500, big.NewInt(250), nil)
+	if _, err := vldtr.VerifySignature(tx); err != types.ErrInvalidSig {
+		t.Errorf("Expected %s for unsigned tx, have %v", types.ErrInvalidSig, err)
+	}
+	signTx, err := types.SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer2, err := vldtr.VerifySignature(signTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer2 != addr {
+		t.Errorf("Expected signer %s, have %s", addr, signer2)
+	}
+	// tx signed by other key is not accepted from addr
+	var other, _ = types.GenerateAccount()
+	otherTx, _ := types.SignTx(tx, signer, other)
+	if vldtr.ValidateTransaction(otherTx, addr) {
+		t.Errorf("Tx signed by other key should be rejected")
+	}
+	// public key of victim copied to signature made by other key
+	var h = signer.Hash(tx)
+	sig, err := types.Sign(h[:], other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk.PublicKey.X.FillBytes(sig[64:96])
+	pk.PublicKey.Y.FillBytes(sig[96:])
+	forgedTx, _ := tx.WithSignature(signer, sig)
+	if _, err := vldtr.VerifySignature(forgedTx); err != types.ErrInvalidSig {
+		t.Errorf("Expected %s for forged signature, have %v", types.ErrInvalidSig, err)
+	}
+	if vldtr.ValidateTransaction(forgedTx, addr) {
+		t.Errorf("Tx with forged signature should be rejected")
+	}
+	// tx signed for other chain
+	var otherChain = &DDDDDValidator{signer: types.NewSimpleSignerWithPen(big.NewInt(12), nil)}
+	if _, err := otherChain.VerifySignature(signTx); err != types.ErrInvalidSig {
+		t.Errorf("Expected %s for tx of other chain, have %v", types.ErrInvalidSig, err)
+	}
+	if _, err := (&DDDDDValidator{}).VerifySignature(signTx); err != ErrNoSigner {
+		t.Errorf("Expected %s, have %v", ErrNoSigner, err)
+	}
+	if _, err := parsePemKey([]byte("not a key")); err == nil {
+		t.Errorf("Broken key should not be parsed")
+	}
+}
+
+func TestRejectBelowIntrinsicGas(t *testing.T) {
+	var vldtr = &DDDDDValidator{gasSchedule: types.GasSchedule{Base: 10, PerByte: 1}}
+	var pk, _ = types.GenerateAccount()
+	var to = types.PubkeyToAddress(pk.PublicKey)
+	var create = func(gas uint64, size int) *types.GTransaction {
+		return types.NewTransaction(1, to, big.NewInt(1), gas, big.NewInt(250), make([]byte, size))
+	}
+	if !vldtr.ValidateRawTransaction(create(10, 0)) || !vldtr.ValidateRawTransaction(create(1034, 1024)) {
+		t.Errorf("Tx with gas covering intrinsic gas should be accepted")
+	}
+	if vldtr.ValidateRawTransaction(create(9, 0)) || vldtr.ValidateRawTransaction(create(1033, 1024)) {
+		t.Errorf("Tx with gas below intrinsic gas should be rejected")
+	}
+	if vldtr.ValidateRawTransaction(create(500, 1<<20)) {
+		t.Errorf("Large tx with small gas should be rejected")
+	}
+}
+
+func TestMinGasPriceFor(t *testing.T) {
+	var pk, _ = types.GenerateAccount()
+	var to = types.PubkeyToAddress(pk.PublicKey)
+	var transfer = types.NewTransaction(1, to, big.NewInt(1), 10, big.NewInt(100), nil)
+	var call = types.NewTransaction(1, to, big.NewInt(1), 10, big.NewInt(100), []byte{0xa9, 0x05, 0x9c, 0xbb, 0x1})
+	var create = types.NewTx(&types.PGTransaction{Value: big.NewInt(0), GasPrice: big.NewInt(100), Gas: 10, Data: []byte{0x60}})
+	var reward = coinbase.CreateCoinBaseTransation(1, 1704067200000, to)
+
+	var tests = []struct {
+		name   string
+		tx     *types.GTransaction
+		kind   byte
+		floor  int64 // without create floor
+		create int64 // with create floor 500
+	}{
+		{"transfer", transfer, TxKindTransfer, 100, 100},
+		{"call", call, TxKindCall, 100, 100},
+		{"create", create, TxKindCreate, 100, 500},
+		{"coinbase", reward, TxKindCoinbase, 0, 0},
+	}
+	var flat = &DDDDDValidator{minGasPrice: big.NewInt(100)}
+	var withCreate = &DDDDDValidator{minGasPrice: big.NewInt(100), createGasPrice: big.NewInt(500)}
+	for _, tt := range tests {
+		if kind := TxKind(tt.tx); kind != tt.kind {
+			t.Errorf("%s: different kind, have %d, want %d", tt.name, kind, tt.kind)
+		}
+		if floor := flat.MinGasPriceFor(tt.kind); floor.Int64() != tt.floor {
+			t.Errorf("%s: different floor, have %d, want %d", tt.name, floor, tt.floor)
+		}
+		if floor := withCreate.MinGasPriceFor(tt.kind); floor.Int64() != tt.create {
+			t.Errorf("%s: different floor with create floor, have %d, want %d", tt.name, floor, tt.create)
+		}
+	}
+	if floor := flat.MinGasPriceFor(TxKindFaucet); floor.Sign() != 0 {
+		t.Errorf("Faucet should be free, have %d", floor)
+	}
+	// floor of creation is not below floor of other txs
+	var low = &DDDDDValidator{minGasPrice: big.NewInt(100), createGasPrice: big.NewInt(50)}
+	if floor := low.MinGasPriceFor(TxKindCreate); floor.Int64() != 100 {
+		t.Errorf("Expected floor 100, have %d", floor)
+	}
+}
@@ -33,6 +33,7 @@ func SetCoinbase(publicKey, privateKey string, balance big.Int) {
 		Nonce:    1,
 		Root:     common.HexToHash(AddressHex),
 		Status:   "OP_ACC_C",
+		Type:     types.AccountCoinbase,
 		Inputs:   []common.Hash{},
 	}
 	Coinbase = coinbaseData{
@@ -0,0 +1,51 @@
+package coinbase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/cerera/internal/cerera/types"
+)
+
+// reward of first blocks, it is halved every RewardHalvingInterval blocks
+var InitialBlockReward = types.FloatToBigInt(50.0)
+
+const RewardHalvingInterval = 210000
+
+// data of coinbase tx starts with marker followed by height of block
+var coinbaseMarker = []byte("OP_COINBASE")
+
+// BlockReward returns reward for block of height by schedule.
+func BlockReward(height int) *big.Int {
+	if height < 0 {
+		return big.NewInt(0)
+	}
+	var halvings = uint(height / RewardHalvingInterval)
+	return new(big.Int).Rsh(InitialBlockReward, halvings)
+}
+
+// CreateCoinBaseTransation creates reward tx of block with height and timestamp (ms).
+// Height is used as nonce and is written into data and reward is value of tx,
+// so hash of coinbase tx is unique for every block.
+func CreateCoinBaseTransation(height int, timestamp uint64, addr types.Address) *types.GTransaction {
+	var data = make([]byte, len(coinbaseMarker)+8)
+	copy(data, coinbaseMarker)
+	binary.BigEndian.PutUint64(data[len(coinbaseMarker):], uint64(height))
+	return types.NewTx(&types.PGTransaction{
+		Nonce:    uint64(height),
+		To:       &addr,
+		Value:    BlockReward(height),
+		Gas:      0,
+		GasPrice: big.NewInt(0),
+		Data:     data,
+		Time:     time.UnixMilli(int64(timestamp)).UTC(),
+	})
+}
+
+// IsCoinBaseTransaction reports whether tx is reward tx of block.
+func IsCoinBaseTransaction(tx *types.GTransaction) bool {
+	var data = tx.Data()
+	return len(data) == len(coinbaseMarker)+8 && bytes.HasPrefix(data, coinbaseMarker)
+}
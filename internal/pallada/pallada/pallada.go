@@ -23,6 +23,17 @@ func Prepare() {
 	pld = Pallada{}
 }
 
+// IsStale reports whether result of method may be outdated
+// because node is catching up with peers.
+func IsStale(method string) bool {
+	switch method {
+	case "get_balance", "balances", "inputs", "history":
+		var bc = chain.GetBlockChain()
+		return !bc.IsSynced()
+	}
+	return false
+}
+
 func Execute(method string, params []interface{}) interface{} {
 	// workaround
 	// https://stackoverflow.com/questions/28447297/how-to-check-for-an-empty-struct
@@ -79,7 +90,54 @@ func Execute(method string, params []interface{}) interface{} {
 			return 0xf
 		}
 		var addr = types.HexToAddress(addressStr)
-		pld.Data = types.BigIntToFloat(vlt.Get(addr).Balance)
+		if sa := vlt.GetCopy(addr); sa != nil {
+			pld.Data = types.BigIntToFloat(sa.Balance)
+		} else {
+			pld.Data = 0.0
+		}
+	case "balances":
+		// get exact balances of several addresses at once
+		//
+		// addresses - list of addresses, unknown ones have null balance
+		var addrs = make([]types.Address, 0, len(params))
+		for _, param := range params {
+			addressStr, ok := param.(string)
+			if !ok {
+				pld.Data = "Error"
+				return 0xf
+			}
+			addrs = append(addrs, types.HexToAddress(addressStr))
+		}
+		pld.Data = vlt.GetBalances(addrs)
+	case "inputs":
+		// get inputs of account by pages
+		//
+		// address - address of account
+		// offset - count of inputs to skip
+		// limit - max count of inputs in page
+		if len(params) < 3 {
+			pld.Data = "Wrong count of params"
+			return 0xf
+		}
+		addressStr, ok1 := params[0].(string)
+		offset, ok2 := params[1].(float64)
+		limit, ok3 := params[2].(float64)
+		if !ok1 || !ok2 || !ok3 {
+			pld.Data = "Error"
+			return 0xf
+		}
+		var inputs, total = []types.InputEntry{}, 0
+		if sa := vlt.GetCopy(types.HexToAddress(addressStr)); sa != nil {
+			inputs, total = sa.InputsPage(int(offset), int(limit))
+		}
+		type res struct {
+			Inputs []types.InputEntry `json:"inputs"`
+			Total  int                `json:"total"`
+		}
+		pld.Data = &res{
+			Inputs: inputs,
+			Total:  total,
+		}
 	case "faucet":
 		// faucet
 		to, ok1 := params[0].(string)
@@ -95,12 +153,76 @@ func Execute(method string, params []interface{}) interface{} {
 			return 0xf
 		}
 		pld.Data = "SUCCESS"
+	case "faucet_status":
+		// check if address can request faucet now, without dispensing
+		to, ok := params[0].(string)
+		if !ok {
+			pld.Data = "Error"
+			return 0xf
+		}
+		pld.Data = vlt.FaucetStatus(types.HexToAddress(to))
+	case "history":
+		// get incoming txs of account newest first by pages
+		//
+		// address - address of account
+		// cursor - hash of last tx of previous page, empty for first page
+		// limit - max count of txs in page
+		if len(params) < 3 {
+			pld.Data = "Wrong count of params"
+			return 0xf
+		}
+		addressStr, ok1 := params[0].(string)
+		cursorStr, ok2 := params[1].(string)
+		limit, ok3 := params[2].(float64)
+		if !ok1 || !ok2 || !ok3 {
+			pld.Data = "Error"
+			return 0xf
+		}
+		var cursor common.Hash
+		if cursorStr != "" {
+			cursor = common.HexToHash(cursorStr)
+		}
+		records, err := vlt.TransactionHistory(types.HexToAddress(addressStr), cursor, int(limit))
+		if err != nil {
+			pld.Data = err.Error()
+			return 0xf
+		}
+		type res struct {
+			Records []storage.TxRecord `json:"records"`
+			Next    *common.Hash       `json:"next,omitempty"`
+		}
+		var page = &res{Records: records}
+		if len(records) == int(limit) {
+			page.Next = &records[len(records)-1].Hash
+		}
+		pld.Data = page
+	case "stats":
+		// state of node: chain info and sync state
+		type res struct {
+			Chain interface{}     `json:"chain"`
+			Sync  chain.SyncState `json:"sync"`
+		}
+		pld.Data = &res{
+			Chain: bc.GetInfo(),
+			Sync:  bc.SyncState(),
+		}
+	case "consensus":
+		// full consensus state: voters, seen nodes, nonce and membership hash
+		pld.Data = bc.ConsensusSnapshot()
+	case "hashrate":
+		// nonces tried by node per second while sealing blocks
+		pld.Data = bc.Hashrate()
 	case "getblockchaininfo":
 		// get info of (block)chain
 		pld.Data = bc.GetInfo()
 	case "getblockcount":
 		// get latest block of chain
-		pld.Data = bc.GetLatestBlock().Header().Number
+		if latest := bc.GetLatestBlock(); latest != nil {
+			pld.Data = latest.Header().Number
+		} else {
+			pld.Data = "Chain is empty"
+			return 0xf
+		}
 	case "getblockhash":
 		number, ok := params[0].(float64)
 		if !ok {
@@ -116,6 +238,40 @@ func Execute(method string, params []interface{}) interface{} {
 			return 0xf
 		}
 		pld.Data = bc.GetBlock(blockHashStr)
+	case "getblockbyheight":
+		// get block by height, missing block is an error
+		if len(params) < 1 {
+			pld.Data = "Error"
+			return 0xf
+		}
+		number, ok := params[0].(float64)
+		if !ok {
+			pld.Data = "Error"
+			return 0xf
+		}
+		b, err := bc.GetBlockByHeight(int(number))
+		if err != nil {
+			pld.Data = err.Error()
+			return 0xf
+		}
+		pld.Data = b
+	case "getblockbyhash":
+		// get block by hash, missing block is an error
+		if len(params) < 1 {
+			pld.Data = "Error"
+			return 0xf
+		}
+		blockHashStr, ok := params[0].(string)
+		if !ok {
+			pld.Data = "Error"
+			return 0xf
+		}
+		b, err := bc.GetBlockByHash(common.HexToHash(blockHashStr))
+		if err != nil {
+			pld.Data = err.Error()
+			return 0xf
+		}
+		pld.Data = b
 	case "getblockheader":
 		// get header by block hash
 		blockHashStr, ok := params[0].(string)